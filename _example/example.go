@@ -33,10 +33,10 @@ func main() {
 		}
 		f.Close()
 
-		// Append a frame(type: *image.Image). First frame used as the default image.
+		// Append a frame(type: image.Image). First frame used as the default image.
 		outApng.Images = append(outApng.Images, inPng)
 
-		// Append a delay time(type: uint32) per frame in 10 milliseconds.
+		// Append a delay time(type: uint16) per frame in 10 milliseconds.
 		// If it is 0, the decoder renders the next frame as quickly as possible.
 		outApng.Delays = append(outApng.Delays, 0)
 	}
@@ -0,0 +1,66 @@
+package goapng
+
+import (
+	"context"
+	"image"
+	"io"
+	"time"
+)
+
+// FrameInfo describes how one frame is stored in an APNG file.
+type FrameInfo struct {
+	Bounds   image.Rectangle // The frame region on the canvas.
+	Delay    time.Duration
+	Disposal byte
+	Blend    byte
+
+	// Bytes is the size of the frame's chunks in the file, fcTL included.
+	// CompressedBytes counts only the compressed image data, and RawBytes
+	// the filtered scanlines it inflates to. Ratio is CompressedBytes over
+	// RawBytes.
+	Bytes           int64
+	CompressedBytes int64
+	RawBytes        int64
+	Ratio           float64
+}
+
+// Analyze reads an APNG from r and describes how each of its frames is
+// stored, without decompressing them, so the frames that dominate the file
+// size can be found. A static PNG is described as a single frame.
+func Analyze(r io.Reader) ([]FrameInfo, error) {
+	d := &decoder{ctx: context.Background()}
+	if err := d.readChunks(r); err != nil {
+		return nil, err
+	}
+
+	infos := make([]FrameInfo, len(d.frames))
+	for i, f := range d.frames {
+		fi := FrameInfo{
+			Bounds:   f.fc.bounds(),
+			Delay:    f.fc.duration(),
+			Disposal: f.fc.disposeOp,
+			Blend:    f.fc.blendOp,
+		}
+		if d.order.seenacTL {
+			fi.Bytes = 12 + 26 // fcTL
+		}
+		for _, id := range f.idats {
+			fi.CompressedBytes += int64(len(id))
+			fi.Bytes += 12 + int64(len(id))
+			if d.order.seenacTL && (i > 0 || !d.order.idatFrame) {
+				fi.Bytes += 4 // The sequence number of fdAT.
+			}
+		}
+
+		ihdr := make([]byte, len(d.ihdr))
+		copy(ihdr, d.ihdr)
+		writeUint32(ihdr[0:4], f.fc.width)
+		writeUint32(ihdr[4:8], f.fc.height)
+		fi.RawBytes = rawSize(ihdr)
+		if fi.RawBytes > 0 {
+			fi.Ratio = float64(fi.CompressedBytes) / float64(fi.RawBytes)
+		}
+		infos[i] = fi
+	}
+	return infos, nil
+}
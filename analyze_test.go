@@ -0,0 +1,98 @@
+package goapng
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"time"
+)
+
+func TestAnalyze(t *testing.T) {
+	a := testAPNG(3, 6, 6)
+	// Frame 1 changes only a 2x1 region of frame 0, which Optimize stores
+	// on its own.
+	m := solid(6, 6, a.Images[0].(*image.NRGBA).NRGBAAt(0, 0))
+	m.SetNRGBA(3, 4, color.NRGBA{1, 2, 3, 0xff})
+	m.SetNRGBA(4, 4, color.NRGBA{1, 2, 3, 0xff})
+	a.Images[1] = m
+	a.Durations[1] = 250 * time.Millisecond
+	Optimize(a, OptimizeDelta)
+	var buf bytes.Buffer
+	if err := EncodeAll(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+
+	infos, err := Analyze(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 3 {
+		t.Fatalf("got %d frames, want 3", len(infos))
+	}
+	if infos[0].Bounds != image.Rect(0, 0, 6, 6) || infos[1].Bounds != image.Rect(3, 4, 5, 5) {
+		t.Errorf("bounds %v and %v, want the canvas and (3,4)-(5,5)", infos[0].Bounds, infos[1].Bounds)
+	}
+	if infos[1].Delay != 250*time.Millisecond || infos[2].Delay != 100*time.Millisecond {
+		t.Errorf("delays %v and %v, want 250ms and 100ms", infos[1].Delay, infos[2].Delay)
+	}
+	for i, fi := range infos {
+		if fi.Disposal != a.disposal(i) || fi.Blend != a.blend(i) {
+			t.Errorf("frame %d: dispose_op %d, blend_op %d, want %d and %d", i, fi.Disposal, fi.Blend, a.disposal(i), a.blend(i))
+		}
+		if fi.RawBytes == 0 || fi.Ratio != float64(fi.CompressedBytes)/float64(fi.RawBytes) {
+			t.Errorf("frame %d: %d raw bytes, ratio %v", i, fi.RawBytes, fi.Ratio)
+		}
+	}
+	// The smaller frame inflates to fewer bytes.
+	if infos[1].RawBytes >= infos[0].RawBytes {
+		t.Errorf("raw bytes %d for 2x1, %d for 6x6", infos[1].RawBytes, infos[0].RawBytes)
+	}
+
+	// The sizes add up to the frame chunks of the file.
+	var frameBytes, dataBytes, gotBytes, gotData int64
+	for _, c := range readTestChunks(t, buf.Bytes()) {
+		switch c.name {
+		case "fcTL":
+			frameBytes += 12 + int64(len(c.data))
+		case "IDAT":
+			frameBytes += 12 + int64(len(c.data))
+			dataBytes += int64(len(c.data))
+		case "fdAT":
+			frameBytes += 12 + int64(len(c.data))
+			dataBytes += int64(len(c.data)) - 4
+		}
+	}
+	for _, fi := range infos {
+		gotBytes += fi.Bytes
+		gotData += fi.CompressedBytes
+	}
+	if gotBytes != frameBytes || gotData != dataBytes {
+		t.Errorf("frames total %d bytes, %d compressed; want %d and %d", gotBytes, gotData, frameBytes, dataBytes)
+	}
+}
+
+func TestAnalyzeStatic(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, solid(4, 3, color.NRGBA{1, 2, 3, 0xff})); err != nil {
+		t.Fatal(err)
+	}
+	var idat int64
+	for _, c := range readTestChunks(t, buf.Bytes()) {
+		if c.name == "IDAT" {
+			idat += int64(len(c.data))
+		}
+	}
+	infos, err := Analyze(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || infos[0].Bounds != image.Rect(0, 0, 4, 3) || infos[0].CompressedBytes != idat || infos[0].Bytes != 12+idat {
+		t.Errorf("got %+v, want one 4x3 frame of %d compressed bytes", infos, idat)
+	}
+
+	if _, err := Analyze(bytes.NewReader(buf.Bytes()[:20])); err == nil {
+		t.Error("truncated file: got no error")
+	}
+}
@@ -0,0 +1,80 @@
+package goapng
+
+import (
+	"image"
+	"io"
+	"time"
+)
+
+// Animation is a format-neutral animation: full-canvas frames as they are
+// displayed, with the time each one is shown. It is the bridge to other
+// animated formats, such as animated WebP, whose muxers don't know about
+// APNG's frame regions, disposal or blending.
+type Animation struct {
+	Frames    []*image.RGBA   // The composited canvas of each frame.
+	Durations []time.Duration // The display time of each frame.
+	LoopCount uint32          // The number of plays; LoopForever (0) plays forever.
+	Width     int             // The canvas width.
+	Height    int             // The canvas height.
+}
+
+// Muxer writes an Animation in another format, such as animated WebP.
+type Muxer interface {
+	Mux(w io.Writer, anim *Animation) error
+}
+
+// Animation renders a to an Animation.
+func (a *APNG) Animation() *Animation {
+	b := a.bounds()
+	anim := &Animation{
+		Frames:    make([]*image.RGBA, len(a.Images)),
+		Durations: make([]time.Duration, len(a.Images)),
+		LoopCount: a.LoopCount,
+		Width:     b.Dx(),
+		Height:    b.Dy(),
+	}
+	c := newCompositor(b.Dx(), b.Dy())
+	ts := a.timeline()
+	for i, img := range a.Images {
+		anim.Frames[i] = cloneRGBA(c.render(img, a.disposal(i), a.blend(i)))
+		anim.Durations[i] = ts[i+1] - ts[i]
+	}
+	return anim
+}
+
+// RenderedFrames returns every frame of a composited onto the canvas as it
+// is displayed, each in its own image, together with the display time of
+// each frame. The images are ready to use as textures in a game engine.
+func (a *APNG) RenderedFrames() ([]*image.RGBA, []time.Duration) {
+	anim := a.Animation()
+	return anim.Frames, anim.Durations
+}
+
+// FromAnimation returns an APNG holding the frames of anim.
+func FromAnimation(anim *Animation) *APNG {
+	a := &APNG{
+		Images:    make([]image.Image, len(anim.Frames)),
+		Durations: append([]time.Duration(nil), anim.Durations...),
+		LoopCount: anim.LoopCount,
+		Config: image.Config{
+			Width:  anim.Width,
+			Height: anim.Height,
+		},
+	}
+	for i, m := range anim.Frames {
+		a.Images[i] = m
+	}
+	if len(a.Images) > 0 {
+		a.Config.ColorModel = a.Images[0].ColorModel()
+	}
+	return a
+}
+
+// Export renders a and writes it with m, so the same frames can be
+// published as APNG and in the format m implements.
+func Export(w io.Writer, a *APNG, m Muxer) error {
+	if len(a.Images) == 0 {
+		return ErrNoFrames
+	}
+	return m.Mux(w, a.Animation())
+}
@@ -0,0 +1,128 @@
+// Package apnghttp serves animated PNGs encoded on the fly, such as charts
+// rendered per request, over HTTP.
+package apnghttp
+
+import (
+	"bytes"
+	"image"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cia-rana/goapng"
+)
+
+// ContentType is the media type of APNG files.
+const ContentType = "image/apng"
+
+// Handler encodes an animation for each request and writes it to the
+// response. Encoding stops when the request's context is canceled, such as
+// when the client goes away.
+type Handler struct {
+	// Source returns the frames to serve for r. It is called once per
+	// request. If it fails, the request is answered with
+	// 500 Internal Server Error.
+	Source func(r *http.Request) (goapng.FrameSource, error)
+
+	LoopCount uint32          // The number of plays; goapng.LoopForever (0) plays forever.
+	Encoder   *goapng.Encoder // The Encoder to use; nil means the default settings.
+
+	// ContentLength, if set, has the whole animation encoded before any of
+	// it is sent, so that the response carries a Content-Length header and
+	// an encoding error can still be answered with an error status.
+	// Otherwise the animation is written as it is encoded; a FrameSource
+	// without a Len method is then still held in memory until its end, as
+	// EncodeSource does for writers that can't seek.
+	ContentLength bool
+
+	// Progressive, if set, flushes the response after each frame, the
+	// first of which is the default image, so that browsers can show the
+	// animation while later frames are still being generated. Frames can
+	// only be sent as they are encoded if Source returns a FrameSource
+	// with a Len method. Progressive is ignored with ContentLength.
+	Progressive bool
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	src, err := h.Source(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	enc := h.Encoder
+	if enc == nil {
+		enc = new(goapng.Encoder)
+	}
+
+	if h.ContentLength {
+		var buf bytes.Buffer
+		if err := enc.EncodeSourceContext(r.Context(), &buf, src, h.LoopCount); err != nil {
+			if r.Context().Err() == nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", ContentType)
+		w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+		buf.WriteTo(w)
+		return
+	}
+
+	if f, ok := w.(http.Flusher); ok && h.Progressive {
+		fs := &flushingSource{FrameSource: src, f: f}
+		if l, ok := src.(lener); ok {
+			src = &flushingLenSource{fs, l}
+		} else {
+			src = fs
+		}
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	rw := &responseWriter{w: w}
+	// Once part of the animation has been sent, an error can only cut the
+	// response short.
+	if err := enc.EncodeSourceContext(r.Context(), rw, src, h.LoopCount); err != nil && !rw.wrote && r.Context().Err() == nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// responseWriter records whether anything has been written to w.
+type responseWriter struct {
+	w     http.ResponseWriter
+	wrote bool
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	rw.wrote = true
+	return rw.w.Write(b)
+}
+
+// flushingSource flushes f before each frame but the first is asked for,
+// that is, once the previous frame has been written, and at the end of the
+// animation.
+type flushingSource struct {
+	goapng.FrameSource
+	f       http.Flusher
+	started bool
+}
+
+func (s *flushingSource) Next() (image.Image, time.Duration, error) {
+	if s.started {
+		s.f.Flush()
+	}
+	s.started = true
+	return s.FrameSource.Next()
+}
+
+// lener is implemented by FrameSources that know their length.
+type lener interface {
+	Len() int
+}
+
+// flushingLenSource is a flushingSource that keeps the Len method of the
+// wrapped FrameSource, which EncodeSource needs to send frames as they are
+// encoded.
+type flushingLenSource struct {
+	*flushingSource
+	lener
+}
@@ -0,0 +1,75 @@
+package apnghttp
+
+import (
+	"image"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cia-rana/goapng"
+)
+
+// lenSource serves n frames and records how much of the response had been
+// flushed each time a frame was asked for.
+type lenSource struct {
+	n       int
+	i       int
+	rec     *httptest.ResponseRecorder
+	flushed []int
+}
+
+func (s *lenSource) Len() int { return s.n }
+
+func (s *lenSource) Next() (image.Image, time.Duration, error) {
+	if s.i == s.n {
+		return nil, 0, io.EOF
+	}
+	n := 0
+	if s.rec.Flushed {
+		n = s.rec.Body.Len()
+	}
+	s.flushed = append(s.flushed, n)
+	s.i++
+	return image.NewRGBA(image.Rect(0, 0, 8, 8)), 50 * time.Millisecond, nil
+}
+
+func TestHandlerProgressive(t *testing.T) {
+	tests := []struct {
+		name        string
+		progressive bool
+	}{
+		{"buffered", false},
+		{"progressive", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			src := &lenSource{n: 3, rec: rec}
+			h := &Handler{
+				Source:      func(*http.Request) (goapng.FrameSource, error) { return src, nil },
+				Encoder:     &goapng.Encoder{},
+				Progressive: tt.progressive,
+			}
+			h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+			if got := rec.Header().Get("Content-Type"); got != ContentType {
+				t.Errorf("Content-Type = %q, want %q", got, ContentType)
+			}
+			a, err := goapng.DecodeAll(rec.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(a.Images) != src.n {
+				t.Errorf("got %d frames, want %d", len(a.Images), src.n)
+			}
+			for i := 1; i < len(src.flushed); i++ {
+				grew := src.flushed[i] > src.flushed[i-1]
+				if grew != tt.progressive {
+					t.Errorf("frame %d: flushed %d bytes before, %d after; want flushing %v", i, src.flushed[i-1], src.flushed[i], tt.progressive)
+				}
+			}
+		})
+	}
+}
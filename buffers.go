@@ -0,0 +1,67 @@
+package goapng
+
+import (
+	"bytes"
+	"compress/zlib"
+	"image/png"
+	"sync"
+)
+
+// encodeBuffers is the scratch space of a frame encode. An Encoder keeps
+// it between frames and between encodes, so that encoding many animations
+// doesn't allocate it afresh each time.
+type encodeBuffers struct {
+	png    bytes.Buffer       // The output of image/png.
+	pngBuf *png.EncoderBuffer // The state image/png keeps between encodes.
+	out    bytes.Buffer       // The compressed rows of encodeScanlines.
+	zw     *zlib.Writer       // The writer of out when compressing with ZlibCompressor.
+	zlevel CompressionLevel   // The level of zw.
+	rows   []byte             // The row buffers of encodeScanlines.
+}
+
+// Get and Put make b the png.EncoderBufferPool of a single buffer.
+func (b *encodeBuffers) Get() *png.EncoderBuffer   { return b.pngBuf }
+func (b *encodeBuffers) Put(eb *png.EncoderBuffer) { b.pngBuf = eb }
+
+// rowBuffers returns n zeroed buffers of size bytes each.
+func (b *encodeBuffers) rowBuffers(n, size int) [][]byte {
+	if cap(b.rows) < n*size {
+		b.rows = make([]byte, n*size)
+	}
+	b.rows = b.rows[:n*size]
+	for i := range b.rows {
+		b.rows[i] = 0
+	}
+	bufs := make([][]byte, n)
+	for i := range bufs {
+		bufs[i] = b.rows[i*size : (i+1)*size : (i+1)*size]
+	}
+	return bufs
+}
+
+// buffersMu guards the creation and Reset of the buffer pools of Encoders.
+var buffersMu sync.Mutex
+
+// bufferPool returns the pool of scratch buffers of enc, creating it on
+// first use.
+func (enc *Encoder) bufferPool() *sync.Pool {
+	buffersMu.Lock()
+	defer buffersMu.Unlock()
+	if enc.buffers == nil {
+		enc.buffers = &sync.Pool{
+			New: func() interface{} { return new(encodeBuffers) },
+		}
+	}
+	return enc.buffers
+}
+
+// Reset drops the scratch buffers enc has kept from earlier encodes, such
+// as after encoding an unusually large animation, so that their memory
+// can be reclaimed. The settings of enc are kept. Encoders reuse their
+// buffers between encodes, so encoding many animations with one Encoder
+// costs less than encoding each with a new one.
+func (enc *Encoder) Reset() {
+	buffersMu.Lock()
+	enc.buffers = nil
+	buffersMu.Unlock()
+}
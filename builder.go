@@ -0,0 +1,101 @@
+package goapng
+
+import (
+	"errors"
+	"image"
+	"io"
+	"time"
+)
+
+// BuilderConfig configures a Builder.
+type BuilderConfig struct {
+	LoopCount uint32        // The number of plays; LoopForever (0) plays forever.
+	Delay     time.Duration // The delay of frames not given one.
+	Encoder   *Encoder      // The encoder to write with; nil uses the defaults.
+}
+
+// A Builder assembles an animation one frame at a time and writes it when
+// closed, as an alternative to filling in the parallel slices of an APNG:
+//
+//	b := goapng.NewBuilder(w, goapng.BuilderConfig{})
+//	b.AddFrame(img1).Delay(120 * time.Millisecond)
+//	b.AddFrame(img2).Delay(80 * time.Millisecond).Dispose(goapng.DisposeOpPrevious).Blend(goapng.BlendOpOver)
+//	err := b.Close()
+type Builder struct {
+	w      io.Writer
+	enc    *Encoder
+	a      APNG
+	delay  time.Duration
+	closed bool
+}
+
+// NewBuilder returns a Builder that writes to w.
+func NewBuilder(w io.Writer, config BuilderConfig) *Builder {
+	enc := config.Encoder
+	if enc == nil {
+		enc = new(Encoder)
+	}
+	return &Builder{
+		w:     w,
+		enc:   enc,
+		a:     APNG{LoopCount: config.LoopCount},
+		delay: config.Delay,
+	}
+}
+
+// AddFrame adds img as the next frame, drawn with DisposeOpNone and
+// BlendOpSource unless set otherwise through the returned FrameBuilder.
+func (b *Builder) AddFrame(img image.Image) *FrameBuilder {
+	b.a.Images = append(b.a.Images, img)
+	b.a.Durations = append(b.a.Durations, b.delay)
+	b.a.Disposals = append(b.a.Disposals, DisposeOpNone)
+	b.a.Blends = append(b.a.Blends, BlendOpSource)
+	return &FrameBuilder{b: b, i: len(b.a.Images) - 1}
+}
+
+// Len returns the number of frames added so far.
+func (b *Builder) Len() int {
+	return len(b.a.Images)
+}
+
+// Close encodes the frames added and writes them to the underlying writer.
+// Problems with the frames, such as frames outside the canvas set by the
+// first, are reported here. Close does not close the underlying writer.
+func (b *Builder) Close() error {
+	if b.closed {
+		return errors.New("apng: builder already closed")
+	}
+	b.closed = true
+	return b.enc.EncodeAll(b.w, &b.a)
+}
+
+// A FrameBuilder sets the properties of a frame added to a Builder. Its
+// methods return the FrameBuilder, so calls can be chained.
+type FrameBuilder struct {
+	b *Builder
+	i int
+}
+
+// Delay sets how long the frame is displayed.
+func (f *FrameBuilder) Delay(d time.Duration) *FrameBuilder {
+	f.b.a.Durations[f.i] = d
+	return f
+}
+
+// Dispose sets the disposal method of the frame, such as DisposeOpPrevious.
+func (f *FrameBuilder) Dispose(op byte) *FrameBuilder {
+	f.b.a.Disposals[f.i] = op
+	return f
+}
+
+// Blend sets the blend operation of the frame, such as BlendOpOver.
+func (f *FrameBuilder) Blend(op byte) *FrameBuilder {
+	f.b.a.Blends[f.i] = op
+	return f
+}
+
+// AddFrame adds the next frame to the Builder, for chaining the frames of
+// an animation in a single expression.
+func (f *FrameBuilder) AddFrame(img image.Image) *FrameBuilder {
+	return f.b.AddFrame(img)
+}
@@ -0,0 +1,67 @@
+package goapng
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestBuilder(t *testing.T) {
+	a := testAPNG(3, 6, 6)
+	var buf bytes.Buffer
+	b := NewBuilder(&buf, BuilderConfig{LoopCount: 2, Delay: 50 * time.Millisecond})
+	b.AddFrame(a.Images[0]).Delay(120 * time.Millisecond).
+		AddFrame(a.Images[1]).Dispose(DisposeOpPrevious).Blend(BlendOpOver).
+		AddFrame(a.Images[2])
+	if b.Len() != 3 {
+		t.Errorf("Len = %d, want 3", b.Len())
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Close(); err == nil {
+		t.Error("second Close: got no error")
+	}
+
+	got, err := DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.LoopCount != 2 {
+		t.Errorf("loop count %d, want 2", got.LoopCount)
+	}
+	wantDelays := []time.Duration{120 * time.Millisecond, 50 * time.Millisecond, 50 * time.Millisecond}
+	wantDisposals := []byte{DisposeOpNone, DisposeOpPrevious, DisposeOpNone}
+	wantBlends := []byte{BlendOpSource, BlendOpOver, BlendOpSource}
+	for i := range wantDelays {
+		if got.delay(i) != wantDelays[i] || got.Disposals[i] != wantDisposals[i] || got.Blends[i] != wantBlends[i] {
+			t.Errorf("frame %d: delay %v, dispose_op %d, blend_op %d; want %v, %d, %d",
+				i, got.delay(i), got.Disposals[i], got.Blends[i], wantDelays[i], wantDisposals[i], wantBlends[i])
+		}
+		if !samePixels(got.Images[i], a.Images[i]) {
+			t.Errorf("frame %d differs", i)
+		}
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	// Problems with the frames are reported by Close, with nothing written.
+	var buf bytes.Buffer
+	b := NewBuilder(&buf, BuilderConfig{Encoder: &Encoder{}})
+	red := color.NRGBA{0xff, 0, 0, 0xff}
+	b.AddFrame(solid(4, 4, red))
+	b.AddFrame(translate(solid(4, 4, red), image.Pt(2, 2)))
+	if err := b.Close(); !errors.Is(err, ErrFrameRegion) {
+		t.Errorf("frame outside the canvas: got error %v, want ErrFrameRegion", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("wrote %d bytes after failing", buf.Len())
+	}
+
+	if err := NewBuilder(&buf, BuilderConfig{}).Close(); !errors.Is(err, ErrNoFrames) {
+		t.Errorf("no frames: got error %v, want ErrNoFrames", err)
+	}
+}
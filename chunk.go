@@ -0,0 +1,230 @@
+package goapng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// maxChunkLength is the largest chunk length allowed by the PNG spec.
+const maxChunkLength = 1<<31 - 1
+
+// maxFrameDataLength is the most frame data that fits in one chunk,
+// leaving room for the sequence number of an fdAT chunk.
+const maxFrameDataLength = maxChunkLength - 4
+
+// chunkReader reads a PNG stream one chunk at a time. The chunk data it
+// returns is freshly allocated, so it never aliases a shared buffer and
+// may be of any length allowed by the spec.
+type chunkReader struct {
+	r       io.Reader
+	tmp     [8]byte
+	skipCRC bool // Whether to report every checksum as good without computing it.
+}
+
+func newChunkReader(r io.Reader) *chunkReader {
+	return &chunkReader{r: r}
+}
+
+func (cr *chunkReader) readSignature() error {
+	if _, err := io.ReadFull(cr.r, cr.tmp[:len(pngHeader)]); err != nil {
+		return unexpectedEOF(err)
+	}
+	if string(cr.tmp[:len(pngHeader)]) != pngHeader {
+		return errors.New("apng: not a PNG file")
+	}
+	return nil
+}
+
+// next returns the type and data of the next chunk. It returns io.EOF only
+// if the stream ends cleanly before a chunk header.
+func (cr *chunkReader) next() (string, []byte, error) {
+	name, data, crcOK, err := cr.nextUnchecked()
+	if err != nil {
+		return "", nil, err
+	}
+	if !crcOK {
+		return "", nil, ErrChecksum
+	}
+	return name, data, nil
+}
+
+// nextUnchecked is like next but reports a bad checksum instead of failing.
+func (cr *chunkReader) nextUnchecked() (name string, data []byte, crcOK bool, err error) {
+	name, length, err := cr.header()
+	if err != nil {
+		return "", nil, false, err
+	}
+	data, crcOK, err = cr.body(name, length)
+	return name, data, crcOK, err
+}
+
+// header reads the header of the next chunk, returning its type and the
+// length of its data. It returns io.EOF only if the stream ends cleanly
+// before the header.
+func (cr *chunkReader) header() (string, uint32, error) {
+	if _, err := io.ReadFull(cr.r, cr.tmp[:8]); err != nil {
+		return "", 0, err
+	}
+	length := binary.BigEndian.Uint32(cr.tmp[:4])
+	if length > maxChunkLength {
+		return "", 0, ErrChunkTooLarge
+	}
+	return string(cr.tmp[4:8]), length, nil
+}
+
+// body reads the data and checksum of the chunk whose header was just
+// read, and reports whether the checksum matches.
+func (cr *chunkReader) body(name string, length uint32) ([]byte, bool, error) {
+	// Grow the buffer as data arrives rather than trusting length up front,
+	// so a corrupt header can't force a huge allocation.
+	bb := new(bytes.Buffer)
+	if _, err := io.CopyN(bb, cr.r, int64(length)); err != nil {
+		return nil, false, unexpectedEOF(err)
+	}
+	data := bb.Bytes()
+
+	if _, err := io.ReadFull(cr.r, cr.tmp[:4]); err != nil {
+		return nil, false, unexpectedEOF(err)
+	}
+	if cr.skipCRC {
+		return data, true, nil
+	}
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(name))
+	crc.Write(data)
+	return data, crc.Sum32() == binary.BigEndian.Uint32(cr.tmp[:4]), nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// ChunkWriter writes a PNG stream one chunk at a time, framing each chunk
+// with its length and checksum, for building custom muxers or adding
+// chunks this package doesn't write. The PNG signature is written before
+// the first chunk. Chunks are written as given: their order, and the
+// sequence numbers of fcTL and fdAT chunks, are up to the caller.
+type ChunkWriter struct {
+	e       encoder
+	started bool
+}
+
+// NewChunkWriter returns a ChunkWriter writing to w.
+func NewChunkWriter(w io.Writer) *ChunkWriter {
+	return &ChunkWriter{e: encoder{w: w}}
+}
+
+// WriteChunk writes a chunk of type typ, such as "tEXt", holding data.
+// After an error, every later call returns the same error.
+func (cw *ChunkWriter) WriteChunk(typ string, data []byte) error {
+	if cw.e.err != nil {
+		return cw.e.err
+	}
+	if !validChunkType(typ) {
+		return fmt.Errorf("apng: invalid chunk type %q", typ)
+	}
+	if !cw.started {
+		cw.started = true
+		if _, err := io.WriteString(cw.e.w, pngHeader); err != nil {
+			cw.e.err = err
+			return err
+		}
+	}
+	cw.e.writeChunk(data, typ)
+	return cw.e.err
+}
+
+// validChunkType reports whether typ is four ASCII letters, as the PNG
+// spec requires of chunk types.
+func validChunkType(typ string) bool {
+	if len(typ) != 4 {
+		return false
+	}
+	for i := 0; i < 4; i++ {
+		if c := typ[i] | 0x20; c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// ChunkInfo describes a chunk of a PNG stream.
+type ChunkInfo struct {
+	Offset int64  // Offset of the chunk, at its length field, from the start of the stream.
+	Type   string // The chunk type, such as "fcTL".
+	Length uint32 // The length of the chunk data.
+	CRCOK  bool   // Whether the checksum matches the chunk.
+
+	// Data is the chunk data, except for the image data of IDAT and fdAT
+	// chunks, which is only checksummed: it is nil for IDAT chunks and
+	// holds only the sequence number of fdAT chunks.
+	Data []byte
+}
+
+// ReadChunks reads the PNG stream from r, up to and including IEND, and
+// describes each of its chunks. It checks the framing of the stream only:
+// chunks are not interpreted, and a bad checksum is reported in CRCOK
+// rather than as an error, so that broken files can be examined. On error
+// the chunks read so far are returned with it.
+func ReadChunks(r io.Reader) ([]ChunkInfo, error) {
+	cr := newChunkReader(r)
+	if err := cr.readSignature(); err != nil {
+		return nil, err
+	}
+
+	var chunks []ChunkInfo
+	off := int64(len(pngHeader))
+	for {
+		if _, err := io.ReadFull(cr.r, cr.tmp[:8]); err != nil {
+			return chunks, unexpectedEOF(err)
+		}
+		c := ChunkInfo{
+			Offset: off,
+			Type:   string(cr.tmp[4:8]),
+			Length: binary.BigEndian.Uint32(cr.tmp[:4]),
+		}
+		if c.Length > maxChunkLength {
+			return chunks, fmt.Errorf("%w: %s chunk at offset %d", ErrChunkTooLarge, c.Type, off)
+		}
+
+		keep := int64(c.Length)
+		switch c.Type {
+		case "IDAT":
+			keep = 0
+		case "fdAT":
+			if keep > 4 {
+				keep = 4
+			}
+		}
+		// Grow the buffer as data arrives, as chunkReader does.
+		crc := crc32.NewIEEE()
+		crc.Write(cr.tmp[4:8])
+		var data bytes.Buffer
+		if _, err := io.CopyN(io.MultiWriter(crc, &data), cr.r, keep); err != nil {
+			return chunks, unexpectedEOF(err)
+		}
+		if _, err := io.CopyN(crc, cr.r, int64(c.Length)-keep); err != nil {
+			return chunks, unexpectedEOF(err)
+		}
+		if keep > 0 {
+			c.Data = data.Bytes()
+		}
+		if _, err := io.ReadFull(cr.r, cr.tmp[:4]); err != nil {
+			return chunks, unexpectedEOF(err)
+		}
+		c.CRCOK = crc.Sum32() == binary.BigEndian.Uint32(cr.tmp[:4])
+
+		chunks = append(chunks, c)
+		off += 12 + int64(c.Length)
+		if c.Type == "IEND" {
+			return chunks, nil
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package goapng
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestReadChunks(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeAll(&buf, testAPNG(2, 4, 4)); err != nil {
+		t.Fatal(err)
+	}
+	file := buf.Bytes()
+	chunks, err := ReadChunks(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := readTestChunks(t, file)
+	if len(chunks) != len(want) {
+		t.Fatalf("got %d chunks, want %d", len(chunks), len(want))
+	}
+	off := int64(len(pngHeader))
+	for i, c := range chunks {
+		w := want[i]
+		if c.Type != w.name || c.Length != uint32(len(w.data)) || c.Offset != off || !c.CRCOK {
+			t.Errorf("chunk %d = %+v, want %s of %d bytes at %d", i, c, w.name, len(w.data), off)
+		}
+		var data []byte
+		switch c.Type {
+		case "IDAT":
+		case "fdAT":
+			data = w.data[:4]
+		default:
+			data = w.data
+		}
+		if !bytes.Equal(c.Data, data) {
+			t.Errorf("chunk %d (%s): Data = %x, want %x", i, c.Type, c.Data, data)
+		}
+		off += 12 + int64(c.Length)
+	}
+
+	// A bad checksum is reported, not fatal.
+	bad := append([]byte(nil), file...)
+	bad[len(pngHeader)+8] ^= 1 // The first byte of the IHDR data.
+	chunks, err = ReadChunks(bytes.NewReader(bad))
+	if err != nil || chunks[0].CRCOK || !chunks[1].CRCOK {
+		t.Errorf("flipped IHDR bit: got CRCOK %v, %v and error %v", chunks[0].CRCOK, chunks[1].CRCOK, err)
+	}
+}
+
+func TestReadChunksBroken(t *testing.T) {
+	header := func(length uint32, typ string) []byte {
+		b := make([]byte, 8)
+		writeUint32(b, length)
+		copy(b[4:], typ)
+		return b
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"not a PNG", []byte("GIF89a..."), nil},
+		{"no chunks", []byte(pngHeader), io.ErrUnexpectedEOF},
+		{"huge length", append([]byte(pngHeader), header(maxChunkLength, "tEXt")...), io.ErrUnexpectedEOF},
+		{"too large", append([]byte(pngHeader), header(maxChunkLength+1, "tEXt")...), ErrChunkTooLarge},
+		{"missing CRC", append(append([]byte(pngHeader), header(2, "tEXt")...), 'a', 'b'), io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		_, err := ReadChunks(bytes.NewReader(tt.data))
+		if err == nil || tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: got error %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package goapng
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// chunkOrder checks that the chunks of a file come in an order the PNG and
+// APNG specs allow and that their sequence numbers run on from one fcTL or
+// fdAT chunk to the next. The decoder and VerifyStream share it, so that
+// they agree on what is out of order.
+type chunkOrder struct {
+	lastName  string
+	seenIHDR  bool
+	seenacTL  bool
+	seenPLTE  bool
+	seentRNS  bool
+	seenIDAT  bool
+	idatFrame bool   // Whether the IDAT data is the first frame.
+	frames    int    // The number of frames, less those dropped for having no data.
+	frameData bool   // Whether the current frame has data.
+	seq       uint32 // The next sequence number.
+}
+
+// check checks chunk name, whose data starts with head, against the chunks
+// before it and returns the problems found. skip reports whether the chunk
+// cannot be used where it is; it is then left out of the state, but for
+// its sequence number. A wrong sequence number restarts the count, so that
+// a missing chunk is reported once rather than at every chunk after it.
+func (c *chunkOrder) check(name string, head []byte) (problems []string, skip bool) {
+	defer func() { c.lastName = name }()
+	problem := func(msg string, skips bool) {
+		problems = append(problems, msg)
+		skip = skip || skips
+	}
+
+	if c.lastName == "" && name != "IHDR" {
+		problem("first chunk is not IHDR", false)
+	}
+	if name == "IDAT" && c.seenIDAT && c.lastName != "IDAT" {
+		problem("IDAT chunks are not consecutive", false)
+	}
+
+	switch name {
+	case "IHDR":
+		if c.seenIHDR {
+			problem("more than one IHDR", true)
+		}
+	case "acTL":
+		switch {
+		case c.seenacTL:
+			problem("more than one acTL", true)
+		case c.seenIDAT:
+			problem("acTL after IDAT", true)
+		}
+	case "PLTE":
+		switch {
+		case c.seenPLTE:
+			problem("more than one PLTE", true)
+		case c.seenIDAT:
+			problem("PLTE after IDAT", true)
+		}
+	case "tRNS":
+		switch {
+		case c.seentRNS:
+			problem("more than one tRNS", true)
+		case c.seenIDAT:
+			problem("tRNS after IDAT", true)
+		}
+	case "bKGD":
+		if c.seenIDAT {
+			problem("bKGD after IDAT", true)
+		}
+	case "fcTL":
+		if !c.seenacTL {
+			// Without acTL the file is a static PNG, and the decoder
+			// discards the frames at IEND.
+			problem("fcTL without acTL", false)
+		}
+		if c.frames > 0 && !c.frameData {
+			problem(fmt.Sprintf("frame %d has no data", c.frames-1), false)
+		}
+	case "fdAT":
+		switch {
+		case c.frames == 0:
+			problem("fdAT before fcTL", true)
+		case !c.seenIDAT:
+			problem("fdAT before IDAT", true)
+		case c.frames == 1 && c.idatFrame:
+			problem("fdAT in the frame stored in IDAT", true)
+		}
+	case "IEND":
+		if c.frames > 0 && !c.frameData {
+			problem(fmt.Sprintf("frame %d has no data", c.frames-1), false)
+		}
+	}
+
+	if (name == "fcTL" || name == "fdAT") && len(head) >= 4 {
+		if n := binary.BigEndian.Uint32(head[0:4]); n != c.seq {
+			problem(fmt.Sprintf("sequence number %d, want %d", n, c.seq), false)
+			c.seq = n
+		}
+		c.seq++
+	}
+	if skip {
+		return problems, skip
+	}
+
+	switch name {
+	case "IHDR":
+		c.seenIHDR = true
+	case "acTL":
+		c.seenacTL = true
+	case "PLTE":
+		c.seenPLTE = true
+	case "tRNS":
+		c.seentRNS = true
+	case "fcTL":
+		// A frame with no data is dropped, and this one takes its place.
+		if c.frames == 0 || c.frameData {
+			c.frames++
+		}
+		c.frameData = false
+	case "IDAT":
+		if !c.seenIDAT {
+			c.idatFrame = c.frames == 1
+		}
+		c.seenIDAT = true
+		c.frameData = true
+	case "fdAT":
+		c.frameData = true
+	}
+	return problems, skip
+}
@@ -0,0 +1,113 @@
+package goapng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestChunkOrderAgrees(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeAll(&buf, testAPNG(3, 6, 6)); err != nil {
+		t.Fatal(err)
+	}
+	file := buf.Bytes()
+	chunks := readTestChunks(t, file)
+	// insert adds c to file after the first chunk named after, or before
+	// every chunk if after is "".
+	insert := func(after string, c testChunk) []byte {
+		var out bytes.Buffer
+		cw := NewChunkWriter(&out)
+		if after == "" {
+			cw.WriteChunk(c.name, c.data)
+		}
+		for _, tc := range chunks {
+			cw.WriteChunk(tc.name, tc.data)
+			if tc.name == after {
+				cw.WriteChunk(c.name, c.data)
+				after = ""
+			}
+		}
+		return out.Bytes()
+	}
+	// The fdAT of frame 1.
+	fdAT := chunks[5]
+	if fdAT.name != "fdAT" {
+		t.Fatalf("chunk 5 is %s, want fdAT", fdAT.name)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"two acTL", insert("acTL", chunks[1]), "more than one acTL"},
+		{"bKGD after IDAT", insert("IDAT", testChunk{"bKGD", make([]byte, 6)}), "bKGD after IDAT"},
+		{"IHDR not first", insert("", testChunk{"tEXt", []byte("Comment\x00first")}), "first chunk is not IHDR"},
+		{"fcTL without acTL", rewriteChunks(t, file, func(c *testChunk) bool { return c.name != "acTL" }), "fcTL without acTL"},
+		{"sequence gap", bumpSequence(t, file), "sequence number 10, want 0"},
+		{"fdAT in the IDAT frame", insert("IDAT", fdAT), "fdAT in the frame stored in IDAT"},
+	}
+	for _, tt := range tests {
+		report, err := VerifyStream(bytes.NewReader(tt.data))
+		if err != nil {
+			t.Fatalf("%s: VerifyStream: %v", tt.name, err)
+		}
+		if report.OK() || report.Problems[0].Message != tt.want {
+			t.Errorf("%s: VerifyStream found %v, want %q first", tt.name, report.Problems, tt.want)
+		}
+		_, err = DecodeAll(bytes.NewReader(tt.data))
+		if err == nil || !strings.HasSuffix(err.Error(), ": "+tt.want) {
+			t.Errorf("%s: DecodeAll: got error %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestChunkOrderResync(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeAll(&buf, testAPNG(4, 6, 6)); err != nil {
+		t.Fatal(err)
+	}
+	// Skip sequence number 3, as if a chunk had been lost: every chunk from
+	// the third frame on is numbered one too high.
+	file := rewriteChunks(t, buf.Bytes(), func(c *testChunk) bool {
+		if c.name == "fcTL" || c.name == "fdAT" {
+			if n := binary.BigEndian.Uint32(c.data[:4]); n >= 3 {
+				writeUint32(c.data[:4], n+1)
+			}
+		}
+		return true
+	})
+
+	report, err := VerifyStream(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Problems) != 1 || report.Problems[0].Message != "sequence number 4, want 3" {
+		t.Errorf("VerifyStream found %v, want one sequence problem", report.Problems)
+	}
+
+	decodes := []struct {
+		name   string
+		decode func(dec *Decoder) (*APNG, error)
+	}{
+		{"DecodeAll", func(dec *Decoder) (*APNG, error) { return dec.DecodeAll(bytes.NewReader(file)) }},
+		{"DecodeAllAt", func(dec *Decoder) (*APNG, error) { return dec.DecodeAllAt(bytes.NewReader(file)) }},
+	}
+	for _, d := range decodes {
+		var warnings []error
+		dec := &Decoder{Lenient: true, OnWarning: func(err error) { warnings = append(warnings, err) }}
+		a, err := d.decode(dec)
+		if err != nil {
+			t.Errorf("%s: %v", d.name, err)
+			continue
+		}
+		if len(a.Images) != 4 {
+			t.Errorf("%s: got %d frames, want 4", d.name, len(a.Images))
+		}
+		if len(warnings) != 1 {
+			t.Errorf("%s: got warnings %v, want one", d.name, warnings)
+		}
+	}
+}
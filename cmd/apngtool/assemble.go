@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/cia-rana/goapng"
+)
+
+var assembleCmd = &command{
+	name:  "assemble",
+	usage: "-o out.png [-d delay] [-loop n] frame.png... | -o out.png -m manifest.json",
+	short: "encode PNG files as the frames of an APNG",
+	run:   runAssemble,
+}
+
+func runAssemble(fs *flag.FlagSet, args []string) error {
+	out := fs.String("o", "", "output `file`")
+	delay := fs.Duration("d", 100*time.Millisecond, "frame `delay`")
+	loop := fs.Uint("loop", goapng.LoopForever, "number of plays, 0 for forever")
+	manifest := fs.String("m", "", "JSON `manifest` listing the frames and their timing")
+	paths, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if *manifest != "" {
+		if *out == "" || len(paths) != 0 {
+			return errUsage
+		}
+		// Frame files are named relative to the manifest.
+		fsys := os.DirFS(filepath.Dir(*manifest))
+		return createFile(*out, func(f *os.File) error {
+			return goapng.EncodeFromManifest(f, fsys, filepath.Base(*manifest))
+		})
+	}
+	if *out == "" || len(paths) == 0 {
+		return errUsage
+	}
+
+	a := &goapng.APNG{LoopCount: uint32(*loop)}
+	for _, path := range paths {
+		imgs, err := decodePNGs(path)
+		if err != nil {
+			return err
+		}
+		for _, img := range imgs {
+			a.Images = append(a.Images, img)
+			a.Durations = append(a.Durations, *delay)
+		}
+	}
+	for _, img := range a.Images[1:] {
+		if !reflect.DeepEqual(img.ColorModel(), a.Images[0].ColorModel()) {
+			toNRGBA(a.Images)
+			break
+		}
+	}
+	return createFile(*out, func(f *os.File) error {
+		return goapng.EncodeAll(f, a)
+	})
+}
+
+// toNRGBA converts every image of imgs to *image.NRGBA.
+func toNRGBA(imgs []image.Image) {
+	for i, img := range imgs {
+		m := image.NewNRGBA(img.Bounds())
+		draw.Draw(m, m.Rect, img, m.Rect.Min, draw.Src)
+		imgs[i] = m
+	}
+}
+
+// decodePNGs decodes the PNG file at path. A path of "-" stands for the
+// standard input, which may hold several PNG images one after another, as
+// written by ffmpeg's image2pipe format.
+func decodePNGs(path string) ([]image.Image, error) {
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		img, err := png.Decode(f)
+		if err != nil {
+			return nil, err
+		}
+		return []image.Image{img}, nil
+	}
+
+	var imgs []image.Image
+	r := bufio.NewReader(os.Stdin)
+	for {
+		if _, err := r.Peek(1); err == io.EOF {
+			return imgs, nil
+		}
+		img, err := png.Decode(r)
+		if err != nil {
+			return nil, err
+		}
+		imgs = append(imgs, img)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cia-rana/goapng"
+)
+
+// runCmd runs c with args, discarding its usage messages.
+func runCmd(c *command, args ...string) error {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return c.run(fs, args)
+}
+
+// writePNG writes m to a new PNG file in dir and returns its path.
+func writePNG(t *testing.T, dir, name string, m image.Image) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, m); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAssemble(t *testing.T) {
+	dir := t.TempDir()
+	gray := image.NewGray(image.Rect(0, 0, 3, 2))
+	rgba := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	rgba.SetNRGBA(1, 1, color.NRGBA{0xff, 0, 0, 0xff})
+	f0 := writePNG(t, dir, "f0.png", gray)
+	f1 := writePNG(t, dir, "f1.png", rgba)
+	out := filepath.Join(dir, "out.png")
+
+	if err := runCmd(assembleCmd, "-o", out, "-d", "40ms", "-loop", "3", f0, f1, f0); err != nil {
+		t.Fatal(err)
+	}
+	a := decodeFile(t, out)
+	if len(a.Images) != 3 || a.Config.Width != 3 || a.Config.Height != 2 {
+		t.Fatalf("got %d frames on a %dx%d canvas, want 3 on 3x2", len(a.Images), a.Config.Width, a.Config.Height)
+	}
+	for i := range a.Images {
+		if d := delay(a, i); d != 40*time.Millisecond {
+			t.Errorf("frame %d: delay %v, want 40ms", i, d)
+		}
+	}
+	if a.LoopCount != 3 {
+		t.Errorf("loop count %d, want 3", a.LoopCount)
+	}
+	// The frames of different models are converted to NRGBA.
+	frames, err := goapng.Composite(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := frames[1].RGBAAt(1, 1); got != (color.RGBA{0xff, 0, 0, 0xff}) {
+		t.Errorf("frame 1 at 1,1: %v, want red", got)
+	}
+	if got := frames[2].RGBAAt(1, 1); got != (color.RGBA{0, 0, 0, 0xff}) {
+		t.Errorf("frame 2 at 1,1: %v, want black", got)
+	}
+
+	for _, args := range [][]string{
+		{f0},
+		{"-o", out},
+		{"-o", out, "-m", "manifest.json", f0},
+	} {
+		if err := runCmd(assembleCmd, args...); !errors.Is(err, errUsage) {
+			t.Errorf("%v: got error %v, want errUsage", args, err)
+		}
+	}
+	// A failed encode leaves no output behind.
+	bad := filepath.Join(dir, "bad.png")
+	big := writePNG(t, dir, "big.png", image.NewGray(image.Rect(0, 0, 4, 4)))
+	if err := runCmd(assembleCmd, "-o", bad, f0, big); err == nil {
+		t.Error("frames larger than the canvas: got no error")
+	}
+	if _, err := os.Stat(bad); !os.IsNotExist(err) {
+		t.Errorf("failed output was kept: %v", err)
+	}
+	if err := runCmd(assembleCmd, "-o", out, filepath.Join(dir, "missing.png")); err == nil {
+		t.Error("missing frame: got no error")
+	}
+}
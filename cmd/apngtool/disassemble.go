@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+
+	"github.com/cia-rana/goapng"
+)
+
+var disassembleCmd = &command{
+	name:  "disassemble",
+	usage: "[-o dir] [-composited] in.png",
+	short: "write the frames of an APNG as PNG files with a timing manifest",
+	run:   runDisassemble,
+}
+
+func runDisassemble(fs *flag.FlagSet, args []string) error {
+	dir := fs.String("o", ".", "output `directory`")
+	composited := fs.Bool("composited", false, "write each frame as displayed, on the full canvas")
+	paths, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(paths) != 1 {
+		return errUsage
+	}
+
+	data, err := readFile(paths[0])
+	if err != nil {
+		return err
+	}
+	// Only the frame headers are needed for the manifest.
+	dec := goapng.Decoder{Lazy: true}
+	a, err := dec.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*dir, 0777); err != nil {
+		return err
+	}
+	if err := goapng.ExtractFrames(bytes.NewReader(data), *dir, *composited); err != nil {
+		return err
+	}
+
+	m := goapng.NewManifest(a, nil)
+	if *composited {
+		// Composited frames cover the canvas and replace each other.
+		for i := range m.Frames {
+			f := &m.Frames[i]
+			f.X, f.Y, f.Dispose, f.Blend = 0, 0, "", ""
+		}
+	}
+	return createFile(filepath.Join(*dir, "manifest.json"), func(f *os.File) error {
+		return goapng.WriteManifest(f, m)
+	})
+}
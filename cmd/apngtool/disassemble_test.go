@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cia-rana/goapng"
+)
+
+func TestDisassemble(t *testing.T) {
+	dir := t.TempDir()
+	// Frame 1 changes a 2x1 region, which Optimize stores on its own.
+	a := &goapng.APNG{LoopCount: 2}
+	for i, c := range []color.NRGBA{{0xff, 0, 0, 0xff}, {0, 0xff, 0, 0xff}} {
+		m := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+		for j := 0; j < len(m.Pix); j += 4 {
+			m.Pix[j], m.Pix[j+1], m.Pix[j+2], m.Pix[j+3] = 0xff, 0, 0, 0xff
+		}
+		m.SetNRGBA(1, 2, c)
+		m.SetNRGBA(2, 2, c)
+		a.Images = append(a.Images, m)
+		a.Durations = append(a.Durations, time.Duration(i+1)*50*time.Millisecond)
+	}
+	goapng.Optimize(a, goapng.OptimizeDelta)
+	in := filepath.Join(dir, "in.png")
+	f, err := os.Create(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := goapng.EncodeAll(f, a); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	want := decodeFile(t, in)
+
+	for _, composited := range []bool{false, true} {
+		out := filepath.Join(dir, "frames")
+		if composited {
+			out += "-composited"
+		}
+		args := []string{in, "-o", out}
+		if composited {
+			args = append(args, "-composited")
+		}
+		if err := runCmd(disassembleCmd, args...); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		data, err := os.ReadFile(filepath.Join(out, "manifest.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var m goapng.Manifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			t.Fatal(err)
+		}
+		if len(m.Frames) != 2 || m.Loop != 2 || m.Frames[0].File != "frame_000.png" || m.Frames[1].File != "frame_001.png" {
+			t.Fatalf("%v: got manifest %+v", args, m)
+		}
+		// Composited frames cover the canvas.
+		wantX, wantY := 1, 2
+		if composited {
+			wantX, wantY = 0, 0
+		}
+		if m.Frames[1].X != wantX || m.Frames[1].Y != wantY {
+			t.Errorf("%v: frame 1 at %d,%d, want %d,%d", args, m.Frames[1].X, m.Frames[1].Y, wantX, wantY)
+		}
+
+		// Assembling the manifest gives back the animation.
+		re := filepath.Join(dir, "re.png")
+		if err := runCmd(assembleCmd, "-o", re, "-m", filepath.Join(out, "manifest.json")); err != nil {
+			t.Fatalf("%v: assembling: %v", args, err)
+		}
+		if ok, diffs := goapng.EqualFrames(decodeFile(t, re), want, 0); !ok {
+			t.Errorf("%v: reassembled frames differ: %v", args, diffs)
+		}
+	}
+
+	for _, args := range [][]string{{}, {in, in}} {
+		if err := runCmd(disassembleCmd, args...); !errors.Is(err, errUsage) {
+			t.Errorf("%v: got error %v, want errUsage", args, err)
+		}
+	}
+	if err := runCmd(disassembleCmd, "-o", dir, filepath.Join(dir, "missing.png")); err == nil {
+		t.Error("missing file: got no error")
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"math"
+	"os"
+
+	"github.com/cia-rana/goapng"
+)
+
+var resizeCmd = &command{
+	name:  "resize",
+	usage: "-o out.png (-width w | -height h | -scale f) [-filter name] in.png",
+	short: "scale the canvas and frames of an APNG",
+	run:   runResize,
+}
+
+var cropCmd = &command{
+	name:  "crop",
+	usage: "-o out.png -rect x0,y0,x1,y1 in.png",
+	short: "crop the canvas of an APNG",
+	run:   runCrop,
+}
+
+var filters = map[string]goapng.Filter{
+	"nearest":    goapng.NearestNeighbor,
+	"bilinear":   goapng.Bilinear,
+	"catmullrom": goapng.CatmullRom,
+}
+
+func runResize(fs *flag.FlagSet, args []string) error {
+	out := fs.String("o", "", "output `file`")
+	width := fs.Int("width", 0, "canvas `width`; the height follows the aspect ratio if not set")
+	height := fs.Int("height", 0, "canvas `height`; the width follows the aspect ratio if not set")
+	scale := fs.Float64("scale", 0, "scale `factor`, instead of width and height")
+	filter := fs.String("filter", "catmullrom", "interpolation `filter`: nearest, bilinear or catmullrom")
+	paths, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	f, ok := filters[*filter]
+	if *out == "" || len(paths) != 1 || !ok || *width < 0 || *height < 0 || *scale < 0 ||
+		*scale > 0 && (*width > 0 || *height > 0) || *scale == 0 && *width == 0 && *height == 0 {
+		return errUsage
+	}
+
+	return editFile(*out, paths[0], func(a *goapng.APNG) error {
+		w, h := a.Config.Width, a.Config.Height
+		switch {
+		case *scale > 0:
+			w, h = scaled(w, *scale), scaled(h, *scale)
+		case *width > 0 && *height > 0:
+			w, h = *width, *height
+		case *width > 0:
+			w, h = *width, scaled(h, float64(*width)/float64(w))
+		default:
+			w, h = scaled(w, float64(*height)/float64(h)), *height
+		}
+		return goapng.Resize(a, w, h, f)
+	})
+}
+
+// scaled returns n scaled by f, rounded and at least 1.
+func scaled(n int, f float64) int {
+	s := int(math.Round(float64(n) * f))
+	if s < 1 {
+		s = 1
+	}
+	return s
+}
+
+func runCrop(fs *flag.FlagSet, args []string) error {
+	out := fs.String("o", "", "output `file`")
+	rect := fs.String("rect", "", "crop rectangle `x0,y0,x1,y1`")
+	paths, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if *out == "" || len(paths) != 1 || *rect == "" {
+		return errUsage
+	}
+	var r image.Rectangle
+	if _, err := fmt.Sscanf(*rect, "%d,%d,%d,%d", &r.Min.X, &r.Min.Y, &r.Max.X, &r.Max.Y); err != nil {
+		return errors.New("bad -rect " + *rect)
+	}
+
+	return editFile(*out, paths[0], func(a *goapng.APNG) error {
+		return goapng.CropCanvas(a, r)
+	})
+}
+
+// editFile decodes the APNG file in, calls edit with it and writes the
+// result to a new file out.
+func editFile(out, in string, edit func(a *goapng.APNG) error) error {
+	data, err := readFile(in)
+	if err != nil {
+		return err
+	}
+	a, err := goapng.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if err := edit(a); err != nil {
+		return err
+	}
+	return createFile(out, func(f *os.File) error {
+		return goapng.EncodeAll(f, a)
+	})
+}
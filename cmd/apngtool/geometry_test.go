@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestResize(t *testing.T) {
+	dir := t.TempDir()
+	in := writeTestAPNG(t, dir, 2)
+	out := filepath.Join(dir, "out.png")
+
+	tests := []struct {
+		args []string
+		w, h int
+	}{
+		{[]string{"-scale", "2"}, 8, 8},
+		{[]string{"-width", "6"}, 6, 6},
+		{[]string{"-height", "2", "-filter", "nearest"}, 2, 2},
+		{[]string{"-width", "8", "-height", "3", "-filter", "bilinear"}, 8, 3},
+		{[]string{"-scale", "0.01"}, 1, 1},
+	}
+	for _, tt := range tests {
+		args := append(tt.args, "-o", out, in)
+		if err := runCmd(resizeCmd, args...); err != nil {
+			t.Errorf("%v: %v", args, err)
+			continue
+		}
+		a := decodeFile(t, out)
+		if len(a.Images) != 2 || a.Config.Width != tt.w || a.Config.Height != tt.h {
+			t.Errorf("%v: got %d frames on a %dx%d canvas, want 2 on %dx%d", args, len(a.Images), a.Config.Width, a.Config.Height, tt.w, tt.h)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"-o", out, in},
+		{"-scale", "2", in},
+		{"-scale", "2", "-o", out},
+		{"-scale", "2", "-width", "3", "-o", out, in},
+		{"-width", "-3", "-o", out, in},
+		{"-scale", "2", "-filter", "lanczos", "-o", out, in},
+	} {
+		if err := runCmd(resizeCmd, args...); !errors.Is(err, errUsage) {
+			t.Errorf("%v: got error %v, want errUsage", args, err)
+		}
+	}
+}
+
+func TestCrop(t *testing.T) {
+	dir := t.TempDir()
+	in := writeTestAPNG(t, dir, 2)
+	out := filepath.Join(dir, "out.png")
+
+	if err := runCmd(cropCmd, "-rect", "1,0,4,2", "-o", out, in); err != nil {
+		t.Fatal(err)
+	}
+	a := decodeFile(t, out)
+	if len(a.Images) != 2 || a.Config.Width != 3 || a.Config.Height != 2 {
+		t.Errorf("got %d frames on a %dx%d canvas, want 2 on 3x2", len(a.Images), a.Config.Width, a.Config.Height)
+	}
+
+	for _, args := range [][]string{
+		{"-o", out, in},
+		{"-rect", "0,0,1,1", in},
+	} {
+		if err := runCmd(cropCmd, args...); !errors.Is(err, errUsage) {
+			t.Errorf("%v: got error %v, want errUsage", args, err)
+		}
+	}
+	for _, rect := range []string{"1,2,3", "5,5,9,9"} {
+		if err := runCmd(cropCmd, "-rect", rect, "-o", out, in); err == nil {
+			t.Errorf("-rect %s: got no error", rect)
+		}
+	}
+}
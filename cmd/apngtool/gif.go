@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"image/gif"
+	"os"
+
+	"github.com/cia-rana/goapng"
+)
+
+var gif2apngCmd = &command{
+	name:  "gif2apng",
+	usage: "-o out.png in.gif",
+	short: "convert an animated GIF to APNG",
+	run:   runGIF2APNG,
+}
+
+var apng2gifCmd = &command{
+	name:  "apng2gif",
+	usage: "-o out.gif in.png",
+	short: "convert an APNG to an animated GIF",
+	run:   runAPNG2GIF,
+}
+
+func runGIF2APNG(fs *flag.FlagSet, args []string) error {
+	out := fs.String("o", "", "output `file`")
+	paths, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if *out == "" || len(paths) != 1 {
+		return errUsage
+	}
+
+	f, err := openFile(paths[0])
+	if err != nil {
+		return err
+	}
+	g, err := gif.DecodeAll(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	a, err := goapng.FromGIF(g)
+	if err != nil {
+		return err
+	}
+	return createFile(*out, func(f *os.File) error {
+		return goapng.EncodeAll(f, a)
+	})
+}
+
+func runAPNG2GIF(fs *flag.FlagSet, args []string) error {
+	out := fs.String("o", "", "output `file`")
+	paths, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if *out == "" || len(paths) != 1 {
+		return errUsage
+	}
+
+	f, err := openFile(paths[0])
+	if err != nil {
+		return err
+	}
+	a, err := goapng.DecodeAll(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	g, err := goapng.ToGIF(a, nil)
+	if err != nil {
+		return err
+	}
+	return createFile(*out, func(f *os.File) error {
+		return gif.EncodeAll(f, g)
+	})
+}
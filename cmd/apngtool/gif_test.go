@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGIFConversion(t *testing.T) {
+	dir := t.TempDir()
+	p := color.Palette{color.RGBA{0xff, 0, 0, 0xff}, color.RGBA{0, 0, 0xff, 0xff}}
+	var g gif.GIF
+	for i := 0; i < 3; i++ {
+		m := image.NewPaletted(image.Rect(0, 0, 4, 3), p)
+		for j := range m.Pix {
+			m.Pix[j] = uint8(i % 2)
+		}
+		g.Image = append(g.Image, m)
+		g.Delay = append(g.Delay, 10*(i+1))
+	}
+	g.LoopCount = 1
+	in := filepath.Join(dir, "in.gif")
+	f, err := os.Create(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gif.EncodeAll(f, &g); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	png := filepath.Join(dir, "out.png")
+	if err := runCmd(gif2apngCmd, in, "-o", png); err != nil {
+		t.Fatal(err)
+	}
+	a := decodeFile(t, png)
+	if len(a.Images) != 3 || a.Config.Width != 4 || a.Config.Height != 3 {
+		t.Fatalf("gif2apng: got %d frames on a %dx%d canvas, want 3 on 4x3", len(a.Images), a.Config.Width, a.Config.Height)
+	}
+	if a.LoopCount != 2 {
+		t.Errorf("gif2apng: loop count %d, want 2", a.LoopCount)
+	}
+
+	back := filepath.Join(dir, "back.gif")
+	if err := runCmd(apng2gifCmd, "-o", back, png); err != nil {
+		t.Fatal(err)
+	}
+	f, err = os.Open(back)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	got, err := gif.DecodeAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Image) != 3 || got.LoopCount != 1 {
+		t.Fatalf("apng2gif: got %d frames, loop count %d; want 3 and 1", len(got.Image), got.LoopCount)
+	}
+	for i := range got.Image {
+		if got.Delay[i] != g.Delay[i] {
+			t.Errorf("apng2gif: frame %d: delay %d, want %d", i, got.Delay[i], g.Delay[i])
+		}
+		if c, want := color.RGBAModel.Convert(got.Image[i].At(2, 1)), p[i%2]; c != want {
+			t.Errorf("apng2gif: frame %d: color %v, want %v", i, c, want)
+		}
+	}
+
+	for _, c := range []*command{gif2apngCmd, apng2gifCmd} {
+		for _, args := range [][]string{{in}, {"-o", png}} {
+			if err := runCmd(c, args...); !errors.Is(err, errUsage) {
+				t.Errorf("%s %v: got error %v, want errUsage", c.name, args, err)
+			}
+		}
+	}
+	// Each command rejects the other's input.
+	if err := runCmd(gif2apngCmd, "-o", filepath.Join(dir, "x.png"), png); err == nil {
+		t.Error("gif2apng of a PNG: got no error")
+	}
+	if err := runCmd(apng2gifCmd, "-o", filepath.Join(dir, "x.gif"), in); err == nil {
+		t.Error("apng2gif of a GIF: got no error")
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cia-rana/goapng"
+)
+
+var infoCmd = &command{
+	name:  "info",
+	usage: "[-json] file.png",
+	short: "print the dimensions, frames and timing of an APNG",
+	run:   runInfo,
+}
+
+type info struct {
+	Width      int            `json:"width"`
+	Height     int            `json:"height"`
+	NumFrames  int            `json:"num_frames"`
+	LoopCount  uint32         `json:"loop_count"`
+	DurationMS float64        `json:"duration_ms"`
+	FileSize   int64          `json:"file_size"`
+	ChunkBytes map[string]int `json:"chunk_bytes"` // Bytes per chunk type, including chunk framing.
+	Frames     []infoFrame    `json:"frames"`
+}
+
+type infoFrame struct {
+	X          int     `json:"x"`
+	Y          int     `json:"y"`
+	Width      int     `json:"width"`
+	Height     int     `json:"height"`
+	DelayNum   uint16  `json:"delay_num"`
+	DelayDen   uint16  `json:"delay_den"`
+	DurationMS float64 `json:"duration_ms"`
+	DisposeOp  string  `json:"dispose_op"`
+	BlendOp    string  `json:"blend_op"`
+}
+
+func runInfo(fs *flag.FlagSet, args []string) error {
+	asJSON := fs.Bool("json", false, "print JSON")
+	paths, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(paths) != 1 {
+		return errUsage
+	}
+
+	data, err := readFile(paths[0])
+	if err != nil {
+		return err
+	}
+	in, err := readInfo(data)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(in)
+	}
+	printInfo(in)
+	return nil
+}
+
+func readInfo(data []byte) (*info, error) {
+	in := &info{
+		FileSize:   int64(len(data)),
+		ChunkBytes: make(map[string]int),
+	}
+	chunks, err := goapng.ReadChunks(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range chunks {
+		in.ChunkBytes[c.Type] += 12 + int(c.Length)
+	}
+
+	// Only the frame headers are needed.
+	dec := goapng.Decoder{Lazy: true}
+	a, err := dec.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	in.Width, in.Height = a.Config.Width, a.Config.Height
+	in.NumFrames = len(a.Images)
+	in.LoopCount = a.LoopCount
+	in.DurationMS = ms(a.Duration())
+	ts := append(a.Timestamps(), a.Duration())
+	for i, img := range a.Images {
+		b := img.Bounds()
+		in.Frames = append(in.Frames, infoFrame{
+			X:          b.Min.X,
+			Y:          b.Min.Y,
+			Width:      b.Dx(),
+			Height:     b.Dy(),
+			DelayNum:   a.Delays[i],
+			DelayDen:   a.DelayDens[i],
+			DurationMS: ms(ts[i+1] - ts[i]),
+			DisposeOp:  goapng.DisposeOpName(a.Disposals[i]),
+			BlendOp:    goapng.BlendOpName(a.Blends[i]),
+		})
+	}
+	return in, nil
+}
+
+func printInfo(in *info) {
+	loop := fmt.Sprint(in.LoopCount)
+	if in.LoopCount == goapng.LoopForever {
+		loop = "forever"
+	}
+	fmt.Printf("size:     %dx%d\n", in.Width, in.Height)
+	fmt.Printf("frames:   %d\n", in.NumFrames)
+	fmt.Printf("plays:    %s\n", loop)
+	fmt.Printf("duration: %v\n", time.Duration(in.DurationMS*float64(time.Millisecond)))
+	fmt.Printf("file:     %d bytes\n", in.FileSize)
+
+	names := make([]string, 0, len(in.ChunkBytes))
+	for name := range in.ChunkBytes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHUNK\tBYTES")
+	for _, name := range names {
+		fmt.Fprintf(tw, "%s\t%d\n", name, in.ChunkBytes[name])
+	}
+	tw.Flush()
+
+	fmt.Println()
+	tw = tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "FRAME\tREGION\tDELAY\tDISPOSE\tBLEND")
+	for i, f := range in.Frames {
+		fmt.Fprintf(tw, "%d\t%dx%d+%d+%d\t%d/%d (%gms)\t%s\t%s\n",
+			i, f.Width, f.Height, f.X, f.Y, f.DelayNum, f.DelayDen, f.DurationMS, f.DisposeOp, f.BlendOp)
+	}
+	tw.Flush()
+}
+
+// ms returns d in milliseconds.
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInfo(t *testing.T) {
+	dir := t.TempDir()
+	in := writeTestAPNG(t, dir, 3)
+	data, err := os.ReadFile(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := captureStdout(t, func() error { return runCmd(infoCmd, "-json", in) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got info
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("decoding %q: %v", out, err)
+	}
+	if got.Width != 4 || got.Height != 4 || got.NumFrames != 3 || got.LoopCount != 2 || got.FileSize != int64(len(data)) {
+		t.Errorf("got %+v", got)
+	}
+	if got.DurationMS != 100 {
+		t.Errorf("duration %vms, want 100ms", got.DurationMS)
+	}
+	total := 8 // The PNG signature.
+	for _, n := range got.ChunkBytes {
+		total += n
+	}
+	if total != len(data) || got.ChunkBytes["IEND"] != 12 || got.ChunkBytes["fcTL"] != 3*38 {
+		t.Errorf("chunk bytes %v, want %d in total", got.ChunkBytes, len(data)-8)
+	}
+	if len(got.Frames) != 3 {
+		t.Fatalf("got %d frames, want 3", len(got.Frames))
+	}
+	want := infoFrame{Width: 4, Height: 4, DelayNum: 1, DelayDen: 30, DisposeOp: "none", BlendOp: "source"}
+	for i, f := range got.Frames {
+		d := f.DurationMS
+		f.DurationMS = 0
+		if f != want || d < 33 || d > 34 {
+			t.Errorf("frame %d: %+v, duration %vms", i, f, d)
+		}
+	}
+
+	out, err = captureStdout(t, func() error { return runCmd(infoCmd, in) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"size:     4x4", "frames:   3", "plays:    2", "duration: 100ms", "1/30"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("output lacks %q:\n%s", s, out)
+		}
+	}
+
+	// A static PNG is one frame shown forever.
+	static := writePNG(t, dir, "static.png", image.NewGray(image.Rect(0, 0, 5, 2)))
+	out, err = captureStdout(t, func() error { return runCmd(infoCmd, static) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"size:     5x2", "frames:   1", "plays:    forever"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("static PNG: output lacks %q:\n%s", s, out)
+		}
+	}
+
+	if in, err := readInfo(data[:len(data)/2]); err == nil {
+		t.Errorf("truncated file: got %+v and no error", in)
+	}
+
+	for _, args := range [][]string{{}, {in, in}} {
+		if err := runCmd(infoCmd, args...); !errors.Is(err, errUsage) {
+			t.Errorf("%v: got error %v, want errUsage", args, err)
+		}
+	}
+	if err := runCmd(infoCmd, filepath.Join(dir, "missing.png")); err == nil {
+		t.Error("missing file: got no error")
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/cia-rana/goapng"
+)
+
+var inspectCmd = &command{
+	name:  "inspect",
+	usage: "file.png",
+	short: "list the chunks and frames of a PNG or APNG file",
+	run:   runInspect,
+}
+
+func runInspect(fs *flag.FlagSet, args []string) error {
+	paths, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(paths) != 1 {
+		return errUsage
+	}
+	f, err := openFile(paths[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return inspect(os.Stdout, bufio.NewReader(f))
+}
+
+// inspect writes a listing of the chunks read from r to w.
+func inspect(w io.Writer, r io.Reader) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "OFFSET\tCHUNK\tLENGTH\tCRC\tFIELDS")
+	var frames []int64 // Compressed bytes of each frame.
+	chunks, err := goapng.ReadChunks(r)
+	for _, c := range chunks {
+		switch c.Type {
+		case "fcTL":
+			frames = append(frames, 0)
+		case "IDAT":
+			if len(frames) > 0 {
+				frames[len(frames)-1] += int64(c.Length)
+			}
+		case "fdAT":
+			if len(frames) > 0 && c.Length >= 4 {
+				frames[len(frames)-1] += int64(c.Length - 4)
+			}
+		}
+		crc := "ok"
+		if !c.CRCOK {
+			crc = "BAD"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%s\t%s\n", c.Offset, c.Type, c.Length, crc, chunkFields(c.Type, c.Data))
+	}
+	tw.Flush()
+
+	if len(frames) > 0 {
+		fmt.Fprintln(w)
+		tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "FRAME\tCOMPRESSED BYTES")
+		for i, n := range frames {
+			fmt.Fprintf(tw, "%d\t%d\n", i, n)
+		}
+		tw.Flush()
+	}
+	return err
+}
+
+// chunkFields describes the fields of the chunks that control the image
+// and the animation, from the data kept by goapng.ReadChunks.
+func chunkFields(name string, b []byte) string {
+	switch name {
+	case "IHDR":
+		if len(b) != 13 {
+			return "bad length"
+		}
+		return fmt.Sprintf("width=%d height=%d depth=%d color_type=%d interlace=%d",
+			binary.BigEndian.Uint32(b[0:4]), binary.BigEndian.Uint32(b[4:8]), b[8], b[9], b[12])
+	case "acTL":
+		if len(b) != 8 {
+			return "bad length"
+		}
+		return fmt.Sprintf("num_frames=%d num_plays=%d",
+			binary.BigEndian.Uint32(b[0:4]), binary.BigEndian.Uint32(b[4:8]))
+	case "fcTL":
+		if len(b) != 26 {
+			return "bad length"
+		}
+		return fmt.Sprintf("seq=%d width=%d height=%d x=%d y=%d delay=%d/%d dispose_op=%s blend_op=%s",
+			binary.BigEndian.Uint32(b[0:4]),
+			binary.BigEndian.Uint32(b[4:8]), binary.BigEndian.Uint32(b[8:12]),
+			binary.BigEndian.Uint32(b[12:16]), binary.BigEndian.Uint32(b[16:20]),
+			binary.BigEndian.Uint16(b[20:22]), binary.BigEndian.Uint16(b[22:24]),
+			goapng.DisposeOpName(b[24]), goapng.BlendOpName(b[25]))
+	case "fdAT":
+		if len(b) < 4 {
+			return "bad length"
+		}
+		return fmt.Sprintf("seq=%d", binary.BigEndian.Uint32(b[0:4]))
+	case "PLTE":
+		return fmt.Sprintf("entries=%d", len(b)/3)
+	}
+	return ""
+}
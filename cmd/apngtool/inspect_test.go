@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	dir := t.TempDir()
+	data, err := os.ReadFile(writeTestAPNG(t, dir, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := inspect(&buf, bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"OFFSET  CHUNK",
+		"width=4 height=4 depth=8",
+		"num_frames=2 num_plays=2",
+		"seq=0 width=4 height=4 x=0 y=0 delay=1/30 dispose_op=none blend_op=source",
+		"fdAT",
+		"IEND",
+		"FRAME  COMPRESSED BYTES",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "BAD") {
+		t.Errorf("output reports a bad CRC:\n%s", out)
+	}
+
+	// A damaged CRC is reported, and the chunks before a truncation are
+	// listed with the error.
+	bad := append([]byte(nil), data...)
+	bad[len(bad)-1] ^= 0xff
+	buf.Reset()
+	if err := inspect(&buf, bytes.NewReader(bad)); err != nil {
+		t.Fatal(err)
+	}
+	var iend []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if f := strings.Fields(line); len(f) > 1 && f[1] == "IEND" {
+			iend = f
+		}
+	}
+	if len(iend) != 4 || iend[3] != "BAD" {
+		t.Errorf("damaged CRC not reported:\n%s", buf.String())
+	}
+	buf.Reset()
+	if err := inspect(&buf, bytes.NewReader(data[:len(data)-20])); err == nil {
+		t.Error("truncated file: got no error")
+	}
+	if !strings.Contains(buf.String(), "IHDR") {
+		t.Errorf("truncated file: chunks before the end not listed:\n%s", buf.String())
+	}
+
+	for _, args := range [][]string{{}, {"a.png", "b.png"}} {
+		if err := runCmd(inspectCmd, args...); !errors.Is(err, errUsage) {
+			t.Errorf("%v: got error %v, want errUsage", args, err)
+		}
+	}
+	if err := runCmd(inspectCmd, filepath.Join(dir, "missing.png")); err == nil {
+		t.Error("missing file: got no error")
+	}
+}
+
+func TestChunkFields(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"IHDR", make([]byte, 12), "bad length"},
+		{"acTL", []byte{0, 0, 0, 3, 0, 0, 0, 0}, "num_frames=3 num_plays=0"},
+		{"fdAT", []byte{0, 0, 0, 7, 1, 2}, "seq=7"},
+		{"fdAT", []byte{0, 0}, "bad length"},
+		{"PLTE", make([]byte, 12), "entries=4"},
+		{"tEXt", []byte("Comment\x00hi"), ""},
+	}
+	for _, tt := range tests {
+		if got := chunkFields(tt.name, tt.data); got != tt.want {
+			t.Errorf("chunkFields(%s, % x) = %q, want %q", tt.name, tt.data, got, tt.want)
+		}
+	}
+}
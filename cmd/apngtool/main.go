@@ -0,0 +1,151 @@
+// Command apngtool creates, inspects and converts APNG images.
+//
+// Usage:
+//
+//	apngtool <command> [flags] [arguments]
+//
+// Run "apngtool <command> -h" for the flags of a command. A file name of
+// "-" stands for the standard input or output, so that apngtool can be
+// used in pipelines.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cia-rana/goapng"
+)
+
+// A command is an apngtool subcommand.
+type command struct {
+	name  string
+	usage string // The arguments, after the command name.
+	short string // A one-line description.
+	run   func(fs *flag.FlagSet, args []string) error
+}
+
+var commands = []*command{
+	assembleCmd,
+	disassembleCmd,
+	infoCmd,
+	inspectCmd,
+	verifyCmd,
+	optimizeCmd,
+	gif2apngCmd,
+	apng2gifCmd,
+	retimeCmd,
+	resizeCmd,
+	cropCmd,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+		fs.Usage = func() {
+			fmt.Fprintf(fs.Output(), "usage: apngtool %s %s\n", c.name, c.usage)
+			fs.PrintDefaults()
+		}
+		err := c.run(fs, os.Args[2:])
+		switch {
+		case errors.Is(err, flag.ErrHelp):
+			os.Exit(0)
+		case errors.Is(err, errUsage):
+			fs.Usage()
+			os.Exit(2)
+		case err != nil:
+			fmt.Fprintln(os.Stderr, "apngtool:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	fmt.Fprintf(os.Stderr, "apngtool: unknown command %q\n", name)
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: apngtool <command> [flags] [arguments]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", c.name, c.short)
+	}
+}
+
+// errUsage reports bad command-line arguments.
+var errUsage = errors.New("bad usage")
+
+// createFile calls write with a new file at path. The file is removed if
+// write fails. A path of "-" stands for the standard output.
+func createFile(path string, write func(f *os.File) error) error {
+	if path == "-" {
+		return write(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// parseArgs parses args with fs, allowing flags to follow the positional
+// arguments as in "apngtool disassemble in.png -o frames".
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return pos, nil
+		}
+		pos = append(pos, args[0])
+		args = args[1:]
+	}
+}
+
+// openFile opens the file at path for reading. A path of "-" stands for
+// the standard input.
+func openFile(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+// readFile returns the contents of the file at path, or of the standard
+// input if path is "-".
+func readFile(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// remuxFile remuxes the APNG file in to a new file out.
+func remuxFile(out, in string, opts *goapng.RemuxOptions) error {
+	f, err := openFile(in)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return createFile(out, func(w *os.File) error {
+		return goapng.Remux(w, f, opts)
+	})
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/cia-rana/goapng"
+)
+
+var optimizeCmd = &command{
+	name:  "optimize",
+	usage: "-o out.png [-level n] in.png",
+	short: "re-encode an APNG to fewer bytes",
+	run:   runOptimize,
+}
+
+func runOptimize(fs *flag.FlagSet, args []string) error {
+	out := fs.String("o", "", "output `file`")
+	level := fs.Int("level", 2, "optimization `level`: 0 re-encodes, 1 stores only changed regions,\n2 also shares a palette when possible, 3 also compresses best")
+	paths, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if *out == "" || len(paths) != 1 || *level < 0 || *level > 3 {
+		return errUsage
+	}
+
+	data, err := readFile(paths[0])
+	if err != nil {
+		return err
+	}
+	a, err := goapng.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	goapng.Optimize(a, *level)
+	enc := goapng.Encoder{}
+	if *level >= 3 {
+		enc.CompressionLevel = goapng.BestCompression
+	}
+	var n int64
+	err = createFile(*out, func(f *os.File) error {
+		stats, err := enc.EncodeAllStats(f, a)
+		if err == nil {
+			n = stats.Bytes
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	report := os.Stdout
+	if *out == "-" {
+		report = os.Stderr
+	}
+	size := len(data)
+	fmt.Fprintf(report, "%s: %d -> %d bytes (%+.1f%%)\n", paths[0], size, n, 100*(float64(n)/float64(size)-1))
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cia-rana/goapng"
+)
+
+// captureStdout calls f with the standard output redirected and returns
+// what f wrote to it.
+func captureStdout(t *testing.T, f func() error) (string, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+	err = f()
+	os.Stdout = stdout
+	w.Close()
+	return <-out, err
+}
+
+func TestOptimize(t *testing.T) {
+	dir := t.TempDir()
+	in := writeTestAPNG(t, dir, 4)
+	want := decodeFile(t, in)
+	out := filepath.Join(dir, "out.png")
+
+	for _, level := range []string{"0", "1", "2", "3"} {
+		report, err := captureStdout(t, func() error {
+			return runCmd(optimizeCmd, "-o", out, "-level", level, in)
+		})
+		if err != nil {
+			t.Errorf("level %s: %v", level, err)
+			continue
+		}
+		info, err := os.Stat(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(report, in+": ") || !strings.Contains(report, " -> "+strconv.FormatInt(info.Size(), 10)+" bytes") {
+			t.Errorf("level %s: report %q doesn't give the output size %d", level, report, info.Size())
+		}
+		if ok, diffs := goapng.EqualFrames(decodeFile(t, out), want, 0); !ok {
+			t.Errorf("level %s: frames differ: %v", level, diffs)
+		}
+	}
+
+	for _, args := range [][]string{
+		{in},
+		{"-o", out},
+		{"-o", out, "-level", "4", in},
+		{"-o", out, "-level", "-1", in},
+	} {
+		if err := runCmd(optimizeCmd, args...); !errors.Is(err, errUsage) {
+			t.Errorf("%v: got error %v, want errUsage", args, err)
+		}
+	}
+	if err := runCmd(optimizeCmd, "-o", out, filepath.Join(dir, "missing.png")); err == nil {
+		t.Error("missing file: got no error")
+	}
+}
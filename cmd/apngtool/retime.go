@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"time"
+
+	"github.com/cia-rana/goapng"
+)
+
+var retimeCmd = &command{
+	name:  "retime",
+	usage: "-o out.png [-fps n | -speed factor] [-loop n] in.png",
+	short: "change the timing of an APNG without re-encoding it",
+	run:   runRetime,
+}
+
+func runRetime(fs *flag.FlagSet, args []string) error {
+	out := fs.String("o", "", "output `file`")
+	fps := fs.Float64("fps", 0, "set every delay to 1/`fps` seconds")
+	speed := fs.Float64("speed", 0, "play `factor` times as fast")
+	loop := fs.Int("loop", -1, "number of plays, 0 for forever")
+	paths, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if *out == "" || len(paths) != 1 || *fps < 0 || *speed < 0 || *fps > 0 && *speed > 0 {
+		return errUsage
+	}
+
+	opts := &goapng.RemuxOptions{}
+	switch {
+	case *fps > 0:
+		num, den := goapng.DelayFraction(time.Duration(float64(time.Second) / *fps))
+		opts.Delay = func(int, uint16, uint16) (uint16, uint16) { return num, den }
+	case *speed > 0:
+		if opts.Delay, err = goapng.SpeedDelay(*speed); err != nil {
+			return err
+		}
+	}
+	if *loop >= 0 {
+		n := uint32(*loop)
+		opts.LoopCount = &n
+	}
+	return remuxFile(*out, paths[0], opts)
+}
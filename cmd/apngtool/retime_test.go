@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cia-rana/goapng"
+)
+
+// writeTestAPNG writes an animation of n frames of 1/30 s to a new file in
+// dir and returns its path.
+func writeTestAPNG(t *testing.T, dir string, n int) string {
+	t.Helper()
+	a := &goapng.APNG{LoopCount: 2}
+	for i := 0; i < n; i++ {
+		m := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+		for j := 0; j < len(m.Pix); j += 4 {
+			m.Pix[j], m.Pix[j+3] = uint8(40*i), 0xff
+		}
+		a.Images = append(a.Images, m)
+		a.Durations = append(a.Durations, time.Second/30)
+	}
+	path := filepath.Join(dir, "in.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := goapng.EncodeAll(f, a); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func decodeFile(t *testing.T, path string) *goapng.APNG {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	a, err := goapng.DecodeAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// delay returns the delay of frame i of a decoded animation.
+func delay(a *goapng.APNG, i int) time.Duration {
+	return time.Duration(a.Delays[i]) * time.Second / time.Duration(a.DelayDens[i])
+}
+
+func TestRetime(t *testing.T) {
+	dir := t.TempDir()
+	in := writeTestAPNG(t, dir, 10)
+	out := filepath.Join(dir, "out.png")
+	run := func(args ...string) error {
+		fs := flag.NewFlagSet("retime", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		return runRetime(fs, args)
+	}
+
+	tests := []struct {
+		args      []string
+		wantDelay time.Duration // Of every frame.
+		wantLoops uint32
+	}{
+		{[]string{"-o", out, "-fps", "25", in}, 40 * time.Millisecond, 2},
+		{[]string{in, "-o", out, "-speed", "2"}, time.Second / 60, 2},
+		{[]string{"-o", out, "-loop", "0", in}, time.Second / 30, goapng.LoopForever},
+	}
+	for _, tt := range tests {
+		if err := run(tt.args...); err != nil {
+			t.Errorf("%v: %v", tt.args, err)
+			continue
+		}
+		a := decodeFile(t, out)
+		if len(a.Images) != 10 {
+			t.Errorf("%v: got %d frames, want 10", tt.args, len(a.Images))
+		}
+		for i := range a.Images {
+			if d := delay(a, i); d != tt.wantDelay {
+				t.Errorf("%v: frame %d: delay %v, want %v", tt.args, i, d, tt.wantDelay)
+				break
+			}
+		}
+		if a.LoopCount != tt.wantLoops {
+			t.Errorf("%v: loop count %d, want %d", tt.args, a.LoopCount, tt.wantLoops)
+		}
+	}
+
+	// At 0.7 times the speed, frames of 1/30 s don't round evenly; the
+	// rounding must not add up over the animation.
+	if err := run("-o", out, "-speed", "0.7", in); err != nil {
+		t.Fatal(err)
+	}
+	var total time.Duration
+	a := decodeFile(t, out)
+	for i := range a.Images {
+		total += delay(a, i)
+	}
+	if want := time.Second * 10 / 21; total-want > time.Second/100 || want-total > time.Second/100 {
+		t.Errorf("-speed 0.7: total %v, want about %v", total, want)
+	}
+
+	for _, args := range [][]string{
+		{in},
+		{"-o", out},
+		{"-o", out, "-fps", "30", "-speed", "2", in},
+		{"-o", out, "-speed", "-1", in},
+	} {
+		if err := run(args...); !errors.Is(err, errUsage) {
+			t.Errorf("%v: got error %v, want errUsage", args, err)
+		}
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	out := fs.String("o", "", "")
+	n := fs.Int("n", 0, "")
+	paths, err := parseArgs(fs, []string{"a.png", "-o", "out", "b.png", "-n", "3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 || paths[0] != "a.png" || paths[1] != "b.png" || *out != "out" || *n != 3 {
+		t.Errorf("got paths %q, -o %q, -n %d", paths, *out, *n)
+	}
+}
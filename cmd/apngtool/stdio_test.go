@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cia-rana/goapng"
+)
+
+// withStdin calls f with the standard input reading data.
+func withStdin(t *testing.T, data []byte, f func() error) error {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stdin")
+	if err := os.WriteFile(path, data, 0666); err != nil {
+		t.Fatal(err)
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	stdin := os.Stdin
+	os.Stdin = in
+	defer func() { os.Stdin = stdin }()
+	return f()
+}
+
+func TestStdio(t *testing.T) {
+	dir := t.TempDir()
+	in := writeTestAPNG(t, dir, 3)
+	data, err := os.ReadFile(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := decodeFile(t, in)
+
+	// Several PNG images one after another on the standard input are
+	// assembled as frames.
+	var frames bytes.Buffer
+	for i := 0; i < 3; i++ {
+		if err := png.Encode(&frames, image.NewGray(image.Rect(0, 0, 2, 2))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var out string
+	err = withStdin(t, frames.Bytes(), func() error {
+		out, err = captureStdout(t, func() error { return runCmd(assembleCmd, "-o", "-", "-") })
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := goapng.DecodeAll(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Images) != 3 {
+		t.Errorf("assemble: got %d frames, want 3", len(a.Images))
+	}
+
+	// The commands that read an APNG read it from the standard input, and
+	// write to the standard output.
+	for _, c := range []*command{cropCmd, retimeCmd, optimizeCmd} {
+		var args []string
+		switch c {
+		case cropCmd:
+			args = []string{"-rect", "0,0,4,4"}
+		case retimeCmd:
+			args = []string{"-speed", "1"}
+		}
+		args = append(args, "-o", "-", "-")
+		err := withStdin(t, data, func() error {
+			out, err = captureStdout(t, func() error { return runCmd(c, args...) })
+			return err
+		})
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		// The optimize report goes to the standard error instead.
+		got, err := goapng.DecodeAll(strings.NewReader(out))
+		if err != nil {
+			t.Errorf("%s: decoding the output: %v", c.name, err)
+			continue
+		}
+		if ok, diffs := goapng.EqualFrames(got, want, 0); !ok {
+			t.Errorf("%s: frames differ: %v", c.name, diffs)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+
+	"github.com/cia-rana/goapng"
+)
+
+var verifyCmd = &command{
+	name:  "verify",
+	usage: "file.png",
+	short: "check the structure of a PNG or APNG file",
+	run:   runVerify,
+}
+
+func runVerify(fs *flag.FlagSet, args []string) error {
+	paths, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(paths) != 1 {
+		return errUsage
+	}
+	f, err := openFile(paths[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	report, err := goapng.VerifyStream(bufio.NewReader(f))
+	if report != nil {
+		for _, p := range report.Problems {
+			fmt.Printf("%s: %s\n", paths[0], p)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if !report.OK() {
+		return fmt.Errorf("%s: %d problems found", paths[0], len(report.Problems))
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	in := writeTestAPNG(t, dir, 3)
+	out, err := captureStdout(t, func() error { return runCmd(verifyCmd, in) })
+	if err != nil || out != "" {
+		t.Errorf("valid file: got error %v and output %q", err, out)
+	}
+
+	// A damaged CRC is a problem, listed before the error.
+	data, err := os.ReadFile(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 0xff
+	bad := filepath.Join(dir, "bad.png")
+	if err := os.WriteFile(bad, data, 0666); err != nil {
+		t.Fatal(err)
+	}
+	out, err = captureStdout(t, func() error { return runCmd(verifyCmd, bad) })
+	if err == nil || !strings.Contains(err.Error(), "1 problems found") {
+		t.Errorf("damaged file: got error %v, want one problem", err)
+	}
+	if !strings.HasPrefix(out, bad+": ") || strings.Count(out, "\n") != 1 {
+		t.Errorf("damaged file: got output %q, want one problem", out)
+	}
+
+	for _, args := range [][]string{{}, {in, in}} {
+		if err := runCmd(verifyCmd, args...); !errors.Is(err, errUsage) {
+			t.Errorf("%v: got error %v, want errUsage", args, err)
+		}
+	}
+	if err := runCmd(verifyCmd, filepath.Join(dir, "missing.png")); err == nil {
+		t.Error("missing file: got no error")
+	}
+}
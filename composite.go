@@ -0,0 +1,429 @@
+package goapng
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"sort"
+	"time"
+)
+
+// compositor implements the APNG rendering model: each frame is blended
+// onto an output canvas, and the frame's region is disposed of before the
+// next frame is rendered.
+type compositor struct {
+	canvas *image.RGBA // The output buffer.
+	prev   *image.RGBA // The canvas before the last frame, for DisposeOpPrevious.
+
+	// Disposal still to be applied for the last rendered frame.
+	disposeOp byte
+	region    image.Rectangle
+
+	first bool
+}
+
+func newCompositor(width, height int) *compositor {
+	r := image.Rect(0, 0, width, height)
+	return &compositor{
+		canvas: image.NewRGBA(r),
+		prev:   image.NewRGBA(r),
+		first:  true,
+	}
+}
+
+// render disposes of the previous frame, draws img onto the canvas and
+// returns the canvas. The canvas is reused by the next call.
+func (c *compositor) render(img image.Image, disposeOp, blendOp byte) *image.RGBA {
+	switch c.disposeOp {
+	case DisposeOpBackground:
+		draw.Draw(c.canvas, c.region, image.Transparent, image.Point{}, draw.Src)
+	case DisposeOpPrevious:
+		draw.Draw(c.canvas, c.region, c.prev, c.region.Min, draw.Src)
+	}
+
+	// A first frame disposed to previous is treated as disposed to background.
+	if disposeOp == DisposeOpPrevious && c.first {
+		disposeOp = DisposeOpBackground
+	}
+	if disposeOp == DisposeOpPrevious {
+		copy(c.prev.Pix, c.canvas.Pix)
+	}
+
+	op := draw.Src
+	if blendOp == BlendOpOver {
+		op = draw.Over
+	}
+	r := img.Bounds()
+	draw.Draw(c.canvas, r, img, r.Min, op)
+
+	c.disposeOp = disposeOp
+	c.region = r.Intersect(c.canvas.Rect)
+	c.first = false
+	return c.canvas
+}
+
+// Composite renders a the way a browser displays it: each frame is drawn
+// onto the canvas left by the frames before it, according to their
+// disposal and blend operations, and returned as a full-canvas image of its
+// own. It returns an error if a is not valid, if a frame uses an unknown
+// disposal or blend operation, or if a lazily decoded frame fails to
+// decode.
+func Composite(a *APNG) ([]*image.RGBA, error) {
+	if err := Validate(a); err != nil {
+		return nil, err
+	}
+	for i := range a.Images {
+		if err := a.checkRender(i); err != nil {
+			return nil, err
+		}
+	}
+
+	b := a.bounds()
+	c := newCompositor(b.Dx(), b.Dy())
+	out := make([]*image.RGBA, len(a.Images))
+	for i, img := range a.Images {
+		out[i] = cloneRGBA(c.render(img, a.disposal(i), a.blend(i)))
+	}
+	return out, nil
+}
+
+// CompositedFrame returns the canvas as it is displayed while frame n of a
+// is shown, rendering frames 0 through n with their disposal and blend
+// operations. Lazily decoded frames after frame n are not decompressed.
+func CompositedFrame(a *APNG, n int) (*image.RGBA, error) {
+	if err := validate(a, n+1); err != nil {
+		return nil, err
+	}
+	if n < 0 || n >= len(a.Images) {
+		return nil, ErrFrameIndex
+	}
+	return compositedFrame(a, n)
+}
+
+// FrameAt returns the canvas as it is displayed t after the animation
+// starts, taking the loop count into account. Once the last play has ended
+// the last frame stays on screen. Negative times give the first frame.
+// Lazily decoded frames after the one displayed are not decompressed.
+func FrameAt(a *APNG, t time.Duration) (*image.RGBA, error) {
+	if len(a.Images) == 0 {
+		return nil, ErrNoFrames
+	}
+	if errs := checkSlices(a); errs != nil {
+		return nil, errors.Join(errs...)
+	}
+	n := a.frameIndexAt(t)
+	if errs := checkFrames(a, n+1); errs != nil {
+		return nil, errors.Join(errs...)
+	}
+	return compositedFrame(a, n)
+}
+
+// compositedFrame is CompositedFrame for a valid as far as frame n.
+func compositedFrame(a *APNG, n int) (*image.RGBA, error) {
+	b := a.bounds()
+	c := newCompositor(b.Dx(), b.Dy())
+	var canvas *image.RGBA
+	for i := 0; i <= n; i++ {
+		if err := a.checkRender(i); err != nil {
+			return nil, err
+		}
+		canvas = c.render(a.Images[i], a.disposal(i), a.blend(i))
+	}
+	return canvas, nil
+}
+
+// frameIndexAt returns the index of the frame of a displayed at t.
+func (a *APNG) frameIndexAt(t time.Duration) int {
+	ts := a.timeline()
+	n := len(a.Images)
+	total := ts[n]
+	if t < 0 {
+		t = 0
+	}
+	if total > 0 && t >= total {
+		plays := t / total
+		if a.LoopCount != LoopForever && plays >= time.Duration(a.LoopCount) {
+			return n - 1
+		}
+		t %= total
+	}
+	// The frame shown is the last one to start at or before t; frames
+	// with no delay are replaced as soon as they are drawn.
+	i := sort.Search(n, func(i int) bool { return ts[i+1] > t })
+	if i == n {
+		i = n - 1
+	}
+	return i
+}
+
+// checkRender returns an error if frame i of a uses an unknown disposal or
+// blend operation, or is a LazyImage that fails to decode.
+func (a *APNG) checkRender(i int) error {
+	if op := a.disposal(i); op > DisposeOpPrevious {
+		return &FrameError{i, fmt.Errorf("apng: unknown dispose_op %d", op)}
+	}
+	if op := a.blend(i); op > BlendOpOver {
+		return &FrameError{i, fmt.Errorf("apng: unknown blend_op %d", op)}
+	}
+	if l, ok := a.Images[i].(*LazyImage); ok {
+		if _, err := l.Decode(); err != nil {
+			return &FrameError{i, err}
+		}
+	}
+	return nil
+}
+
+// bounds returns the canvas of a, which is the size of the first frame.
+func (a *APNG) bounds() image.Rectangle {
+	b := a.Images[0].Bounds()
+	return image.Rect(0, 0, b.Dx(), b.Dy())
+}
+
+// disposal returns the disposal method of frame i.
+func (a *APNG) disposal(i int) byte {
+	if a.Disposals == nil {
+		return DisposeOpNone
+	}
+	return a.Disposals[i]
+}
+
+// blend returns the blend operation of frame i.
+func (a *APNG) blend(i int) byte {
+	if a.Blends == nil {
+		return BlendOpSource
+	}
+	return a.Blends[i]
+}
+
+// ensureOps allocates Disposals and Blends if they are nil, so that they
+// can be edited per frame.
+func (a *APNG) ensureOps() {
+	if a.Disposals == nil {
+		a.Disposals = make([]byte, len(a.Images))
+	}
+	if a.Blends == nil {
+		a.Blends = make([]byte, len(a.Images))
+	}
+}
+
+// convertLike returns a copy of m in the image type of ref, so that it can
+// be encoded alongside frames sharing ref's color model.
+func convertLike(ref, m image.Image) image.Image {
+	if l, ok := ref.(*LazyImage); ok {
+		ref, _ = l.Decode()
+	}
+
+	r := m.Bounds()
+	dst := newLike(ref, r)
+	draw.Draw(dst, r, m, r.Min, draw.Src)
+	return dst
+}
+
+// newLike returns a new image of the bounds r in the image type of ref,
+// or an *image.NRGBA for types without a lossless counterpart.
+func newLike(ref image.Image, r image.Rectangle) draw.Image {
+	switch ref := ref.(type) {
+	case *image.Paletted:
+		return image.NewPaletted(r, ref.Palette)
+	case *image.Gray:
+		return image.NewGray(r)
+	case *image.Gray16:
+		return image.NewGray16(r)
+	case *image.RGBA:
+		return image.NewRGBA(r)
+	case *image.RGBA64:
+		return image.NewRGBA64(r)
+	case *image.NRGBA64:
+		return image.NewNRGBA64(r)
+	}
+	return image.NewNRGBA(r)
+}
+
+// likeFrames returns ms, full canvases rendered from a, in the image type
+// of the first frame of a, so that they can be stored alongside its
+// frames. If that type cannot hold them, as convertExact reports, every
+// frame of a is converted instead, to the type wideLike gives, and ms are
+// returned in that type.
+func (a *APNG) likeFrames(ms []image.Image) []image.Image {
+	ref := a.Images[0]
+	out, ok := convertExact(ref, ms)
+	if ok {
+		return out
+	}
+	wide := wideLike(ref)
+	for i, img := range a.Images {
+		a.Images[i] = convertLike(wide, img)
+	}
+	for i, m := range ms {
+		out[i] = convertLike(wide, m)
+	}
+	return out
+}
+
+// convertExact returns copies of ms in the image type of ref, or false if
+// that type cannot hold them: newLike has no counterpart for it, or they
+// use colors outside its palette or gray scale. Colors are only kept to
+// the precision of the type.
+func convertExact(ref image.Image, ms []image.Image) ([]image.Image, bool) {
+	if l, ok := ref.(*LazyImage); ok {
+		ref, _ = l.Decode()
+	}
+
+	var bits uint
+	switch ref.(type) {
+	case *image.RGBA, *image.RGBA64, *image.NRGBA, *image.NRGBA64:
+	case *image.Paletted, *image.Gray:
+		bits = 8
+	case *image.Gray16:
+		bits = 16
+	default:
+		return make([]image.Image, len(ms)), false
+	}
+	out := make([]image.Image, len(ms))
+	for i, m := range ms {
+		out[i] = convertLike(ref, m)
+		if bits > 0 && !sameColors(out[i], m, bits) {
+			return out, false
+		}
+	}
+	return out, true
+}
+
+// wideLike returns an image whose type can hold frames that the type of
+// ref cannot: *image.NRGBA64 for 16-bit frames and *image.NRGBA otherwise.
+func wideLike(ref image.Image) image.Image {
+	switch ref.ColorModel() {
+	case color.Gray16Model, color.Alpha16Model:
+		return &image.NRGBA64{}
+	}
+	return &image.NRGBA{}
+}
+
+// sameColors reports whether m1 and m2, which have the same bounds, have
+// the same colors to the given number of bits per channel.
+func sameColors(m1, m2 image.Image, bits uint) bool {
+	shift := 16 - bits
+	b := m1.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r1, g1, b1, a1 := m1.At(x, y).RGBA()
+			r2, g2, b2, a2 := m2.At(x, y).RGBA()
+			if r1>>shift != r2>>shift || g1>>shift != g2>>shift || b1>>shift != b2>>shift || a1>>shift != a2>>shift {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// flattenFrom replaces frame k of a, and the following frames that draw
+// onto its disposal, with the full canvas they display. Afterwards, the
+// frames from k on render the same whatever frames precede them.
+//
+// Replacing frame k by its displayed canvas only reproduces the canvas the
+// next frame draws on if frame k was not disposed of, so the replacement
+// continues up to and including the first frame with DisposeOpNone.
+func flattenFrom(a *APNG, k int) {
+	end := k
+	for end < len(a.Images)-1 && a.disposal(end) != DisposeOpNone {
+		end++
+	}
+
+	b := a.bounds()
+	c := newCompositor(b.Dx(), b.Dy())
+	var flat []image.Image
+	for i := 0; i <= end; i++ {
+		canvas := c.render(a.Images[i], a.disposal(i), a.blend(i))
+		if i >= k {
+			flat = append(flat, cloneRGBA(canvas))
+		}
+	}
+
+	a.ensureOps()
+	for j, m := range a.likeFrames(flat) {
+		a.Images[k+j] = m
+		a.Disposals[k+j] = DisposeOpNone
+		a.Blends[k+j] = BlendOpSource
+	}
+}
+
+// renderAll returns the full canvas displayed for each frame of a, in the
+// image type likeFrames gives them, converting the frames of a if it does.
+func renderAll(a *APNG) []image.Image {
+	b := a.bounds()
+	c := newCompositor(b.Dx(), b.Dy())
+	out := make([]image.Image, len(a.Images))
+	for i, img := range a.Images {
+		out[i] = cloneRGBA(c.render(img, a.disposal(i), a.blend(i)))
+	}
+	return a.likeFrames(out)
+}
+
+// setFlat replaces the frames of a with full-canvas frames that don't
+// depend on each other.
+func (a *APNG) setFlat(imgs []image.Image) {
+	a.Images = imgs
+	a.Disposals = make([]byte, len(imgs))
+	a.Blends = make([]byte, len(imgs))
+}
+
+// selectFrames returns a copy of a holding the frames listed in idx, in
+// that order. Per-frame slices that are nil in a stay nil.
+func (a *APNG) selectFrames(idx []int) *APNG {
+	b := &APNG{
+		Images:     make([]image.Image, len(idx)),
+		LoopCount:  a.LoopCount,
+		Config:     a.Config,
+		Background: a.Background,
+	}
+	if a.Delays != nil {
+		b.Delays = make([]uint16, len(idx))
+	}
+	if a.DelayDens != nil {
+		b.DelayDens = make([]uint16, len(idx))
+	}
+	if a.Durations != nil {
+		b.Durations = make([]time.Duration, len(idx))
+	}
+	if a.Disposals != nil {
+		b.Disposals = make([]byte, len(idx))
+	}
+	if a.Blends != nil {
+		b.Blends = make([]byte, len(idx))
+	}
+	for j, i := range idx {
+		b.Images[j] = a.Images[i]
+		if a.Delays != nil {
+			b.Delays[j] = a.Delays[i]
+		}
+		if a.DelayDens != nil {
+			b.DelayDens[j] = a.DelayDens[i]
+		}
+		if a.Durations != nil {
+			b.Durations[j] = a.Durations[i]
+		}
+		if a.Disposals != nil {
+			b.Disposals[j] = a.Disposals[i]
+		}
+		if a.Blends != nil {
+			b.Blends[j] = a.Blends[i]
+		}
+	}
+	return b
+}
+
+// slice returns a copy of a holding frames from through to-1.
+func (a *APNG) slice(from, to int) *APNG {
+	return a.selectFrames(frameRange(from, to))
+}
+
+// frameRange returns the frame indices from through to-1.
+func frameRange(from, to int) []int {
+	idx := make([]int, 0, to-from)
+	for i := from; i < to; i++ {
+		idx = append(idx, i)
+	}
+	return idx
+}
@@ -0,0 +1,74 @@
+package goapng
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestCompositor(t *testing.T) {
+	var (
+		clear = color.RGBA{}
+		red   = color.RGBA{0xff, 0, 0, 0xff}
+		green = color.RGBA{0, 0xff, 0, 0xff}
+		blue  = color.RGBA{0, 0, 0xff, 0xff}
+		half  = color.RGBA{0, 0, 0x80, 0x80} // Premultiplied half-transparent blue.
+	)
+	// pixels returns an image over x0 to x0+len(cs) on row 0.
+	pixels := func(x0 int, cs ...color.RGBA) image.Image {
+		m := image.NewRGBA(image.Rect(x0, 0, x0+len(cs), 1))
+		for i, c := range cs {
+			m.SetRGBA(x0+i, 0, c)
+		}
+		return m
+	}
+	type frame struct {
+		img       image.Image
+		disposeOp byte
+		blendOp   byte
+		want      []color.RGBA // The 3x1 canvas after the frame.
+	}
+	tests := []struct {
+		name   string
+		frames []frame
+	}{
+		{"source replaces", []frame{
+			{pixels(0, red, red, red), DisposeOpNone, BlendOpSource, []color.RGBA{red, red, red}},
+			{pixels(1, half), DisposeOpNone, BlendOpSource, []color.RGBA{red, half, red}},
+		}},
+		{"over blends", []frame{
+			{pixels(0, red, red, red), DisposeOpNone, BlendOpSource, []color.RGBA{red, red, red}},
+			{pixels(1, half), DisposeOpNone, BlendOpOver, []color.RGBA{red, {0x7f, 0, 0x80, 0xff}, red}},
+			{pixels(2, clear), DisposeOpNone, BlendOpOver, []color.RGBA{red, {0x7f, 0, 0x80, 0xff}, red}},
+		}},
+		{"dispose to background", []frame{
+			{pixels(0, red, red), DisposeOpBackground, BlendOpSource, []color.RGBA{red, red, clear}},
+			{pixels(2, green), DisposeOpNone, BlendOpSource, []color.RGBA{clear, clear, green}},
+		}},
+		{"dispose to previous", []frame{
+			{pixels(0, red, red, red), DisposeOpNone, BlendOpSource, []color.RGBA{red, red, red}},
+			{pixels(1, green), DisposeOpPrevious, BlendOpSource, []color.RGBA{red, green, red}},
+			{pixels(2, blue), DisposeOpPrevious, BlendOpSource, []color.RGBA{red, red, blue}},
+			{pixels(0, green), DisposeOpNone, BlendOpOver, []color.RGBA{green, red, red}},
+		}},
+		{"first frame disposed to previous clears", []frame{
+			{pixels(0, red, red, red), DisposeOpPrevious, BlendOpSource, []color.RGBA{red, red, red}},
+			{pixels(1, green), DisposeOpNone, BlendOpOver, []color.RGBA{clear, green, clear}},
+		}},
+		{"region outside the canvas is clipped", []frame{
+			{pixels(2, blue, blue), DisposeOpBackground, BlendOpSource, []color.RGBA{clear, clear, blue}},
+			{pixels(0, red), DisposeOpNone, BlendOpSource, []color.RGBA{red, clear, clear}},
+		}},
+	}
+	for _, tt := range tests {
+		c := newCompositor(3, 1)
+		for i, f := range tt.frames {
+			canvas := c.render(f.img, f.disposeOp, f.blendOp)
+			for x, want := range f.want {
+				if got := canvas.RGBAAt(x, 0); got != want {
+					t.Errorf("%s, frame %d: pixel %d = %v, want %v", tt.name, i, x, got, want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package goapng
+
+import (
+	"compress/zlib"
+	"io"
+)
+
+// A Compressor creates writers that compress frame data into a zlib stream.
+// It lets callers plug in a deflate implementation other than compress/zlib,
+// such as github.com/klauspost/compress/zlib or a zopfli encoder. A
+// Compressor shared by encodes running at once must allow NewWriter to be
+// called concurrently.
+type Compressor interface {
+	NewWriter(w io.Writer, level CompressionLevel) (io.WriteCloser, error)
+}
+
+// ZlibCompressor is the Compressor backed by compress/zlib.
+type ZlibCompressor struct{}
+
+func (ZlibCompressor) NewWriter(w io.Writer, level CompressionLevel) (io.WriteCloser, error) {
+	return zlib.NewWriterLevel(w, levelToZlib(level))
+}
@@ -0,0 +1,105 @@
+package goapng
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestCrossfade(t *testing.T) {
+	black := solid(2, 2, color.NRGBA{0, 0, 0, 0xff})
+	white := solid(2, 2, color.NRGBA{0xff, 0xff, 0xff, 0xff})
+	fades := Crossfade(black, white, 3)
+	if len(fades) != 3 {
+		t.Fatalf("got %d images, want 3", len(fades))
+	}
+	for i, want := range []uint8{64, 128, 191} {
+		if got := color.RGBAModel.Convert(fades[i].At(1, 1)).(color.RGBA); got != (color.RGBA{want, want, want, 0xff}) {
+			t.Errorf("image %d: %v, want gray %d", i, got, want)
+		}
+	}
+
+	// Images of different bounds fade over their union, transparent
+	// outside each.
+	small := translate(solid(1, 1, color.NRGBA{0xff, 0, 0, 0xff}), image.Pt(3, 0))
+	fades = Crossfade(black, small, 1)
+	if b := fades[0].Bounds(); b != image.Rect(0, 0, 4, 2) {
+		t.Errorf("bounds %v, want the union", b)
+	}
+	checks := []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{0, 0, color.RGBA{0, 0, 0, 0x80}},
+		{3, 0, color.RGBA{0x80, 0, 0, 0x80}},
+		{2, 1, color.RGBA{}},
+	}
+	for _, c := range checks {
+		if got := fades[0].At(c.x, c.y); got != c.want {
+			t.Errorf("at %d,%d: %v, want %v", c.x, c.y, got, c.want)
+		}
+	}
+
+	if fades := Crossfade(black, white, 0); len(fades) != 0 {
+		t.Errorf("no steps: got %d images", len(fades))
+	}
+}
+
+func TestInsertCrossfades(t *testing.T) {
+	ms := func(ds ...int) []time.Duration {
+		var out []time.Duration
+		for _, d := range ds {
+			out = append(out, time.Duration(d)*time.Millisecond)
+		}
+		return out
+	}
+	tests := []struct {
+		name     string
+		delays   []time.Duration
+		steps    int
+		d        time.Duration
+		wantDurs []time.Duration
+	}{
+		{"two steps", ms(100, 100, 100), 2, 40 * time.Millisecond, ms(60, 20, 20, 60, 20, 20, 100)},
+		{"fade longer than the frame", ms(30, 100), 2, 40 * time.Millisecond, ms(0, 15, 15, 100)},
+		{"no steps", ms(100, 100), 0, 40 * time.Millisecond, ms(100, 100)},
+	}
+	for _, tt := range tests {
+		a := testAPNG(len(tt.delays), 4, 4)
+		a.Durations = tt.delays
+		frames, err := Composite(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		total := a.Duration()
+		if err := InsertCrossfades(a, tt.steps, tt.d); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if fmt.Sprint(a.Durations) != fmt.Sprint(tt.wantDurs) {
+			t.Errorf("%s: durations %v, want %v", tt.name, a.Durations, tt.wantDurs)
+		}
+		if a.Duration() != total {
+			t.Errorf("%s: total %v, want %v unchanged", tt.name, a.Duration(), total)
+		}
+		// The original frames are kept between the fades.
+		for i, m := range frames {
+			if j := i * (tt.steps + 1); j >= len(a.Images) || !samePixels(a.Images[j], m) {
+				t.Errorf("%s: frame %d is not at %d", tt.name, i, j)
+			}
+		}
+		if err := Validate(a); err != nil {
+			t.Errorf("%s: Validate: %v", tt.name, err)
+		}
+	}
+
+	a := testAPNG(2, 4, 4)
+	if err := InsertCrossfades(a, -1, time.Second); err == nil {
+		t.Error("negative steps: got no error")
+	}
+	if err := InsertCrossfades(a, 1, -time.Second); err == nil {
+		t.Error("negative duration: got no error")
+	}
+}
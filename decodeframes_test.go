@@ -0,0 +1,205 @@
+package goapng
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestDecodeFramesReuseCanvas(t *testing.T) {
+	a := testAPNG(4, 5, 5)
+	a.Images[2] = solid(2, 2, color.NRGBA{0, 0, 0xff, 0x80})
+	a.Disposals = []byte{DisposeOpNone, DisposeOpBackground, DisposeOpPrevious, DisposeOpNone}
+	a.Blends = []byte{BlendOpSource, BlendOpSource, BlendOpOver, BlendOpOver}
+	want, err := Composite(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := EncodeAll(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, reuse := range []bool{false, true} {
+		var kept []*image.RGBA
+		dec := Decoder{ReuseCanvas: reuse}
+		err := dec.DecodeFrames(bytes.NewReader(buf.Bytes()), func(i int, img *image.RGBA, _ time.Duration) error {
+			if !samePixels(img, want[i]) {
+				t.Errorf("ReuseCanvas %v: frame %d differs from Composite", reuse, i)
+			}
+			kept = append(kept, img)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Only without ReuseCanvas may the frames be kept.
+		if !reuse {
+			for i, img := range kept {
+				if !samePixels(img, want[i]) {
+					t.Errorf("frame %d changed after its callback returned", i)
+				}
+			}
+		} else if kept[0] != kept[len(kept)-1] {
+			t.Error("ReuseCanvas passed different canvases")
+		}
+	}
+}
+
+// rawPNG returns a PNG of the given color type and bit depth holding the
+// pixel data rows, with each row filtered by the filter after the one
+// used for the row above it.
+func rawPNG(t *testing.T, w int, colorType, depth byte, rows [][]byte, plte, trns []byte) []byte {
+	t.Helper()
+	channels := map[byte]int{ctGrayscale: 1, ctTrueColor: 3, ctPaletted: 1, ctGrayscaleAlpha: 2, ctTrueColorAlpha: 4}[colorType]
+	bpp := (channels*int(depth) + 7) / 8
+	var data bytes.Buffer
+	zw := zlib.NewWriter(&data)
+	prev := make([]byte, len(rows[0]))
+	for y, row := range rows {
+		ft := y % filterNum
+		zw.Write(append([]byte{byte(ft)}, refFilter(ft, row, prev, bpp)...))
+		prev = row
+	}
+	zw.Close()
+
+	var out bytes.Buffer
+	cw := NewChunkWriter(&out)
+	ihdr := make([]byte, 13)
+	writeUint32(ihdr[0:4], uint32(w))
+	writeUint32(ihdr[4:8], uint32(len(rows)))
+	ihdr[8], ihdr[9] = depth, colorType
+	cw.WriteChunk("IHDR", ihdr)
+	if plte != nil {
+		cw.WriteChunk("PLTE", plte)
+	}
+	if trns != nil {
+		cw.WriteChunk("tRNS", trns)
+	}
+	cw.WriteChunk("IDAT", data.Bytes())
+	cw.WriteChunk("IEND", nil)
+	return out.Bytes()
+}
+
+func TestDecodeFramesReuseCanvasFormats(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const w, h = 7, 5
+	rows := func(colorType, depth byte) [][]byte {
+		channels := map[byte]int{ctGrayscale: 1, ctTrueColor: 3, ctPaletted: 1, ctGrayscaleAlpha: 2, ctTrueColorAlpha: 4}[colorType]
+		out := make([][]byte, h)
+		for y := range out {
+			out[y] = make([]byte, (w*channels*int(depth)+7)/8)
+			rng.Read(out[y])
+		}
+		return out
+	}
+	type file struct {
+		name string
+		data []byte
+	}
+	var files []file
+	for _, depth := range []byte{1, 2, 4, 8, 16} {
+		r := rows(ctGrayscale, depth)
+		// The first pixel, in a key as long as a 16-bit sample.
+		key := []byte{0, r[0][0] >> (8 - depth)}
+		if depth == 16 {
+			key = r[0][:2]
+		}
+		files = append(files,
+			file{"gray", rawPNG(t, w, ctGrayscale, depth, r, nil, nil)},
+			file{"gray with tRNS", rawPNG(t, w, ctGrayscale, depth, r, nil, key)},
+		)
+		if depth < 16 {
+			// Indices past the end of PLTE show as opaque black.
+			plte := []byte{0xff, 0, 0, 0, 0xff, 0, 0, 0, 0xff}
+			if depth == 1 {
+				plte = plte[:6]
+			}
+			files = append(files, file{"paletted", rawPNG(t, w, ctPaletted, depth, rows(ctPaletted, depth), plte, []byte{0x80, 0})})
+		}
+	}
+	for _, depth := range []byte{8, 16} {
+		r := rows(ctTrueColor, depth)
+		key := []byte{0, r[0][0], 0, r[0][1], 0, r[0][2]}
+		if depth == 16 {
+			key = r[0][:6]
+		}
+		files = append(files,
+			file{"truecolor", rawPNG(t, w, ctTrueColor, depth, r, nil, nil)},
+			file{"truecolor with tRNS", rawPNG(t, w, ctTrueColor, depth, r, nil, key)},
+			file{"gray and alpha", rawPNG(t, w, ctGrayscaleAlpha, depth, rows(ctGrayscaleAlpha, depth), nil, nil)},
+			file{"truecolor and alpha", rawPNG(t, w, ctTrueColorAlpha, depth, rows(ctTrueColorAlpha, depth), nil, nil)},
+		)
+	}
+	// Animations, interlaced, with frames smaller than the canvas.
+	paletted := image.NewPaletted(image.Rect(1, 2, 6, 5), color.Palette{color.Black, color.White, color.Transparent})
+	for i := range paletted.Pix {
+		paletted.Pix[i] = uint8(i % 3)
+	}
+	deep := image.NewNRGBA64(image.Rect(0, 0, 3, 4))
+	rng.Read(deep.Pix)
+	for _, m := range []image.Image{paletted, solid(4, 3, color.NRGBA{1, 2, 3, 0x40}), deep} {
+		a := testAPNG(3, 9, 9)
+		for i := range a.Images {
+			a.Images[i] = convertLike(m, a.Images[i])
+		}
+		a.Images[1] = m
+		a.Blends = []byte{BlendOpSource, BlendOpOver, BlendOpSource}
+		var buf bytes.Buffer
+		if err := (&Encoder{Interlace: true}).EncodeAll(&buf, a); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file{fmt.Sprintf("interlaced %T", m), buf.Bytes()})
+	}
+
+	for _, f := range files {
+		var want []*image.RGBA
+		err := DecodeFrames(bytes.NewReader(f.data), func(i int, img *image.RGBA, _ time.Duration) error {
+			want = append(want, img)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", f.name, err)
+		}
+		dec := Decoder{ReuseCanvas: true}
+		err = dec.DecodeFrames(bytes.NewReader(f.data), func(i int, img *image.RGBA, _ time.Duration) error {
+			if !samePixels(img, want[i]) {
+				t.Errorf("%s: frame %d differs", f.name, i)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("%s: %v", f.name, err)
+		}
+	}
+}
+
+func TestFrameDecoderAllocs(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeAll(&buf, testAPNG(3, 64, 64)); err != nil {
+		t.Fatal(err)
+	}
+	d := new(Decoder).newDecoder(context.Background())
+	if err := d.readChunks(&buf); err != nil {
+		t.Fatal(err)
+	}
+	fd := newFrameDecoder(d)
+	if _, err := fd.decode(d.frames[0]); err != nil {
+		t.Fatal(err)
+	}
+	// Only the checksum state of the zlib reader is allocated anew.
+	allocs := testing.AllocsPerRun(10, func() {
+		if _, err := fd.decode(d.frames[1]); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs > 1 {
+		t.Errorf("%v allocations per frame", allocs)
+	}
+}
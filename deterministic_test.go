@@ -0,0 +1,41 @@
+package goapng
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestDeterministic(t *testing.T) {
+	a := testAPNG(3, 16, 16)
+	a.Images[1] = solid(16, 16, color.NRGBA{0x80, 0, 0, 0x80}).SubImage(image.Rect(4, 4, 12, 12))
+	encodings := []struct {
+		name string
+		enc  func(enc *Encoder, w *bytes.Buffer) error
+	}{
+		{"EncodeAll", func(enc *Encoder, w *bytes.Buffer) error { return enc.EncodeAll(w, a) }},
+		{"EncodeSource", func(enc *Encoder, w *bytes.Buffer) error { return enc.EncodeSource(w, sliceSource(a), 0) }},
+	}
+	for _, e := range encodings {
+		// Separate Encoders, so that nothing is carried over in their
+		// buffers.
+		var out [2]bytes.Buffer
+		for i := range out {
+			if err := e.enc(&Encoder{Deterministic: true}, &out[i]); err != nil {
+				t.Fatalf("%s: %v", e.name, err)
+			}
+		}
+		if !bytes.Equal(out[0].Bytes(), out[1].Bytes()) {
+			t.Errorf("%s: encodings differ", e.name)
+		}
+
+		var buf bytes.Buffer
+		if err := e.enc(&Encoder{Deterministic: true, Compressor: ZlibCompressor{}}, &buf); err == nil {
+			t.Errorf("%s: Deterministic with a Compressor: got no error", e.name)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("%s: wrote %d bytes before failing", e.name, buf.Len())
+		}
+	}
+}
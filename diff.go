@@ -0,0 +1,204 @@
+package goapng
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"image"
+	"io"
+	"time"
+)
+
+// DiffReport describes how two animations differ as displayed.
+type DiffReport struct {
+	FramesA, FramesB       int         // The number of frames of each animation.
+	CanvasA, CanvasB       image.Point // The canvas size of each animation.
+	LoopCountA, LoopCountB uint32      // The loop count of each animation.
+
+	// Frames compares the frames present in both animations, in order.
+	Frames []FrameDiff
+}
+
+// FrameDiff compares one frame of two animations.
+type FrameDiff struct {
+	Index          int
+	DelayA, DelayB time.Duration
+
+	// Mismatched is the number of pixels of the composited canvases that
+	// differ, and Bounds the smallest rectangle holding all of them. A
+	// pixel inside only one of the canvases differs unless it is fully
+	// transparent.
+	Mismatched int
+	Bounds     image.Rectangle
+}
+
+// Equal reports whether the animations display the same.
+func (r *DiffReport) Equal() bool {
+	if r.FramesA != r.FramesB || r.CanvasA != r.CanvasB || r.LoopCountA != r.LoopCountB {
+		return false
+	}
+	for _, f := range r.Frames {
+		if !f.Equal() {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal reports whether the frame is displayed the same in both animations.
+func (f *FrameDiff) Equal() bool {
+	return f.DelayA == f.DelayB && f.Mismatched == 0
+}
+
+// Diff reads two APNGs and compares their frame counts, timing and the
+// pixels of their composited frames, which is what a renderer displays.
+// Frames are decoded one at a time, so only two canvases per animation are
+// held in memory.
+func Diff(a, b io.Reader) (DiffReport, error) {
+	dec := Decoder{Lazy: true}
+	x, err := dec.DecodeAll(a)
+	if err != nil {
+		return DiffReport{}, err
+	}
+	y, err := dec.DecodeAll(b)
+	if err != nil {
+		return DiffReport{}, err
+	}
+	// Lazily decoded frames keep their pixels once decoded; drop them so
+	// memory does not grow with the number of frames.
+	return diff(x, y, 0, true)
+}
+
+// EqualFrames reports whether a and b display the same: whether they have
+// the same canvas size, loop count, number of frames and delays, and their
+// composited frames the same pixels, give or take tolerance in each color
+// channel of 8 bits. The differences found are described frame by frame,
+// which makes EqualFrames suited to golden-file tests. Animations that
+// can't be rendered, such as ones Validate rejects, are reported as
+// different with no frames described.
+func EqualFrames(a, b *APNG, tolerance int) (bool, []FrameDiff) {
+	if Validate(a) != nil || Validate(b) != nil {
+		return false, nil
+	}
+	r, err := diff(a, b, tolerance, false)
+	if err != nil {
+		return false, r.Frames
+	}
+	return r.Equal(), r.Frames
+}
+
+// diff compares x and y frame by frame, counting pixels that differ by
+// more than tolerance in some channel. If release is set, the frames of x
+// and y are set to nil once compared.
+func diff(x, y *APNG, tolerance int, release bool) (DiffReport, error) {
+	ba, bb := x.bounds(), y.bounds()
+	r := DiffReport{
+		FramesA:    len(x.Images),
+		FramesB:    len(y.Images),
+		CanvasA:    ba.Size(),
+		CanvasB:    bb.Size(),
+		LoopCountA: x.LoopCount,
+		LoopCountB: y.LoopCount,
+	}
+
+	n := len(x.Images)
+	if len(y.Images) < n {
+		n = len(y.Images)
+	}
+	ca, cb := newCompositor(ba.Dx(), ba.Dy()), newCompositor(bb.Dx(), bb.Dy())
+	for i := 0; i < n; i++ {
+		if err := x.checkRender(i); err != nil {
+			return r, err
+		}
+		if err := y.checkRender(i); err != nil {
+			return r, err
+		}
+		ma := ca.render(x.Images[i], x.disposal(i), x.blend(i))
+		mb := cb.render(y.Images[i], y.disposal(i), y.blend(i))
+		f := FrameDiff{
+			Index:  i,
+			DelayA: x.delay(i),
+			DelayB: y.delay(i),
+		}
+		f.Mismatched, f.Bounds = diffPixels(ma, mb, tolerance)
+		r.Frames = append(r.Frames, f)
+		if release {
+			x.Images[i], y.Images[i] = nil, nil
+		}
+	}
+	return r, nil
+}
+
+// diffPixels returns the number of pixels that differ between m1 and m2 by
+// more than tolerance in some channel, and the smallest rectangle holding
+// them. m1 and m2 may have different bounds; pixels outside an image are
+// transparent.
+func diffPixels(m1, m2 *image.RGBA, tolerance int) (int, image.Rectangle) {
+	var (
+		n int
+		r image.Rectangle
+	)
+	u := m1.Rect.Union(m2.Rect)
+	for y := u.Min.Y; y < u.Max.Y; y++ {
+		for x := u.Min.X; x < u.Max.X; x++ {
+			if !similar(rgbaAt(m1, x, y), rgbaAt(m2, x, y), tolerance) {
+				n++
+				r = r.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return n, r
+}
+
+// rgbaAt returns the pixel of m at (x, y) as four bytes packed together, or
+// 0 outside m.
+func rgbaAt(m *image.RGBA, x, y int) uint32 {
+	if !(image.Point{x, y}.In(m.Rect)) {
+		return 0
+	}
+	i := m.PixOffset(x, y)
+	p := m.Pix[i : i+4 : i+4]
+	return uint32(p[0])<<24 | uint32(p[1])<<16 | uint32(p[2])<<8 | uint32(p[3])
+}
+
+// similar reports whether the packed pixels p and q differ by at most
+// tolerance in every channel.
+func similar(p, q uint32, tolerance int) bool {
+	if p == q {
+		return true
+	}
+	for shift := 0; shift < 32; shift += 8 {
+		d := int(p>>shift&0xff) - int(q>>shift&0xff)
+		if d > tolerance || -d > tolerance {
+			return false
+		}
+	}
+	return true
+}
+
+// FrameHashes returns a 64-bit FNV-1a hash of each frame of a as it is
+// displayed, that is, of its composited canvas. Frames that look the same
+// hash the same regardless of how they are stored, so the hashes can key
+// caches or find duplicate frames; with the delays they identify the whole
+// animation. Different frames collide with negligible probability.
+func FrameHashes(a *APNG) ([]uint64, error) {
+	if err := Validate(a); err != nil {
+		return nil, err
+	}
+	b := a.bounds()
+	c := newCompositor(b.Dx(), b.Dy())
+	hashes := make([]uint64, len(a.Images))
+	var size [8]byte
+	binary.BigEndian.PutUint32(size[0:4], uint32(b.Dx()))
+	binary.BigEndian.PutUint32(size[4:8], uint32(b.Dy()))
+	for i, img := range a.Images {
+		if err := a.checkRender(i); err != nil {
+			return nil, err
+		}
+		m := c.render(img, a.disposal(i), a.blend(i))
+		h := fnv.New64a()
+		h.Write(size[:])
+		h.Write(m.Pix)
+		hashes[i] = h.Sum64()
+	}
+	return hashes, nil
+}
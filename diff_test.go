@@ -0,0 +1,86 @@
+package goapng
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	encode := func(a *APNG, opt bool) []byte {
+		t.Helper()
+		if opt {
+			a = copyAPNG(a)
+			Optimize(a, OptimizePalette)
+		}
+		var buf bytes.Buffer
+		if err := EncodeAll(&buf, a); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	a := testAPNG(3, 6, 6)
+	file := encode(a, false)
+
+	changed := copyAPNG(a)
+	m := solid(6, 6, a.Images[1].(*image.NRGBA).NRGBAAt(0, 0))
+	m.SetNRGBA(4, 2, color.NRGBA{1, 2, 3, 0xff})
+	changed.Images[1] = m
+
+	slower := copyAPNG(a)
+	slower.Durations[2] = 150 * time.Millisecond
+
+	longer := testAPNG(4, 6, 6)
+	longer.LoopCount = 2
+
+	tests := []struct {
+		name       string
+		b          []byte
+		equal      bool
+		mismatched []int // Per frame.
+	}{
+		{"same file", file, true, []int{0, 0, 0}},
+		{"stored differently", encode(a, true), true, []int{0, 0, 0}},
+		{"one pixel", encode(changed, false), false, []int{0, 1, 0}},
+		{"delay", encode(slower, false), false, []int{0, 0, 0}},
+		{"more frames and loops", encode(longer, false), false, []int{0, 0, 0}},
+	}
+	for _, tt := range tests {
+		r, err := Diff(bytes.NewReader(file), bytes.NewReader(tt.b))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if r.Equal() != tt.equal {
+			t.Errorf("%s: Equal = %v, want %v", tt.name, r.Equal(), tt.equal)
+		}
+		if len(r.Frames) != len(tt.mismatched) {
+			t.Errorf("%s: %d frames compared, want %d", tt.name, len(r.Frames), len(tt.mismatched))
+			continue
+		}
+		for i, f := range r.Frames {
+			if f.Index != i || f.Mismatched != tt.mismatched[i] {
+				t.Errorf("%s: frame %d: index %d, %d pixels differ; want %d", tt.name, i, f.Index, f.Mismatched, tt.mismatched[i])
+			}
+		}
+	}
+
+	r, err := Diff(bytes.NewReader(file), bytes.NewReader(encode(changed, false)))
+	if err == nil && r.Frames[1].Bounds != image.Rect(4, 2, 5, 3) {
+		t.Errorf("one pixel: bounds %v, want (4,2)-(5,3)", r.Frames[1].Bounds)
+	}
+	r, err = Diff(bytes.NewReader(file), bytes.NewReader(encode(slower, false)))
+	if err == nil && (r.Frames[2].DelayA != 100*time.Millisecond || r.Frames[2].DelayB != 150*time.Millisecond || r.Frames[2].Equal()) {
+		t.Errorf("delay: frame 2 %+v, want delays 100ms and 150ms", r.Frames[2])
+	}
+	r, err = Diff(bytes.NewReader(file), bytes.NewReader(encode(longer, false)))
+	if err == nil && (r.FramesA != 3 || r.FramesB != 4 || r.LoopCountA != 0 || r.LoopCountB != 2) {
+		t.Errorf("more frames: %d and %d frames, loop counts %d and %d", r.FramesA, r.FramesB, r.LoopCountA, r.LoopCountB)
+	}
+
+	if _, err := Diff(bytes.NewReader(file), bytes.NewReader(file[:40])); err == nil {
+		t.Error("truncated file: got no error")
+	}
+}
@@ -0,0 +1,169 @@
+package goapng
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EncodeDir encodes the PNG files of fsys matching glob, in the order of
+// LoadFrames, as the frames of an animation that shows each of them for
+// delay and loops forever. Use os.DirFS to read frames from a directory. If
+// the frames don't share a color model, every frame is converted to
+// *image.NRGBA.
+func EncodeDir(w io.Writer, fsys fs.FS, glob string, delay time.Duration) error {
+	a, err := loadDir(fsys, glob, delay)
+	if err != nil {
+		return err
+	}
+	return EncodeAll(w, a)
+}
+
+// loadDir decodes the frames of EncodeDir.
+func loadDir(fsys fs.FS, glob string, delay time.Duration) (*APNG, error) {
+	imgs, err := LoadFrames(fsys, glob)
+	if err != nil {
+		return nil, err
+	}
+	a := &APNG{
+		Images:    imgs,
+		Durations: make([]time.Duration, len(imgs)),
+	}
+	for i := range a.Durations {
+		a.Durations[i] = delay
+	}
+	return a, nil
+}
+
+// LoadFrames decodes the PNG files of fsys matching pattern, in natural
+// order of their names, so that frame2.png comes before frame10.png, and
+// returns them ready to be the Images of an APNG. If the frames don't share
+// a color model, every frame is converted to *image.NRGBA.
+func LoadFrames(fsys fs.FS, pattern string) ([]image.Image, error) {
+	names, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, errors.New("apng: no files match " + pattern)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return naturalLess(names[i], names[j])
+	})
+
+	imgs := make([]image.Image, len(names))
+	for i, name := range names {
+		if imgs[i], err = decodeFile(fsys, name); err != nil {
+			return nil, err
+		}
+	}
+	if !isSameColorModel(imgs) {
+		for i, img := range imgs {
+			imgs[i] = convertLike(nil, img)
+		}
+	}
+	return imgs, nil
+}
+
+// naturalLess reports whether s sorts before t when runs of digits are
+// compared by their numeric value.
+func naturalLess(s, t string) bool {
+	for s != "" && t != "" {
+		if isDigit(s[0]) && isDigit(t[0]) {
+			ns, nt := digits(s), digits(t)
+			// Compare the numbers without leading zeros by length, then
+			// digit by digit, so that any number of digits works.
+			vs, vt := strings.TrimLeft(s[:ns], "0"), strings.TrimLeft(t[:nt], "0")
+			if len(vs) != len(vt) {
+				return len(vs) < len(vt)
+			}
+			if vs != vt {
+				return vs < vt
+			}
+			if ns != nt {
+				return ns < nt
+			}
+			s, t = s[ns:], t[nt:]
+			continue
+		}
+		if s[0] != t[0] {
+			return s[0] < t[0]
+		}
+		s, t = s[1:], t[1:]
+	}
+	return len(s) < len(t)
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+// digits returns the length of the run of digits at the start of s.
+func digits(s string) int {
+	n := 0
+	for n < len(s) && isDigit(s[n]) {
+		n++
+	}
+	return n
+}
+
+// decodeFile decodes the PNG file name of fsys.
+func decodeFile(fsys fs.FS, name string) (image.Image, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("apng: %s: %w", name, err)
+	}
+	return img, nil
+}
+
+// ExtractFrames decodes the animation read from r and writes each frame to
+// dir as a PNG file named frame_000.png, frame_001.png and so on. The files
+// hold the frames as stored, the size of their frame regions, unless
+// composited is set, in which case they hold the full canvas as each frame
+// is displayed.
+func ExtractFrames(r io.Reader, dir string, composited bool) error {
+	if composited {
+		return DecodeFrames(r, func(i int, img *image.RGBA, _ time.Duration) error {
+			return writeFrameFile(dir, i, img)
+		})
+	}
+	a, err := DecodeAll(r)
+	if err != nil {
+		return err
+	}
+	for i, img := range a.Images {
+		if err := writeFrameFile(dir, i, img); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// frameFileName returns the name of the file holding frame i.
+func frameFileName(i int) string {
+	return fmt.Sprintf("frame_%03d.png", i)
+}
+
+// writeFrameFile writes frame i to dir as a PNG file.
+func writeFrameFile(dir string, i int, img image.Image) error {
+	f, err := os.Create(filepath.Join(dir, frameFileName(i)))
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,53 @@
+package goapng
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestEncodeDirty(t *testing.T) {
+	// A capture buffer reused from frame to frame, with a dirty rectangle
+	// painted per frame.
+	canvas := image.NewNRGBA(image.Rect(0, 0, 10, 8))
+	dirty := []image.Rectangle{{}, image.Rect(1, 1, 3, 2), image.Rect(5, 4, 10, 8), {}}
+	want := &APNG{LoopCount: 1}
+	i := 0
+	next := func() (DirtyFrame, error) {
+		if i == len(dirty) {
+			return DirtyFrame{}, io.EOF
+		}
+		r := dirty[i]
+		draw.Draw(canvas, r, image.NewUniform(color.NRGBA{uint8(50 * i), 0, 0xff, 0xff}), image.Point{}, draw.Src)
+		want.Images = append(want.Images, cloneNRGBA(canvas))
+		want.Durations = append(want.Durations, 20*time.Millisecond)
+		i++
+		var rs []image.Rectangle
+		if !r.Empty() {
+			rs = append(rs, r)
+		}
+		return DirtyFrame{Canvas: canvas, Dirty: rs, Delay: 20 * time.Millisecond}, nil
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeDirty(&buf, next, want.LoopCount); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, diffs := EqualFrames(got, want, 0); !ok {
+		t.Errorf("frames differ: %v", diffs)
+	}
+}
+
+func cloneNRGBA(m *image.NRGBA) *image.NRGBA {
+	c := *m
+	c.Pix = append([]uint8(nil), m.Pix...)
+	return &c
+}
@@ -0,0 +1,387 @@
+package goapng
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"time"
+)
+
+// Trim removes frames from through to-1 of a. Frames after the removed
+// range that drew onto it are replaced by the full canvas they displayed,
+// so the remaining frames look as they did. Should the image type of the
+// first frame be unable to hold those canvases, every frame is converted
+// to one that can, as PingPong does. If foldDelays is set, the delays of
+// the removed frames are added to the preceding frame, or to the following
+// one when the range starts at the first frame.
+func Trim(a *APNG, from, to int, foldDelays bool) error {
+	n := len(a.Images)
+	if err := checkTrim(from, to, n); err != nil {
+		return err
+	}
+	if from == to {
+		return nil
+	}
+
+	if to < n {
+		flattenFrom(a, to)
+	}
+
+	if foldDelays {
+		dst := from - 1
+		if dst < 0 {
+			dst = to
+		}
+		for i := from; i < to; i++ {
+			a.foldDelay(dst, i)
+		}
+	}
+
+	*a = *a.selectFrames(append(frameRange(0, from), frameRange(to, n)...))
+	return nil
+}
+
+// checkTrim checks that frames from through to-1 of an animation of n
+// frames can be trimmed.
+func checkTrim(from, to, n int) error {
+	if from < 0 || to > n || from > to {
+		return fmt.Errorf("%w: trim range %d to %d of %d frames", ErrFrameIndex, from, to, n)
+	}
+	if from < to && to-from == n {
+		return errors.New("apng: cannot trim every frame")
+	}
+	return nil
+}
+
+// Reverse reverses the order of the frames of a. Since frames may only
+// hold the changes from the frames before them, every frame is first
+// replaced by the full canvas it displays.
+func Reverse(a *APNG) {
+	if len(a.Images) == 0 {
+		return
+	}
+	full := renderAll(a)
+	idx := make([]int, len(full))
+	imgs := make([]image.Image, len(full))
+	for i := range idx {
+		idx[i] = len(idx) - 1 - i
+		imgs[i] = full[idx[i]]
+	}
+	*a = *a.selectFrames(idx)
+	a.setFlat(imgs)
+}
+
+// PingPong appends the frames of a in reverse order, without repeating the
+// last and first frames, so that the animation plays forwards and then
+// backwards. The original frames keep their regions; the appended ones
+// are full canvases that don't depend on the frames before them. If the
+// image type of the first frame cannot hold those canvases exactly, every
+// frame is converted to *image.NRGBA, or *image.NRGBA64 for 16-bit frames.
+func PingPong(a *APNG) {
+	n := len(a.Images)
+	if n < 3 {
+		return
+	}
+	full := renderAll(a)
+	idx := frameRange(0, n)
+	for i := n - 2; i > 0; i-- {
+		idx = append(idx, i)
+	}
+	b := a.selectFrames(idx)
+	b.ensureOps()
+	for p := n; p < len(idx); p++ {
+		b.Images[p] = full[idx[p]]
+		b.Disposals[p] = DisposeOpNone
+		b.Blends[p] = BlendOpSource
+	}
+	*a = *b
+}
+
+// Concat decodes the animations read from inputs and writes them to w as a
+// single animation, played one after another. The animations must have the
+// same canvas size. If their frames don't share a color model, every frame
+// is converted to *image.NRGBA. The loop count is taken from the first
+// animation.
+func Concat(w io.Writer, inputs ...io.Reader) error {
+	if len(inputs) == 0 {
+		return errors.New("apng: need at least one input")
+	}
+
+	out := new(APNG)
+	for i, r := range inputs {
+		a, err := DecodeAll(r)
+		if err != nil {
+			return err
+		}
+		if len(a.Images) == 0 {
+			continue
+		}
+		if i == 0 {
+			out.LoopCount = a.LoopCount
+			out.Config = a.Config
+		} else if a.Config.Width != out.Config.Width || a.Config.Height != out.Config.Height {
+			return errors.New("apng: mismatched canvas sizes")
+		}
+
+		// Start each animation on a cleared canvas.
+		flattenFrom(a, 0)
+
+		out.Images = append(out.Images, a.Images...)
+		out.Delays = append(out.Delays, a.Delays...)
+		out.DelayDens = append(out.DelayDens, a.DelayDens...)
+		out.Disposals = append(out.Disposals, a.Disposals...)
+		out.Blends = append(out.Blends, a.Blends...)
+	}
+
+	if !isSameColorModel(out.Images) {
+		for i, img := range out.Images {
+			out.Images[i] = convertLike(nil, img)
+		}
+	}
+	return EncodeAll(w, out)
+}
+
+// Split decodes the animation read from r and splits it into standalone
+// animations of about every in length: a new animation starts with the
+// first frame displayed at or after each multiple of every. Each one opens
+// with the full canvas displayed at the split point, so it plays exactly
+// like its part of the original.
+func Split(r io.Reader, every time.Duration) ([]*APNG, error) {
+	if every <= 0 {
+		return nil, errors.New("apng: split interval must be positive")
+	}
+	a, err := DecodeAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var starts []int
+	var next time.Duration
+	for i, t := range a.Timestamps() {
+		if t >= next {
+			starts = append(starts, i)
+			for next <= t {
+				next += every
+			}
+		}
+	}
+	return split(a, starts), nil
+}
+
+// SplitFrames is like Split but starts a new animation every n frames.
+func SplitFrames(r io.Reader, n int) ([]*APNG, error) {
+	if n <= 0 {
+		return nil, errors.New("apng: split frame count must be positive")
+	}
+	a, err := DecodeAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var starts []int
+	for i := 0; i < len(a.Images); i += n {
+		starts = append(starts, i)
+	}
+	return split(a, starts), nil
+}
+
+// split cuts a before each frame in starts, which begins with 0.
+func split(a *APNG, starts []int) []*APNG {
+	out := make([]*APNG, len(starts))
+	for k, s := range starts {
+		e := len(a.Images)
+		if k+1 < len(starts) {
+			e = starts[k+1]
+		}
+		if s > 0 {
+			flattenFrom(a, s)
+		}
+		out[k] = a.slice(s, e)
+	}
+	return out
+}
+
+// Reorder rebuilds a with the frames in the given order, a list of frame
+// indices that may leave out or repeat frames. A frame that no longer
+// follows the frame it used to follow is replaced by the full canvas it
+// displayed, as are the frames after it that draw onto its disposal, so
+// every frame still looks as it did. If those canvases don't fit the image
+// type of the first frame exactly, the frames kept as they were are
+// converted along with them.
+func Reorder(a *APNG, order []int) error {
+	if len(order) == 0 {
+		return ErrNoFrames
+	}
+	for _, k := range order {
+		if k < 0 || k >= len(a.Images) {
+			return ErrFrameIndex
+		}
+	}
+
+	full := renderAll(a)
+	b := a.selectFrames(order)
+	b.ensureOps()
+
+	flattening := false
+	for p, k := range order {
+		if p == 0 || order[p-1] != k-1 {
+			flattening = p > 0 || k > 0
+		}
+		if flattening {
+			b.Images[p] = full[k]
+			b.Disposals[p] = DisposeOpNone
+			b.Blends[p] = BlendOpSource
+			flattening = a.disposal(k) != DisposeOpNone
+		}
+	}
+
+	*a = *b
+	return nil
+}
+
+// ReplaceFrame replaces frame n of a with img, the full canvas to display
+// at that point. The frames after it that drew onto frame n are replaced by
+// the full canvas they displayed, so every other frame looks as it did.
+// img is stored in the image type of the first frame unless that type
+// cannot hold it exactly, in which case every frame is converted to one
+// that can.
+func ReplaceFrame(a *APNG, n int, img image.Image) error {
+	if n < 0 || n >= len(a.Images) {
+		return ErrFrameIndex
+	}
+	if img.Bounds() != a.bounds() {
+		return errors.New("apng: replacement frame must cover the canvas")
+	}
+
+	if n+1 < len(a.Images) {
+		flattenFrom(a, n+1)
+	}
+	a.ensureOps()
+	a.Images[n] = a.likeFrames([]image.Image{img})[0]
+	a.Disposals[n] = DisposeOpNone
+	a.Blends[n] = BlendOpSource
+	return nil
+}
+
+// ReplaceFrameStream copies the APNG read from r to w with frame n replaced
+// by img, as ReplaceFrame does. Only frame n and the frames that drew onto
+// it are decoded and re-encoded; the data of every other frame is copied
+// verbatim.
+func ReplaceFrameStream(w io.Writer, r io.Reader, n int, img image.Image) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	d := &decoder{ctx: context.Background()}
+	if err := d.readChunks(bytes.NewReader(data)); err != nil {
+		return err
+	}
+	if n < 0 || n >= len(d.frames) {
+		return ErrFrameIndex
+	}
+	if img.Bounds() != image.Rect(0, 0, d.width, d.height) {
+		return errors.New("apng: replacement frame must cover the canvas")
+	}
+	format, err := formatFromIHDR(d.ihdr, d.plte, d.trns)
+	if err != nil {
+		return err
+	}
+
+	// Re-encode frame n and, as in flattenFrom, the frames after it up to
+	// the first one that is not disposed of.
+	end := n
+	if n+1 < len(d.frames) {
+		end = n + 1
+		for end < len(d.frames)-1 && d.frames[end].fc.disposeOp != DisposeOpNone {
+			end++
+		}
+	}
+	replaced := make([]*pngChunk, end-n+1)
+	c := newCompositor(d.width, d.height)
+	for i := 0; i <= end; i++ {
+		m, err := d.decodeFrame(d.frames[i])
+		if err != nil {
+			return err
+		}
+		canvas := c.render(m, d.frames[i].fc.disposeOp, d.frames[i].fc.blendOp)
+		if i == n {
+			m = img
+		} else if i > n {
+			m = canvas
+		} else {
+			continue
+		}
+		if replaced[i-n], err = encodeScanlines(m, &format, ZlibCompressor{}, DefaultCompression, nil); err != nil {
+			return err
+		}
+	}
+
+	cr := newChunkReader(bytes.NewReader(data))
+	cr.readSignature()
+	e := encoder{w: w}
+	_, e.err = io.WriteString(w, pngHeader)
+	frameIndex := -1
+	wroteIDATs := false
+	for e.err == nil {
+		name, data, err := cr.next()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		idx := frameIndex
+		if !d.order.seenacTL {
+			idx = 0 // A static PNG holds a single frame.
+		}
+		pc := (*pngChunk)(nil)
+		if n <= idx && idx <= end {
+			pc = replaced[idx-n]
+		}
+
+		switch name {
+		case "fcTL":
+			frameIndex++
+			if n <= frameIndex && frameIndex <= end {
+				writeUint32(data[4:8], uint32(d.width))
+				writeUint32(data[8:12], uint32(d.height))
+				writeUint32(data[12:16], 0)
+				writeUint32(data[16:20], 0)
+				data[24] = DisposeOpNone
+				data[25] = BlendOpSource
+			}
+			writeUint32(data[0:4], e.seqNum)
+			e.seqNum++
+			e.writeChunk(data, name)
+			// Frame 0 is written in IDAT chunks unless the default image
+			// is kept apart.
+			if (frameIndex > 0 || !d.order.idatFrame) && n <= frameIndex && frameIndex <= end {
+				e.idats = replaced[frameIndex-n].idats
+				e.writefdATs()
+			}
+		case "IDAT":
+			if idx == 0 && pc != nil {
+				if !wroteIDATs {
+					e.idats = pc.idats
+					e.writeIDATs()
+					wroteIDATs = true
+				}
+				continue
+			}
+			e.writeChunk(data, name)
+		case "fdAT":
+			if pc != nil {
+				continue
+			}
+			writeUint32(data[0:4], e.seqNum)
+			e.seqNum++
+			e.writeChunk(data, name)
+		default:
+			e.writeChunk(data, name)
+		}
+		if name == "IEND" {
+			break
+		}
+	}
+	return e.err
+}
@@ -0,0 +1,45 @@
+package goapng
+
+import (
+	"bytes"
+	"image"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestEncodeDir(t *testing.T) {
+	a := testAPNG(3, 4, 4)
+	fsys := fstest.MapFS{
+		"frames/b.png":     pngFile(t, a.Images[1]),
+		"frames/a.png":     pngFile(t, a.Images[0]),
+		"frames/c.png":     pngFile(t, a.Images[2]),
+		"frames/notes.txt": &fstest.MapFile{Data: []byte("not a frame")},
+	}
+	var buf bytes.Buffer
+	if err := EncodeDir(&buf, fsys, "frames/*.png", 40*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := copyAPNG(a)
+	want.Durations = []time.Duration{40 * time.Millisecond, 40 * time.Millisecond, 40 * time.Millisecond}
+	want.LoopCount = LoopForever
+	if ok, diffs := EqualFrames(got, want, 0); !ok {
+		t.Errorf("frames differ: %v", diffs)
+	}
+
+	if err := EncodeDir(&buf, fsys, "frames/*.gif", time.Second); err == nil {
+		t.Error("no matching files: got no error")
+	}
+	if err := EncodeDir(&buf, fsys, "frames/*", time.Second); err == nil {
+		t.Error("a file that isn't a PNG: got no error")
+	}
+	// Frames of different sizes can't be encoded.
+	fsys["frames/d.png"] = pngFile(t, image.NewNRGBA(image.Rect(0, 0, 5, 4)))
+	if err := EncodeDir(&buf, fsys, "frames/*.png", time.Second); err == nil {
+		t.Error("frames of different sizes: got no error")
+	}
+}
@@ -0,0 +1,61 @@
+package goapng
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestEqualFrames(t *testing.T) {
+	a := testAPNG(3, 6, 6)
+	// Frame 1 with one pixel 3 off in red and green.
+	c := a.Images[1].(*image.NRGBA).NRGBAAt(0, 0)
+	m := solid(6, 6, c)
+	m.SetNRGBA(2, 5, color.NRGBA{c.R + 3, c.G - 3, c.B, c.A})
+	near := copyAPNG(a)
+	near.Images[1] = m
+
+	tests := []struct {
+		name       string
+		b          *APNG
+		tolerance  int
+		equal      bool
+		mismatched []int // Per frame, or nil for no frames described.
+	}{
+		{"same", copyAPNG(a), 0, true, []int{0, 0, 0}},
+		{"within tolerance", near, 3, true, []int{0, 0, 0}},
+		{"outside tolerance", near, 2, false, []int{0, 1, 0}},
+		{"invalid", &APNG{Images: a.Images[:2], Durations: a.Durations}, 0, false, nil},
+	}
+	for _, tt := range tests {
+		equal, diffs := EqualFrames(a, tt.b, tt.tolerance)
+		if equal != tt.equal {
+			t.Errorf("%s: got %v, want %v", tt.name, equal, tt.equal)
+		}
+		if len(diffs) != len(tt.mismatched) {
+			t.Errorf("%s: %d frames described, want %d", tt.name, len(diffs), len(tt.mismatched))
+			continue
+		}
+		for i, f := range diffs {
+			if f.Mismatched != tt.mismatched[i] {
+				t.Errorf("%s: frame %d: %d pixels differ, want %d", tt.name, i, f.Mismatched, tt.mismatched[i])
+			}
+		}
+	}
+	if _, diffs := EqualFrames(a, near, 0); len(diffs) == 3 && diffs[1].Bounds != image.Rect(2, 5, 3, 6) {
+		t.Errorf("bounds %v, want (2,5)-(3,6)", diffs[1].Bounds)
+	}
+
+	// The delays, loop count and canvas take part whatever the tolerance.
+	slower := copyAPNG(a)
+	slower.Durations[0] = time.Second
+	looped := copyAPNG(a)
+	looped.LoopCount = 3
+	wider := testAPNG(3, 7, 6)
+	for name, b := range map[string]*APNG{"delay": slower, "loop count": looped, "canvas": wider} {
+		if equal, _ := EqualFrames(a, b, 255); equal {
+			t.Errorf("%s: got equal with any tolerance", name)
+		}
+	}
+}
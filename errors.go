@@ -0,0 +1,53 @@
+package goapng
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"strings"
+)
+
+// Errors returned by the encoder and the editing functions. They may be
+// wrapped, in a FrameError or otherwise; use errors.Is to test for them.
+var (
+	ErrNoFrames         = errors.New("apng: need at least one image")
+	ErrDelayMismatch    = errors.New("apng: mismatched image and delay lengths")
+	ErrDelayDenMismatch = errors.New("apng: mismatched image and delay denominator lengths")
+	ErrDurationMismatch = errors.New("apng: mismatched image and duration lengths")
+	ErrDisposalMismatch = errors.New("apng: mismatch image and disposal lengths")
+	ErrBlendMismatch    = errors.New("apng: mismatch image and blend lengths")
+	ErrLoopCount        = errors.New("apng: loop count too large")
+	ErrNilFrame         = errors.New("apng: nil image")
+	ErrColorModel       = errors.New("apng: color model differs from the first frame")
+	ErrFrameRegion      = errors.New("apng: frame region outside the canvas")
+	ErrFrameOrigin      = errors.New("apng: frame bounds do not start at the origin")
+	ErrFrameIndex       = errors.New("apng: frame index out of range")
+	ErrMinDelay         = errors.New("apng: delay below the minimum")
+	ErrChecksum         = errors.New("apng: invalid checksum")
+	ErrChunkTooLarge    = errors.New("apng: chunk is too large")
+)
+
+// FrameError records an error concerning a single frame.
+type FrameError struct {
+	Index int   // The index of the frame.
+	Cause error // What is wrong with it.
+}
+
+func (e *FrameError) Error() string {
+	return fmt.Sprintf("apng: frame %d: %s", e.Index, strings.TrimPrefix(e.Cause.Error(), "apng: "))
+}
+
+func (e *FrameError) Unwrap() error { return e.Cause }
+
+// RegionError reports a frame that does not lie within the canvas. It
+// matches ErrFrameRegion.
+type RegionError struct {
+	Bounds image.Rectangle // The bounds of the frame.
+	Canvas image.Rectangle // The area the frame must lie within.
+}
+
+func (e *RegionError) Error() string {
+	return fmt.Sprintf("apng: frame region %v is outside the canvas %v", e.Bounds, e.Canvas)
+}
+
+func (e *RegionError) Is(target error) bool { return target == ErrFrameRegion }
@@ -0,0 +1,77 @@
+package goapng
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtractFrames(t *testing.T) {
+	a := testAPNG(3, 6, 6)
+	// Frame 1 covers only a 2x2 region over frame 0.
+	a.Images[1] = solid(2, 2, color.NRGBA{1, 2, 3, 0xff})
+	a.Images[1] = translate(a.Images[1], image.Pt(3, 1))
+	var buf bytes.Buffer
+	if err := EncodeAll(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+	read := func(dir string, i int) image.Image {
+		t.Helper()
+		f, err := os.Open(filepath.Join(dir, frameFileName(i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		m, err := png.Decode(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	for _, composited := range []bool{false, true} {
+		dir := t.TempDir()
+		if err := ExtractFrames(bytes.NewReader(buf.Bytes()), dir, composited); err != nil {
+			t.Fatalf("composited %v: %v", composited, err)
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 3 || entries[0].Name() != "frame_000.png" || entries[2].Name() != "frame_002.png" {
+			t.Errorf("composited %v: got %d files, want frame_000.png to frame_002.png", composited, len(entries))
+			continue
+		}
+		m := read(dir, 1)
+		b := m.Bounds()
+		if composited {
+			// The full canvas, with frame 0 around the new region.
+			if b.Size() != image.Pt(6, 6) {
+				t.Errorf("composited: frame 1 is %v, want the 6x6 canvas", b)
+			}
+			for _, p := range []struct {
+				x, y int
+				want color.Color
+			}{
+				{3, 1, a.Images[1].At(3, 1)},
+				{4, 2, a.Images[1].At(4, 2)},
+				{0, 0, a.Images[0].At(0, 0)},
+				{5, 5, a.Images[0].At(5, 5)},
+			} {
+				if got, want := color.NRGBAModel.Convert(m.At(b.Min.X+p.x, b.Min.Y+p.y)), color.NRGBAModel.Convert(p.want); got != want {
+					t.Errorf("composited: frame 1 at %d,%d: %v, want %v", p.x, p.y, got, want)
+				}
+			}
+		} else if b.Size() != image.Pt(2, 2) {
+			t.Errorf("as stored: frame 1 is %v, want its 2x2 region", b)
+		}
+	}
+
+	if err := ExtractFrames(bytes.NewReader(buf.Bytes()), filepath.Join(t.TempDir(), "missing"), false); err == nil {
+		t.Error("missing directory: got no error")
+	}
+}
@@ -0,0 +1,74 @@
+package goapng
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestFade(t *testing.T) {
+	at := func(m image.Image) color.NRGBA {
+		return color.NRGBAModel.Convert(m.At(1, 1)).(color.NRGBA)
+	}
+	black := color.NRGBA{0, 0, 0, 0xff}
+	white := color.NRGBA{0xff, 0xff, 0xff, 0xff}
+
+	a := testAPNG(2, 4, 4)
+	first := at(a.Images[0])
+	if err := FadeIn(a, black, 3, 90*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if want := "[30ms 30ms 30ms 100ms 100ms]"; fmt.Sprint(a.Durations) != want {
+		t.Errorf("FadeIn: durations %v, want %s", a.Durations, want)
+	}
+	if got, want := at(a.Images[0]), (color.NRGBA{uint8((int(first.R) + 2) / 4), uint8((int(first.G) + 2) / 4), 0, 0xff}); got != want {
+		t.Errorf("FadeIn: first frame %v, want %v", got, want)
+	}
+	if got := at(a.Images[3]); got != first {
+		t.Errorf("FadeIn: frame 3 is %v, want the first frame of the animation, %v", got, first)
+	}
+	if _, ok := a.Images[0].(*image.NRGBA); !ok {
+		t.Errorf("FadeIn: frames are %T, want *image.NRGBA like the input", a.Images[0])
+	}
+
+	a = testAPNG(2, 4, 4)
+	if err := FadeOut(a, white, 2, 100*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if want := "[100ms 100ms 50ms 50ms]"; fmt.Sprint(a.Durations) != want {
+		t.Errorf("FadeOut: durations %v, want %s", a.Durations, want)
+	}
+	// The fade ends on the color alone.
+	if got := at(a.Images[3]); got != white {
+		t.Errorf("FadeOut: last frame %v, want %v", got, white)
+	}
+	last := at(testAPNG(2, 4, 4).Images[1])
+	if got, want := at(a.Images[2]), (color.NRGBA{uint8((int(last.R) + 256) / 2), uint8((int(last.G) + 256) / 2), uint8((int(last.B) + 256) / 2), 0xff}); got != want {
+		t.Errorf("FadeOut: halfway frame %v, want %v", got, want)
+	}
+
+	// A nil color fades from transparent.
+	a = testAPNG(1, 4, 4)
+	if err := FadeIn(a, nil, 1, 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if got := at(a.Images[0]); got.A != 0x80 {
+		t.Errorf("FadeIn from nil: alpha %#x, want 0x80", got.A)
+	}
+	if err := Validate(a); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+
+	a = testAPNG(2, 4, 4)
+	if err := FadeOut(a, black, 0, time.Second); err != nil || len(a.Images) != 2 {
+		t.Errorf("no steps: got %d frames, error %v; want 2 frames", len(a.Images), err)
+	}
+	if err := FadeIn(a, black, -1, time.Second); err == nil {
+		t.Error("negative steps: got no error")
+	}
+	if err := FadeOut(a, black, 1, -time.Second); err == nil {
+		t.Error("negative duration: got no error")
+	}
+}
@@ -0,0 +1,233 @@
+package goapng
+
+// Filter types, as written before each scanline.
+const (
+	ftNone    = 0
+	ftSub     = 1
+	ftUp      = 2
+	ftAverage = 3
+	ftPaeth   = 4
+)
+
+// The kernels below re-slice their arguments to a common length before
+// looping so the compiler can drop the per-byte bounds checks; filtering
+// dominates the CPU time spent on large frames. Each returns the sum of
+// absolute values of the filtered bytes, and may stop early once the sum
+// reaches limit since the row then can't win the heuristic.
+
+// abs8 returns the absolute value of d interpreted as a signed byte.
+func abs8(d uint8) int {
+	v := int(int8(d))
+	m := v >> 31
+	return (v ^ m) - m
+}
+
+func sumNone(cur []byte, limit int) int {
+	sum := 0
+	for _, c := range cur {
+		sum += abs8(c)
+		if sum >= limit {
+			break
+		}
+	}
+	return sum
+}
+
+func filterSub(dst, cur []byte, bpp, limit int) int {
+	n := len(cur)
+	if bpp > n {
+		bpp = n
+	}
+	dst = dst[:n]
+	sum := 0
+	for i, c := range cur[:bpp] {
+		dst[i] = c
+		sum += abs8(c)
+	}
+	left := cur[:n-bpp]
+	right := cur[bpp:]
+	out := dst[bpp:]
+	out = out[:len(right)]
+	left = left[:len(right)]
+	for i, c := range right {
+		d := c - left[i]
+		out[i] = d
+		sum += abs8(d)
+		if sum >= limit {
+			break
+		}
+	}
+	return sum
+}
+
+func filterUp(dst, cur, prev []byte, limit int) int {
+	dst = dst[:len(cur)]
+	prev = prev[:len(cur)]
+	sum := 0
+	for i, c := range cur {
+		d := c - prev[i]
+		dst[i] = d
+		sum += abs8(d)
+		if sum >= limit {
+			break
+		}
+	}
+	return sum
+}
+
+func filterAverage(dst, cur, prev []byte, bpp, limit int) int {
+	n := len(cur)
+	if bpp > n {
+		bpp = n
+	}
+	dst = dst[:n]
+	prev = prev[:n]
+	sum := 0
+	for i, c := range cur[:bpp] {
+		d := c - prev[i]/2
+		dst[i] = d
+		sum += abs8(d)
+	}
+	left := cur[:n-bpp]
+	right := cur[bpp:]
+	up := prev[bpp:]
+	out := dst[bpp:]
+	up = up[:len(right)]
+	out = out[:len(right)]
+	left = left[:len(right)]
+	for i, c := range right {
+		d := c - uint8((int(left[i])+int(up[i]))/2)
+		out[i] = d
+		sum += abs8(d)
+		if sum >= limit {
+			break
+		}
+	}
+	return sum
+}
+
+func filterPaeth(dst, cur, prev []byte, bpp, limit int) int {
+	n := len(cur)
+	if bpp > n {
+		bpp = n
+	}
+	dst = dst[:n]
+	prev = prev[:n]
+	sum := 0
+	for i, c := range cur[:bpp] {
+		d := c - prev[i]
+		dst[i] = d
+		sum += abs8(d)
+	}
+	left := cur[:n-bpp]
+	right := cur[bpp:]
+	up := prev[bpp:]
+	upLeft := prev[:n-bpp]
+	out := dst[bpp:]
+	up = up[:len(right)]
+	upLeft = upLeft[:len(right)]
+	out = out[:len(right)]
+	left = left[:len(right)]
+	for i, c := range right {
+		a, b, cc := int(left[i]), int(up[i]), int(upLeft[i])
+		p := b - cc
+		q := a - cc
+		pa := (p ^ (p >> 31)) - (p >> 31)
+		pb := (q ^ (q >> 31)) - (q >> 31)
+		r := p + q
+		pc := (r ^ (r >> 31)) - (r >> 31)
+		pred := cc
+		if pa <= pb && pa <= pc {
+			pred = a
+		} else if pb <= pc {
+			pred = b
+		}
+		d := c - uint8(pred)
+		out[i] = d
+		sum += abs8(d)
+		if sum >= limit {
+			break
+		}
+	}
+	return sum
+}
+
+// filter chooses the filter for the current row cr[ftNone] given the
+// previous row pr, and applies it. It tries every filter and picks the one
+// that minimizes the sum of absolute differences, the heuristic used by
+// libpng and image/png, in the same order as image/png so that both make
+// the same choice. The return value is the index of the filter and of the
+// row in cr that has had it applied. Rows exclude the filter type byte.
+func filter(cr *[filterNum][]byte, pr []byte, bpp int) int {
+	cur := cr[ftNone]
+	const max = int(^uint(0) >> 1)
+
+	best := filterUp(cr[ftUp], cur, pr, max)
+	ft := ftUp
+
+	if sum := filterPaeth(cr[ftPaeth], cur, pr, bpp, best); sum < best {
+		best, ft = sum, ftPaeth
+	}
+	if sum := sumNone(cur, best); sum < best {
+		best, ft = sum, ftNone
+	}
+	if sum := filterSub(cr[ftSub], cur, bpp, best); sum < best {
+		best, ft = sum, ftSub
+	}
+	if sum := filterAverage(cr[ftAverage], cur, pr, bpp, best); sum < best {
+		ft = ftAverage
+	}
+	return ft
+}
+
+// unfilter reverses the filter named by row[0] on the rest of row, given
+// the previous row prev, unfiltered and without its filter type byte.
+func unfilter(row, prev []byte, bpp int) error {
+	cur := row[1:]
+	prev = prev[:len(cur)]
+	switch row[0] {
+	case ftNone:
+	case ftSub:
+		for i := bpp; i < len(cur); i++ {
+			cur[i] += cur[i-bpp]
+		}
+	case ftUp:
+		for i, p := range prev {
+			cur[i] += p
+		}
+	case ftAverage:
+		for i := range cur {
+			left := 0
+			if i >= bpp {
+				left = int(cur[i-bpp])
+			}
+			cur[i] += uint8((left + int(prev[i])) / 2)
+		}
+	case ftPaeth:
+		for i := range cur {
+			a, b, c := 0, int(prev[i]), 0
+			if i >= bpp {
+				a, c = int(cur[i-bpp]), int(prev[i-bpp])
+			}
+			pa, pb, pc := absInt(b-c), absInt(a-c), absInt(a+b-2*c)
+			switch {
+			case pa <= pb && pa <= pc:
+				cur[i] += uint8(a)
+			case pb <= pc:
+				cur[i] += uint8(b)
+			default:
+				cur[i] += uint8(c)
+			}
+		}
+	default:
+		return FormatError("bad filter type")
+	}
+	return nil
+}
+
+func absInt(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,125 @@
+package goapng
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// refFilter applies filter ft to cur, with previous row prev, as the PNG
+// spec defines it.
+func refFilter(ft int, cur, prev []byte, bpp int) []byte {
+	at := func(b []byte, i int) int {
+		if i < 0 {
+			return 0
+		}
+		return int(b[i])
+	}
+	out := make([]byte, len(cur))
+	for i := range cur {
+		a, b, c := at(cur, i-bpp), at(prev, i), at(prev, i-bpp)
+		var pred int
+		switch ft {
+		case ftSub:
+			pred = a
+		case ftUp:
+			pred = b
+		case ftAverage:
+			pred = (a + b) / 2
+		case ftPaeth:
+			p := a + b - c
+			pa, pb, pc := iabs(p-a), iabs(p-b), iabs(p-c)
+			switch {
+			case pa <= pb && pa <= pc:
+				pred = a
+			case pb <= pc:
+				pred = b
+			default:
+				pred = c
+			}
+		}
+		out[i] = cur[i] - byte(pred)
+	}
+	return out
+}
+
+func iabs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func sumAbs(b []byte) int {
+	sum := 0
+	for _, c := range b {
+		sum += abs8(c)
+	}
+	return sum
+}
+
+func TestFilterKernels(t *testing.T) {
+	const max = int(^uint(0) >> 1)
+	kernels := []struct {
+		ft  int
+		run func(dst, cur, prev []byte, bpp, limit int) int
+	}{
+		{ftSub, func(dst, cur, prev []byte, bpp, limit int) int { return filterSub(dst, cur, bpp, limit) }},
+		{ftUp, func(dst, cur, prev []byte, bpp, limit int) int { return filterUp(dst, cur, prev, limit) }},
+		{ftAverage, filterAverage},
+		{ftPaeth, filterPaeth},
+	}
+	rng := rand.New(rand.NewSource(1))
+	rows := []struct {
+		name string
+		gen  func(i int) byte
+	}{
+		{"zero", func(int) byte { return 0 }},
+		{"ramp", func(i int) byte { return byte(i * 3) }},
+		{"extremes", func(i int) byte { return byte(i%2) * 0xff }},
+		{"random", func(int) byte { return byte(rng.Intn(256)) }},
+	}
+	for _, bpp := range []int{1, 2, 3, 4, 6, 8} {
+		for _, n := range []int{1, 2, 5, 17, 64} {
+			for _, row := range rows {
+				cur, prev := make([]byte, n), make([]byte, n)
+				for i := range cur {
+					cur[i], prev[i] = row.gen(i), row.gen(i+7)
+				}
+				for _, k := range kernels {
+					want := refFilter(k.ft, cur, prev, bpp)
+					dst := make([]byte, n)
+					sum := k.run(dst, cur, prev, bpp, max)
+					if string(dst) != string(want) {
+						t.Errorf("filter %d, bpp %d, %d bytes, %s: got %v, want %v", k.ft, bpp, n, row.name, dst, want)
+					}
+					if sum != sumAbs(want) {
+						t.Errorf("filter %d, bpp %d, %d bytes, %s: sum %d, want %d", k.ft, bpp, n, row.name, sum, sumAbs(want))
+					}
+					// A limit may cut the sum short, but never below it.
+					if limit := sumAbs(want) / 2; limit > 0 {
+						if sum := k.run(make([]byte, n), cur, prev, bpp, limit); sum < limit {
+							t.Errorf("filter %d, bpp %d, %d bytes, %s: sum %d with limit %d", k.ft, bpp, n, row.name, sum, limit)
+						}
+					}
+				}
+
+				// filter picks a filter with the least sum.
+				var cr [filterNum][]byte
+				cr[ftNone] = cur
+				for ft := ftSub; ft < filterNum; ft++ {
+					cr[ft] = make([]byte, n)
+				}
+				ft := filter(&cr, prev, bpp)
+				best := sumAbs(cur)
+				for f := ftSub; f < filterNum; f++ {
+					if s := sumAbs(refFilter(f, cur, prev, bpp)); s < best {
+						best = s
+					}
+				}
+				if got := sumAbs(refFilter(ft, cur, prev, bpp)); got != best {
+					t.Errorf("bpp %d, %d bytes, %s: filter chose %d with sum %d, least is %d", bpp, n, row.name, ft, got, best)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package goapng
+
+import (
+	"image"
+	"time"
+)
+
+// Frame is one frame of an animation: its image, how long it is displayed
+// and how it is drawn. Holding a frame's fields together, rather than in
+// the parallel slices of APNG, means they can't disagree in length.
+type Frame struct {
+	Image    image.Image
+	Delay    time.Duration
+	Disposal byte // The disposal method; DisposeOpNone by default.
+	Blend    byte // The blend operation; BlendOpSource by default.
+
+	// Offset moves Image on the canvas, so that its frame region is
+	// Image.Bounds().Add(Offset). The frames returned by APNG.Frames keep
+	// their position in the bounds of Image and have a zero Offset.
+	Offset image.Point
+}
+
+// image returns the image of f, moved by its offset.
+func (f Frame) image() image.Image {
+	if f.Offset == (image.Point{}) {
+		return f.Image
+	}
+	// translate moves images in place, so it is given a new image sharing
+	// the pixels of f.Image.
+	return translate(subImage(f.Image, f.Image.Bounds()), f.Offset)
+}
+
+// Frames returns the frames of a. The images are shared with a, whose
+// Durations or Delays must have an entry for every frame.
+func (a *APNG) Frames() []Frame {
+	frames := make([]Frame, len(a.Images))
+	ts := a.timeline()
+	for i, img := range a.Images {
+		frames[i] = Frame{
+			Image:    img,
+			Delay:    ts[i+1] - ts[i],
+			Disposal: a.disposal(i),
+			Blend:    a.blend(i),
+		}
+	}
+	return frames
+}
+
+// FromFrames returns an APNG holding frames, which loops forever. The
+// first frame sets the canvas. Images without an offset are shared with
+// frames.
+func FromFrames(frames []Frame) *APNG {
+	a := &APNG{
+		Images:    make([]image.Image, len(frames)),
+		Durations: make([]time.Duration, len(frames)),
+		Disposals: make([]byte, len(frames)),
+		Blends:    make([]byte, len(frames)),
+	}
+	for i, f := range frames {
+		a.Images[i] = f.image()
+		a.Durations[i] = f.Delay
+		a.Disposals[i] = f.Disposal
+		a.Blends[i] = f.Blend
+	}
+	if len(frames) > 0 {
+		b := a.Images[0].Bounds()
+		a.Config = image.Config{
+			ColorModel: a.Images[0].ColorModel(),
+			Width:      b.Dx(),
+			Height:     b.Dy(),
+		}
+	}
+	return a
+}
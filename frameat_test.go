@@ -0,0 +1,59 @@
+package goapng
+
+import (
+	"bytes"
+	"image"
+	"testing"
+	"time"
+)
+
+// decodeLazy returns a lazily decoded copy of a.
+func decodeLazy(t *testing.T, a *APNG) *APNG {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := EncodeAll(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+	b, err := (&Decoder{Lazy: true}).DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// decoded reports which lazily decoded frames of a have been decompressed.
+func decoded(a *APNG) []bool {
+	out := make([]bool, len(a.Images))
+	for i, img := range a.Images {
+		out[i] = img.(*LazyImage).d == nil
+	}
+	return out
+}
+
+func TestCompositedFrameLazy(t *testing.T) {
+	a := testAPNG(4, 6, 6)
+	tests := []struct {
+		name string
+		fn   func(a *APNG) (*image.RGBA, error)
+		want int // The frame displayed.
+	}{
+		{"CompositedFrame", func(a *APNG) (*image.RGBA, error) { return CompositedFrame(a, 1) }, 1},
+		{"FrameAt", func(a *APNG) (*image.RGBA, error) { return FrameAt(a, 250*time.Millisecond) }, 2},
+		{"FrameAt, second play", func(a *APNG) (*image.RGBA, error) { return FrameAt(a, 450*time.Millisecond) }, 0},
+	}
+	for _, tt := range tests {
+		b := decodeLazy(t, a)
+		got, err := tt.fn(b)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !samePixels(got, a.Images[tt.want]) {
+			t.Errorf("%s: does not show frame %d", tt.name, tt.want)
+		}
+		for i, ok := range decoded(b) {
+			if ok != (i <= tt.want) {
+				t.Errorf("%s: frame %d decompressed: %t", tt.name, i, ok)
+			}
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package goapng
+
+import (
+	"compress/zlib"
+	"image"
+	"image/color"
+	"io"
+)
+
+// frameDecoder decompresses the frames of a decoder into a frame buffer
+// that it reuses from one frame to the next, for Decoder.ReuseCanvas.
+// image/png allocates a new image for every frame, so the scanlines are
+// decoded here instead.
+type frameDecoder struct {
+	d         *decoder
+	colorType byte
+	depth     int
+	bits      int // Bits per pixel.
+	bpp       int // Bytes per complete pixel, rounded up to one.
+	interlace bool
+	palette   []color.NRGBA // 256 entries, as indices past PLTE are allowed.
+	trns      []byte        // The tRNS chunk of a grayscale or truecolor image.
+
+	src       idatReader
+	zr        io.ReadCloser
+	cur, prev []byte
+
+	// The frame buffer, as large as the canvas, and the image over the part
+	// of it holding the last frame: an *image.NRGBA64 for 16-bit frames and
+	// an *image.NRGBA otherwise.
+	pix   []byte
+	img   image.NRGBA
+	img64 image.NRGBA64
+}
+
+func newFrameDecoder(d *decoder) *frameDecoder {
+	fd := &frameDecoder{
+		d:         d,
+		colorType: d.ihdr[9],
+		depth:     int(d.ihdr[8]),
+		interlace: d.ihdr[12] == 1,
+	}
+	channels := 1
+	switch fd.colorType {
+	case ctTrueColor:
+		channels = 3
+	case ctGrayscaleAlpha:
+		channels = 2
+	case ctTrueColorAlpha:
+		channels = 4
+	}
+	fd.bits = channels * fd.depth
+	fd.bpp = (fd.bits + 7) / 8
+
+	switch fd.colorType {
+	case ctPaletted:
+		fd.palette = make([]color.NRGBA, 256)
+		for i := range fd.palette {
+			c := color.NRGBA{A: 0xff}
+			if 3*i+2 < len(d.plte) {
+				c.R, c.G, c.B = d.plte[3*i], d.plte[3*i+1], d.plte[3*i+2]
+			}
+			if i < len(d.trns) {
+				c.A = d.trns[i]
+			}
+			fd.palette[i] = c
+		}
+	case ctGrayscale, ctTrueColor:
+		fd.trns = d.trns
+	}
+
+	size := 4
+	if fd.depth == 16 {
+		size = 8
+	}
+	fd.pix = make([]byte, size*d.width*d.height)
+	fd.cur = make([]byte, 1+(d.width*fd.bits+7)/8)
+	fd.prev = make([]byte, len(fd.cur))
+	return fd
+}
+
+// decode decompresses frame f into the frame buffer and returns it, with
+// the frame region as its bounds. The image is only valid until the next
+// call.
+func (fd *frameDecoder) decode(f *rawFrame) (image.Image, error) {
+	idats, err := fd.d.frameData(f)
+	if err != nil {
+		return nil, err
+	}
+	fd.src = idatReader{chunks: idats}
+	if fd.zr == nil {
+		fd.zr, err = zlib.NewReader(&fd.src)
+	} else {
+		err = fd.zr.(zlib.Resetter).Reset(&fd.src, nil)
+	}
+	if err != nil {
+		return nil, FormatError(err.Error())
+	}
+
+	w, h := int(f.fc.width), int(f.fc.height)
+	r := image.Rect(0, 0, w, h).Add(image.Pt(int(f.fc.xOffset), int(f.fc.yOffset)))
+	var m image.Image
+	if fd.depth == 16 {
+		fd.img64 = image.NRGBA64{Pix: fd.pix[:8*w*h], Stride: 8 * w, Rect: r}
+		m = &fd.img64
+	} else {
+		fd.img = image.NRGBA{Pix: fd.pix[:4*w*h], Stride: 4 * w, Rect: r}
+		m = &fd.img
+	}
+
+	if fd.interlace {
+		for p, a := range adam7 {
+			pw, ph := adam7Size(w, h, p)
+			if pw == 0 || ph == 0 {
+				continue
+			}
+			if err := fd.readPass(pw, ph, a.xOff, a.yOff, a.xStep, a.yStep); err != nil {
+				return nil, err
+			}
+		}
+	} else if err := fd.readPass(w, h, 0, 0, 1, 1); err != nil {
+		return nil, err
+	}
+
+	// Read to the end of the data, which checks its checksum, as image/png
+	// does.
+	n := 0
+	for i := 0; n == 0 && err == nil; i++ {
+		if i == 100 {
+			return nil, io.ErrNoProgress
+		}
+		n, err = fd.zr.Read(fd.cur[:1])
+	}
+	if err != nil && err != io.EOF {
+		return nil, FormatError(err.Error())
+	}
+	if n != 0 {
+		return nil, FormatError("too much pixel data")
+	}
+	return m, nil
+}
+
+// readPass reads the width x height pixels of one pass of the frame, which
+// land every xStep and yStep pixels from (xOff, yOff) of the frame buffer.
+// Frames that aren't interlaced are read in a single pass.
+func (fd *frameDecoder) readPass(width, height, xOff, yOff, xStep, yStep int) error {
+	n := 1 + (width*fd.bits+7)/8
+	cur, prev := fd.cur[:n], fd.prev[:n]
+	for i := range prev {
+		prev[i] = 0
+	}
+	for y := 0; y < height; y++ {
+		if _, err := io.ReadFull(fd.zr, cur); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return FormatError("not enough pixel data")
+			}
+			return err
+		}
+		if err := unfilter(cur, prev[1:], fd.bpp); err != nil {
+			return err
+		}
+		fd.setRow(cur[1:], yOff+y*yStep, xOff, xStep, width)
+		cur, prev = prev, cur
+	}
+	return nil
+}
+
+// setRow converts row, width pixels in the format of the image, into row y
+// of the frame buffer, starting at column xOff and every xStep columns.
+func (fd *frameDecoder) setRow(row []byte, y, xOff, xStep, width int) {
+	if fd.depth == 16 {
+		pix := fd.img64.Pix[y*fd.img64.Stride:]
+		for i := 0; i < width; i++ {
+			p := pix[8*(xOff+i*xStep):]
+			p[6], p[7] = 0xff, 0xff
+			switch fd.colorType {
+			case ctGrayscale:
+				s := row[2*i:]
+				p[0], p[1], p[2], p[3], p[4], p[5] = s[0], s[1], s[0], s[1], s[0], s[1]
+				if len(fd.trns) == 2 && s[0] == fd.trns[0] && s[1] == fd.trns[1] {
+					p[6], p[7] = 0, 0
+				}
+			case ctTrueColor:
+				s := row[6*i:]
+				copy(p[:6], s)
+				if len(fd.trns) == 6 && string(s[:6]) == string(fd.trns) {
+					p[6], p[7] = 0, 0
+				}
+			case ctGrayscaleAlpha:
+				s := row[4*i:]
+				p[0], p[1], p[2], p[3], p[4], p[5] = s[0], s[1], s[0], s[1], s[0], s[1]
+				p[6], p[7] = s[2], s[3]
+			case ctTrueColorAlpha:
+				copy(p[:8], row[8*i:])
+			}
+		}
+		return
+	}
+
+	pix := fd.img.Pix[y*fd.img.Stride:]
+	for i := 0; i < width; i++ {
+		p := pix[4*(xOff+i*xStep):]
+		switch fd.colorType {
+		case ctGrayscale:
+			v := fd.sample(row, i)
+			g := uint8(v * 0xff / (1<<fd.depth - 1))
+			p[0], p[1], p[2], p[3] = g, g, g, 0xff
+			// As in image/png, only the low byte of the key is compared.
+			if len(fd.trns) == 2 && uint8(v) == fd.trns[1] {
+				p[3] = 0
+			}
+		case ctTrueColor:
+			s := row[3*i:]
+			p[0], p[1], p[2], p[3] = s[0], s[1], s[2], 0xff
+			if len(fd.trns) == 6 && s[0] == fd.trns[1] && s[1] == fd.trns[3] && s[2] == fd.trns[5] {
+				p[3] = 0
+			}
+		case ctPaletted:
+			c := fd.palette[fd.sample(row, i)]
+			p[0], p[1], p[2], p[3] = c.R, c.G, c.B, c.A
+		case ctGrayscaleAlpha:
+			s := row[2*i:]
+			p[0], p[1], p[2], p[3] = s[0], s[0], s[0], s[1]
+		case ctTrueColorAlpha:
+			copy(p[:4], row[4*i:])
+		}
+	}
+}
+
+// sample returns pixel i of row, a row of single samples of fd.depth bits.
+func (fd *frameDecoder) sample(row []byte, i int) int {
+	if fd.depth == 8 {
+		return int(row[i])
+	}
+	bit := i * fd.depth
+	return int(row[bit/8]>>(8-fd.depth-bit%8)) & (1<<fd.depth - 1)
+}
+
+// idatReader reads the concatenated data of the IDAT or fdAT chunks of a
+// frame, leaving the chunks as they are.
+type idatReader struct {
+	chunks []idat
+	off    int // Offset in chunks[0].
+}
+
+func (r *idatReader) Read(p []byte) (int, error) {
+	if !r.more() {
+		return 0, io.EOF
+	}
+	n := copy(p, r.chunks[0][r.off:])
+	r.off += n
+	return n, nil
+}
+
+// ReadByte makes idatReader a flate.Reader, which the zlib reader uses as
+// it is rather than wrapping it in a new bufio.Reader for every frame.
+func (r *idatReader) ReadByte() (byte, error) {
+	if !r.more() {
+		return 0, io.EOF
+	}
+	b := r.chunks[0][r.off]
+	r.off++
+	return b, nil
+}
+
+// more moves past the chunks that have been read and reports whether
+// there is data left.
+func (r *idatReader) more() bool {
+	for len(r.chunks) > 0 && r.off == len(r.chunks[0]) {
+		r.chunks, r.off = r.chunks[1:], 0
+	}
+	return len(r.chunks) > 0
+}
@@ -0,0 +1,91 @@
+package goapng
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// limitWriter fails once more than n bytes have been written.
+type limitWriter struct {
+	w io.Writer
+	n int
+}
+
+var errLimit = errors.New("write limit reached")
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		n, _ := w.w.Write(p[:w.n])
+		w.n = 0
+		return n, errLimit
+	}
+	w.n -= len(p)
+	return w.w.Write(p)
+}
+
+func TestFrameErrorIndex(t *testing.T) {
+	a := testAPNG(4, 6, 6)
+	a.Durations[2] = 10 * time.Millisecond
+	// The encodes give every frame index from the start of the animation,
+	// whether they hold the whole animation or one frame at a time.
+	encodes := []struct {
+		name   string
+		encode func(enc *Encoder, w io.Writer) error
+	}{
+		{"EncodeAll", func(enc *Encoder, w io.Writer) error { return enc.EncodeAll(w, a) }},
+		{"EncodeSource", func(enc *Encoder, w io.Writer) error {
+			return enc.EncodeSource(w, &lenSource{sliceSource(a), len(a.Images)}, a.LoopCount)
+		}},
+	}
+
+	var full bytes.Buffer
+	if err := EncodeAll(&full, a); err != nil {
+		t.Fatal(err)
+	}
+	// The offset of the fcTL chunk of frame 2.
+	off, fcTLs := len(pngHeader), 0
+	for _, c := range readTestChunks(t, full.Bytes()) {
+		if c.name == "fcTL" {
+			if fcTLs == 2 {
+				break
+			}
+			fcTLs++
+		}
+		off += 12 + len(c.data)
+	}
+
+	var rejects []string
+	for _, e := range encodes {
+		var warnings []error
+		enc := &Encoder{
+			MinDelay:  50 * time.Millisecond,
+			OnWarning: func(err error) { warnings = append(warnings, err) },
+		}
+		if err := e.encode(enc, io.Discard); err != nil {
+			t.Fatalf("%s: %v", e.name, err)
+		}
+		var fe *FrameError
+		if len(warnings) != 1 || !errors.As(warnings[0], &fe) || fe.Index != 2 || !errors.Is(fe, ErrMinDelay) {
+			t.Errorf("%s: got warnings %v, want one about the delay of frame 2", e.name, warnings)
+		}
+
+		enc = &Encoder{MinDelay: 50 * time.Millisecond, MinDelayPolicy: RejectMinDelay}
+		err := e.encode(enc, io.Discard)
+		if !errors.As(err, &fe) || fe.Index != 2 || !errors.Is(err, ErrMinDelay) {
+			t.Errorf("%s: RejectMinDelay: got error %v, want ErrMinDelay for frame 2", e.name, err)
+		} else {
+			rejects = append(rejects, err.Error())
+		}
+
+		err = e.encode(&Encoder{}, &limitWriter{io.Discard, off + 1})
+		if !errors.As(err, &fe) || fe.Index != 2 || !errors.Is(err, errLimit) {
+			t.Errorf("%s: failed write: got error %v, want one for frame 2", e.name, err)
+		}
+	}
+	if len(rejects) == 2 && rejects[0] != rejects[1] {
+		t.Errorf("RejectMinDelay: EncodeAll fails with %q but EncodeSource with %q", rejects[0], rejects[1])
+	}
+}
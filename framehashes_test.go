@@ -0,0 +1,55 @@
+package goapng
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestFrameHashes(t *testing.T) {
+	a := testAPNG(3, 6, 6)
+	a.Images[2] = a.Images[0]
+	hashes, err := FrameHashes(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hashes) != 3 || hashes[0] == hashes[1] || hashes[0] != hashes[2] {
+		t.Fatalf("got hashes %x, want three with the first and last equal", hashes)
+	}
+
+	// Frames that look the same hash the same however they are stored.
+	b := copyAPNG(a)
+	Optimize(b, OptimizePalette)
+	if _, ok := b.Images[0].(*image.Paletted); !ok {
+		t.Fatalf("optimized frames are %T, want *image.Paletted", b.Images[0])
+	}
+	got, err := FrameHashes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range hashes {
+		if got[i] != hashes[i] {
+			t.Errorf("frame %d: hash %x once optimized, want %x", i, got[i], hashes[i])
+		}
+	}
+
+	// The canvas size is hashed with the pixels: transparent canvases of
+	// different shapes hold the same bytes.
+	tall, wide := solid(2, 3, color.NRGBA{}), solid(3, 2, color.NRGBA{})
+	ht, err := FrameHashes(&APNG{Images: []image.Image{tall}, Durations: []time.Duration{0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hw, err := FrameHashes(&APNG{Images: []image.Image{wide}, Durations: []time.Duration{0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ht[0] == hw[0] {
+		t.Error("2x3 and 3x2 transparent frames hash the same")
+	}
+
+	if _, err := FrameHashes(&APNG{}); err == nil {
+		t.Error("no frames: got no error")
+	}
+}
@@ -0,0 +1,153 @@
+package goapng
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+	"time"
+)
+
+var (
+	gifRed   = color.RGBA{0xff, 0, 0, 0xff}
+	gifGreen = color.RGBA{0, 0xff, 0, 0xff}
+	gifBlue  = color.RGBA{0, 0, 0xff, 0xff}
+)
+
+// testGIF returns a 6x6 GIF of three frames sharing a palette with a
+// transparent entry: red over the canvas, a green square at 1,1 with a
+// transparent centre, and a blue pixel at 4,4.
+func testGIF() *gif.GIF {
+	p := color.Palette{color.Transparent, gifRed, gifGreen, gifBlue}
+	fill := func(r image.Rectangle, i uint8) *image.Paletted {
+		m := image.NewPaletted(r, p)
+		for j := range m.Pix {
+			m.Pix[j] = i
+		}
+		return m
+	}
+	square := fill(image.Rect(1, 1, 4, 4), 2)
+	square.SetColorIndex(2, 2, 0)
+	return &gif.GIF{
+		Image:     []*image.Paletted{fill(image.Rect(0, 0, 6, 6), 1), square, fill(image.Rect(4, 4, 5, 5), 3)},
+		Delay:     []int{10, 20, 5},
+		Disposal:  []byte{gif.DisposalNone, gif.DisposalBackground, gif.DisposalNone},
+		LoopCount: 2,
+		Config:    image.Config{ColorModel: p, Width: 6, Height: 6},
+	}
+}
+
+func TestFromGIF(t *testing.T) {
+	a, err := FromGIF(testGIF())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Config.Width != 6 || a.Config.Height != 6 {
+		t.Errorf("canvas %dx%d, want 6x6", a.Config.Width, a.Config.Height)
+	}
+	if a.LoopCount != 3 {
+		t.Errorf("loop count %d, want 3", a.LoopCount)
+	}
+	for i, want := range []uint16{10, 20, 5} {
+		if d := time.Duration(want) * 10 * time.Millisecond; a.delay(i) != d {
+			t.Errorf("frame %d: delay %v, want %v", i, a.delay(i), d)
+		}
+	}
+	if a.Disposals[1] != DisposeOpBackground || a.Blends[1] != BlendOpOver {
+		t.Errorf("frame 1: dispose_op %d, blend_op %d, want background and over", a.Disposals[1], a.Blends[1])
+	}
+	if _, ok := a.Images[0].(*image.Paletted); !ok {
+		t.Errorf("frames are %T, want *image.Paletted", a.Images[0])
+	}
+
+	// The transparent centre of the square shows the red below; the square
+	// is cleared to transparent before the blue pixel is drawn.
+	frames, err := Composite(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checks := []struct {
+		frame, x, y int
+		want        color.RGBA
+	}{
+		{0, 0, 0, gifRed},
+		{1, 1, 1, gifGreen},
+		{1, 2, 2, gifRed},
+		{1, 5, 5, gifRed},
+		{2, 1, 1, color.RGBA{}},
+		{2, 4, 4, gifBlue},
+		{2, 0, 0, gifRed},
+	}
+	for _, c := range checks {
+		if got := frames[c.frame].RGBAAt(c.x, c.y); got != c.want {
+			t.Errorf("frame %d at %d,%d: %v, want %v", c.frame, c.x, c.y, got, c.want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeAll(&buf, a); err != nil {
+		t.Errorf("encoding the result: %v", err)
+	}
+}
+
+func TestFromGIFPadded(t *testing.T) {
+	// A first frame smaller than the canvas, with no transparent entry to
+	// pad it with.
+	p := color.Palette{gifRed, gifGreen}
+	m := image.NewPaletted(image.Rect(0, 0, 2, 2), p)
+	g := &gif.GIF{
+		Image:  []*image.Paletted{m},
+		Delay:  []int{10},
+		Config: image.Config{ColorModel: p, Width: 4, Height: 3},
+	}
+	a, err := FromGIF(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := a.Images[0].Bounds(); b != image.Rect(0, 0, 4, 3) {
+		t.Errorf("first frame %v, want the canvas", b)
+	}
+	if _, ok := a.Images[0].(*image.NRGBA); !ok {
+		t.Errorf("first frame is %T, want *image.NRGBA", a.Images[0])
+	}
+	if _, _, _, alpha := a.Images[0].At(3, 2).RGBA(); alpha != 0 {
+		t.Errorf("padding has alpha %#x, want 0", alpha)
+	}
+}
+
+func TestFromGIFErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(g *gif.GIF)
+	}{
+		{"no frames", func(g *gif.GIF) { g.Image, g.Delay, g.Disposal = nil, nil, nil }},
+		{"short delays", func(g *gif.GIF) { g.Delay = g.Delay[:2] }},
+		{"short disposals", func(g *gif.GIF) { g.Disposal = g.Disposal[:1] }},
+		{"frame outside", func(g *gif.GIF) { g.Config.Width = 3 }},
+	}
+	for _, tt := range tests {
+		g := testGIF()
+		tt.edit(g)
+		if _, err := FromGIF(g); err == nil {
+			t.Errorf("%s: got no error", tt.name)
+		}
+	}
+}
+
+func TestGIFLoopCount(t *testing.T) {
+	tests := []struct {
+		gif  int
+		apng uint32
+	}{
+		{-1, 1},
+		{0, LoopForever},
+		{1, 2},
+		{maxLoopCount, maxLoopCount},
+	}
+	for _, tt := range tests {
+		if got := gifLoopCount(tt.gif); got != tt.apng {
+			t.Errorf("gifLoopCount(%d) = %d, want %d", tt.gif, got, tt.apng)
+		}
+	}
+}
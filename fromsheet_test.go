@@ -0,0 +1,57 @@
+package goapng
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestFromSpriteSheet(t *testing.T) {
+	// A sheet of 3x2 cells at 10,20, with a partial column and row at the
+	// right and bottom.
+	sheet := image.NewNRGBA(image.Rect(10, 20, 17, 25))
+	cell := func(x, y int) color.NRGBA {
+		return color.NRGBA{uint8((x - 10) / 3 * 100), uint8((y - 20) / 2 * 100), uint8(x + y), 0xff}
+	}
+	for y := 20; y < 25; y++ {
+		for x := 10; x < 17; x++ {
+			sheet.SetNRGBA(x, y, cell(x, y))
+		}
+	}
+
+	a, err := FromSpriteSheet(sheet, 3, 2, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Images) != 4 {
+		t.Fatalf("got %d frames, want 4", len(a.Images))
+	}
+	for i, m := range a.Images {
+		if m.Bounds() != image.Rect(0, 0, 3, 2) {
+			t.Errorf("frame %d: bounds %v, want 3x2 at the origin", i, m.Bounds())
+			continue
+		}
+		// Row by row, left to right.
+		x0, y0 := 10+i%2*3, 20+i/2*2
+		for y := 0; y < 2; y++ {
+			for x := 0; x < 3; x++ {
+				if got := color.NRGBAModel.Convert(m.At(x, y)); got != cell(x0+x, y0+y) {
+					t.Errorf("frame %d at %d,%d: %v, want %v", i, x, y, got, cell(x0+x, y0+y))
+				}
+			}
+		}
+		if d := a.delay(i); d != time.Second/30 {
+			t.Errorf("frame %d: delay %v, want 1/30 s", i, d)
+		}
+	}
+	if err := Validate(a); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+
+	for _, size := range [][2]int{{0, 2}, {3, -1}, {8, 2}, {3, 6}} {
+		if _, err := FromSpriteSheet(sheet, size[0], size[1], 30); err == nil {
+			t.Errorf("frame size %dx%d: got no error", size[0], size[1])
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package goapng
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"math"
+	"time"
+)
+
+// Generate writes to w an animation of length d at fps frames per second,
+// each frame of which is drawn by render, and which loops forever. render
+// is called with the time t at which its frame is shown and a width x height
+// canvas to draw into. The canvas is reused from frame to frame, so it still
+// holds the previous frame when render is called; clear it first if need
+// be. Each frame is encoded as soon as it is drawn, so that long animations
+// need not be held in memory.
+func Generate(w io.Writer, width, height int, d time.Duration, fps float64, render func(t time.Duration, dst draw.Image)) error {
+	if width <= 0 || height <= 0 {
+		return errors.New("apng: invalid size")
+	}
+	if d <= 0 || !(fps > 0) {
+		return errors.New("apng: invalid duration or frame rate")
+	}
+
+	// Frames are shown at whole multiples of the frame interval, rounded to
+	// the nanosecond, so that rounding errors don't add up over the
+	// animation; the last frame lasts until d.
+	at := func(i int) time.Duration {
+		return time.Duration(math.Round(float64(i) * float64(time.Second) / fps))
+	}
+	canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+	return EncodeFunc(w, func(i int) (image.Image, time.Duration, bool) {
+		t := at(i)
+		if t >= d && i > 0 {
+			return nil, 0, false
+		}
+		next := at(i + 1)
+		if next > d {
+			next = d
+		}
+		render(t, canvas)
+		return canvas, next - t, true
+	}, LoopForever)
+}
@@ -0,0 +1,62 @@
+package goapng
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+)
+
+// subImage returns the part of m visible through r, sharing pixels with m
+// when possible.
+func subImage(m image.Image, r image.Rectangle) image.Image {
+	if l, ok := m.(*LazyImage); ok {
+		m, _ = l.Decode()
+	}
+	r = r.Intersect(m.Bounds())
+	if s, ok := m.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return s.SubImage(r)
+	}
+	dst := image.NewNRGBA(r)
+	draw.Draw(dst, r, m, r.Min, draw.Src)
+	return dst
+}
+
+// CropCanvas crops the canvas of a to r, cropping every frame and moving
+// its offset to match. Frames that fall entirely outside r are dropped, and
+// their delays are added to the preceding frame so that the timing is kept.
+// The first frame must start at the origin, as Validate requires.
+func CropCanvas(a *APNG, r image.Rectangle) error {
+	if len(a.Images) == 0 {
+		return ErrNoFrames
+	}
+	if a.Images[0] == nil {
+		return &FrameError{0, ErrNilFrame}
+	}
+	if err := checkCanvas(a.Images[0].Bounds()); err != nil {
+		return &FrameError{0, err}
+	}
+	r = r.Intersect(a.bounds())
+	if r.Empty() {
+		return errors.New("apng: crop rectangle outside the canvas")
+	}
+
+	f := frameFolder{a: a}
+	var imgs []image.Image
+	for i, img := range a.Images {
+		fr := img.Bounds().Intersect(r)
+		if fr.Empty() {
+			f.drop(i)
+			continue
+		}
+		f.keep(i)
+		imgs = append(imgs, translate(subImage(img, fr), r.Min.Mul(-1)))
+	}
+
+	b := a.selectFrames(f.kept)
+	b.Images = imgs
+	b.Config.Width, b.Config.Height = r.Dx(), r.Dy()
+	*a = *b
+	return nil
+}
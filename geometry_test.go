@@ -0,0 +1,61 @@
+package goapng
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestCropCanvas(t *testing.T) {
+	a := testAPNG(3, 10, 10)
+	// Frame 1 only changes the bottom right corner, which is cropped away.
+	a.Images[1] = solid(10, 10, color.NRGBA{0, 0, 0xff, 0xff}).SubImage(image.Rect(7, 7, 10, 10))
+	a.Images[2] = solid(10, 10, color.NRGBA{0, 0xff, 0, 0xff}).SubImage(image.Rect(2, 2, 6, 6))
+	if err := CropCanvas(a, image.Rect(1, 1, 5, 5)); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Images) != 2 {
+		t.Fatalf("got %d frames, want 2", len(a.Images))
+	}
+	if want := []time.Duration{200 * time.Millisecond, 100 * time.Millisecond}; a.Durations[0] != want[0] || a.Durations[1] != want[1] {
+		t.Errorf("durations %v, want %v", a.Durations, want)
+	}
+	for i, want := range []image.Rectangle{image.Rect(0, 0, 4, 4), image.Rect(1, 1, 4, 4)} {
+		if b := a.Images[i].Bounds(); b != want {
+			t.Errorf("frame %d: bounds %v, want %v", i, b, want)
+		}
+	}
+	if err := Validate(a); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCropCanvasFirstFrameOrigin(t *testing.T) {
+	// A first frame that misses the crop rectangle used to leave no frame
+	// to fold its delay into.
+	a := testAPNG(2, 10, 10)
+	a.Images[0] = solid(20, 20, color.NRGBA{0xff, 0, 0, 0xff}).SubImage(image.Rect(5, 5, 15, 15))
+	if err := CropCanvas(a, image.Rect(0, 0, 4, 4)); !errors.Is(err, ErrFrameOrigin) {
+		t.Errorf("got %v, want ErrFrameOrigin", err)
+	}
+	if len(a.Images) != 2 {
+		t.Errorf("a changed to %d frames", len(a.Images))
+	}
+}
+
+func TestFrameFolder(t *testing.T) {
+	a := testAPNG(5, 1, 1)
+	a.Durations = []time.Duration{1, 2, 4, 8, 16}
+	f := frameFolder{a: a}
+	f.drop(0)
+	f.drop(1)
+	f.keep(2)
+	f.drop(3)
+	f.keep(4)
+	b := a.selectFrames(f.kept)
+	if want := []time.Duration{7 + 8, 16}; len(b.Durations) != 2 || b.Durations[0] != want[0] || b.Durations[1] != want[1] {
+		t.Errorf("durations %v, want %v", b.Durations, want)
+	}
+}
@@ -0,0 +1,187 @@
+package goapng
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+
+	"github.com/cia-rana/goapng/internal/gifconv"
+)
+
+// FromGIF converts the animation g to an APNG. Frame regions, delays,
+// disposal methods and the loop count carry over; frames with a
+// transparent palette entry are alpha-blended over the canvas, as in a
+// GIF. If the frames don't share a palette, or the first frame doesn't
+// cover the canvas and has no transparent entry to fill the rest with,
+// every frame is converted to *image.NRGBA.
+func FromGIF(g *gif.GIF) (*APNG, error) {
+	n := len(g.Image)
+	if n == 0 {
+		return nil, errors.New("apng: GIF has no frames")
+	}
+	if len(g.Delay) != n {
+		return nil, errors.New("apng: mismatched GIF image and delay lengths")
+	}
+	if g.Disposal != nil && len(g.Disposal) != n {
+		return nil, errors.New("apng: mismatched GIF image and disposal lengths")
+	}
+
+	canvas := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if canvas.Empty() {
+		canvas = image.Rectangle{}
+		for _, p := range g.Image {
+			canvas = canvas.Union(p.Bounds())
+		}
+		canvas.Min = image.Point{}
+	}
+
+	a := &APNG{
+		Images:    make([]image.Image, n),
+		Delays:    make([]uint16, n),
+		Disposals: make([]byte, n),
+		Blends:    make([]byte, n),
+		LoopCount: gifLoopCount(g.LoopCount),
+		Config: image.Config{
+			Width:  canvas.Dx(),
+			Height: canvas.Dy(),
+		},
+	}
+	for i, p := range g.Image {
+		if !p.Bounds().In(canvas) {
+			return nil, errors.New("apng: GIF frame outside the canvas")
+		}
+		a.Images[i] = p
+		d := g.Delay[i]
+		if d < 0 {
+			d = 0
+		} else if d > 0xffff {
+			d = 0xffff
+		}
+		a.Delays[i] = uint16(d)
+		if g.Disposal != nil {
+			switch g.Disposal[i] {
+			case gif.DisposalBackground:
+				a.Disposals[i] = DisposeOpBackground
+			case gif.DisposalPrevious:
+				a.Disposals[i] = DisposeOpPrevious
+			}
+		}
+		if transparentIndex(p.Palette) >= 0 {
+			a.Blends[i] = BlendOpOver
+		}
+	}
+
+	first := g.Image[0]
+	pad := first.Bounds() != canvas
+	if !isSameColorModel(a.Images) || pad && transparentIndex(first.Palette) < 0 {
+		for i, m := range a.Images {
+			a.Images[i] = convertLike(nil, m)
+		}
+	}
+	if pad {
+		a.Images[0] = padTo(a.Images[0], canvas)
+	}
+	a.Config.ColorModel = a.Images[0].ColorModel()
+	return a, nil
+}
+
+// ToGIF converts a to a GIF. Every frame is composited onto the canvas as
+// it is displayed, and the canvas is reduced to a palette of up to 256
+// colors with Floyd-Steinberg dithering. The palette of each frame is
+// chosen by q, or is the web-safe palette if q is nil; one entry is kept
+// for transparency when the canvas has transparent pixels. Delays are
+// rounded to 100ths of a second without drifting from a's timing.
+func ToGIF(a *APNG, q draw.Quantizer) (*gif.GIF, error) {
+	n := len(a.Images)
+	if n == 0 {
+		return nil, ErrNoFrames
+	}
+
+	b := a.bounds()
+	c := newCompositor(b.Dx(), b.Dy())
+	ts := a.timeline()
+	g := &gif.GIF{
+		Image:     make([]*image.Paletted, n),
+		Delay:     make([]int, n),
+		Disposal:  make([]byte, n),
+		LoopCount: gifconv.LoopCount(a.LoopCount),
+		Config: image.Config{
+			Width:  b.Dx(),
+			Height: b.Dy(),
+		},
+	}
+	for i, img := range a.Images {
+		canvas := c.render(img, a.disposal(i), a.blend(i))
+		transparent := !canvas.Opaque()
+
+		p := make(color.Palette, 0, 256)
+		if transparent {
+			p = append(p, color.Transparent)
+		}
+		if q != nil {
+			p = q.Quantize(p, canvas)
+		} else {
+			p = append(p, palette.WebSafe...)
+		}
+		m := image.NewPaletted(canvas.Rect, p)
+		draw.FloydSteinberg.Draw(m, m.Rect, canvas, m.Rect.Min)
+		g.Image[i] = m
+
+		g.Delay[i] = gifconv.Hundredths(ts[i+1]) - gifconv.Hundredths(ts[i])
+		// Each frame is the full canvas, so the frame before a transparent
+		// one must be cleared rather than show through.
+		g.Disposal[i] = gif.DisposalNone
+		if transparent && i > 0 {
+			g.Disposal[i-1] = gif.DisposalBackground
+		}
+	}
+	g.Config.ColorModel = g.Image[0].Palette
+	return g, nil
+}
+
+// gifLoopCount converts image/gif's LoopCount, the number of repeats after
+// the first play or -1 for none, to num_plays.
+func gifLoopCount(n int) uint32 {
+	switch {
+	case n < 0:
+		return 1
+	case n == 0:
+		return LoopForever
+	case n >= maxLoopCount:
+		return maxLoopCount
+	}
+	return uint32(n) + 1
+}
+
+// transparentIndex returns the index of the first fully transparent color
+// of p, or -1.
+func transparentIndex(p color.Palette) int {
+	for i, c := range p {
+		if _, _, _, a := c.RGBA(); a == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// padTo returns m extended to r, with the added area transparent. A
+// *image.Paletted m must have a transparent palette entry.
+func padTo(m image.Image, r image.Rectangle) image.Image {
+	var dst draw.Image
+	switch m := m.(type) {
+	case *image.Paletted:
+		p := image.NewPaletted(r, m.Palette)
+		t := uint8(transparentIndex(m.Palette))
+		for i := range p.Pix {
+			p.Pix[i] = t
+		}
+		dst = p
+	default:
+		dst = image.NewNRGBA(r)
+	}
+	draw.Draw(dst, m.Bounds(), m, m.Bounds().Min, draw.Src)
+	return dst
+}
@@ -0,0 +1,138 @@
+// Package gifcompat reads and writes APNG images through an API shaped
+// like image/gif, so that code written against gif.GIF can switch formats
+// with few edits.
+package gifcompat
+
+import (
+	"image"
+	"image/gif"
+	"io"
+
+	"github.com/cia-rana/goapng"
+	"github.com/cia-rana/goapng/internal/gifconv"
+)
+
+// Disposal methods, with the values of image/gif.
+const (
+	DisposalNone       = gif.DisposalNone
+	DisposalBackground = gif.DisposalBackground
+	DisposalPrevious   = gif.DisposalPrevious
+)
+
+// APNG represents an animation with the fields and semantics of gif.GIF.
+type APNG struct {
+	Image []*image.Paletted // The successive images.
+	Delay []int             // The successive delay times, one per frame, in 100ths of a second.
+	// LoopCount controls the number of times an animation will be
+	// restarted during display, as in image/gif: 0 loops forever, -1 shows
+	// each frame only once and n > 0 shows the animation n+1 times.
+	LoopCount int
+	// Disposal is the successive disposal methods, one per frame. For
+	// backwards compatibility, a nil Disposal is valid to pass to EncodeAll.
+	Disposal []byte
+	// Config is the global color table (palette), width and height. A nil
+	// or empty-color.Palette Config.ColorModel means that each frame has
+	// its own color table. The width and height default to the union of
+	// the frame bounds.
+	Config image.Config
+	// BackgroundIndex is the background index in the global color table.
+	// APNG clears to transparent black, so it is kept only for
+	// compatibility and is not written.
+	BackgroundIndex byte
+}
+
+// EncodeAll writes the images in g to w in APNG format. As in a GIF,
+// palette entries that are fully transparent let the canvas show through.
+func EncodeAll(w io.Writer, g *APNG) error {
+	a, err := goapng.FromGIF(g.gif())
+	if err != nil {
+		return err
+	}
+	return goapng.EncodeAll(w, a)
+}
+
+// DecodeAll reads an APNG image from r and returns the sequential frames
+// and timing information. Animations of paletted frames that a GIF can
+// show as they are map directly; any other animation is composited and
+// reduced to the web-safe palette, as by goapng.ToGIF.
+func DecodeAll(r io.Reader) (*APNG, error) {
+	a, err := goapng.DecodeAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if g := direct(a); g != nil {
+		return g, nil
+	}
+	gg, err := goapng.ToGIF(a, nil)
+	if err != nil {
+		return nil, err
+	}
+	return fromGIF(gg), nil
+}
+
+// gif returns g as a gif.GIF.
+func (g *APNG) gif() *gif.GIF {
+	return &gif.GIF{
+		Image:           g.Image,
+		Delay:           g.Delay,
+		LoopCount:       g.LoopCount,
+		Disposal:        g.Disposal,
+		Config:          g.Config,
+		BackgroundIndex: g.BackgroundIndex,
+	}
+}
+
+func fromGIF(g *gif.GIF) *APNG {
+	return &APNG{
+		Image:           g.Image,
+		Delay:           g.Delay,
+		LoopCount:       g.LoopCount,
+		Disposal:        g.Disposal,
+		Config:          g.Config,
+		BackgroundIndex: g.BackgroundIndex,
+	}
+}
+
+// direct returns a as an APNG of this package without re-rendering it, or
+// nil if a uses features a GIF lacks: frames that aren't paletted, or that
+// replace canvas pixels with transparent ones.
+func direct(a *goapng.APNG) *APNG {
+	ts := append(a.Timestamps(), a.Duration())
+	g := &APNG{
+		Image:     make([]*image.Paletted, len(a.Images)),
+		Delay:     make([]int, len(a.Images)),
+		LoopCount: gifconv.LoopCount(a.LoopCount),
+		Disposal:  make([]byte, len(a.Images)),
+		Config:    a.Config,
+	}
+	for i, img := range a.Images {
+		p, ok := img.(*image.Paletted)
+		if !ok {
+			return nil
+		}
+		if i > 0 && a.Blends[i] == goapng.BlendOpSource && transparent(p) {
+			return nil
+		}
+		g.Image[i] = p
+		g.Delay[i] = gifconv.Hundredths(ts[i+1]) - gifconv.Hundredths(ts[i])
+		switch a.Disposals[i] {
+		case goapng.DisposeOpBackground:
+			g.Disposal[i] = DisposalBackground
+		case goapng.DisposeOpPrevious:
+			g.Disposal[i] = DisposalPrevious
+		default:
+			g.Disposal[i] = DisposalNone
+		}
+	}
+	return g
+}
+
+// transparent reports whether p has a transparent palette entry.
+func transparent(p *image.Paletted) bool {
+	for _, c := range p.Palette {
+		if _, _, _, a := c.RGBA(); a == 0 {
+			return true
+		}
+	}
+	return false
+}
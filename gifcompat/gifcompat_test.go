@@ -0,0 +1,107 @@
+package gifcompat
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	"github.com/cia-rana/goapng"
+)
+
+var (
+	red   = color.RGBA{0xff, 0, 0, 0xff}
+	green = color.RGBA{0, 0xff, 0, 0xff}
+)
+
+func paletted(r image.Rectangle, p color.Palette, i uint8) *image.Paletted {
+	m := image.NewPaletted(r, p)
+	for j := range m.Pix {
+		m.Pix[j] = i
+	}
+	return m
+}
+
+func TestRoundTrip(t *testing.T) {
+	p := color.Palette{red, green}
+	g := &APNG{
+		Image:     []*image.Paletted{paletted(image.Rect(0, 0, 4, 4), p, 0), paletted(image.Rect(1, 1, 3, 3), p, 1)},
+		Delay:     []int{10, 25},
+		LoopCount: 3,
+		Disposal:  []byte{DisposalNone, DisposalBackground},
+		Config:    image.Config{ColorModel: p, Width: 4, Height: 4},
+	}
+	var buf bytes.Buffer
+	if err := EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Image) != 2 || got.Config.Width != 4 || got.Config.Height != 4 {
+		t.Fatalf("got %d frames on a %dx%d canvas, want 2 on 4x4", len(got.Image), got.Config.Width, got.Config.Height)
+	}
+	// Paletted frames come back as they were stored.
+	if got.Image[1].Bounds() != image.Rect(1, 1, 3, 3) || got.Image[1].ColorIndexAt(1, 1) != 1 {
+		t.Errorf("frame 1: bounds %v, want (1,1)-(3,3) of index 1", got.Image[1].Bounds())
+	}
+	if got.Delay[0] != 10 || got.Delay[1] != 25 {
+		t.Errorf("delays %v, want [10 25]", got.Delay)
+	}
+	if got.LoopCount != 3 {
+		t.Errorf("loop count %d, want 3", got.LoopCount)
+	}
+	if got.Disposal[0] != DisposalNone || got.Disposal[1] != DisposalBackground {
+		t.Errorf("disposals %v, want none then background", got.Disposal)
+	}
+
+	// A nil Disposal is valid.
+	g.Disposal = nil
+	buf.Reset()
+	if err := EncodeAll(&buf, g); err != nil {
+		t.Errorf("nil Disposal: %v", err)
+	}
+
+	if err := EncodeAll(&buf, &APNG{}); err == nil {
+		t.Error("no frames: got no error")
+	}
+}
+
+func TestDecodeAllComposited(t *testing.T) {
+	// Frames that aren't paletted are composited to the canvas.
+	a := &goapng.APNG{LoopCount: 1}
+	for _, c := range []color.NRGBA{{0xff, 0, 0, 0xff}, {0, 0, 0xff, 0xff}} {
+		m := image.NewNRGBA(image.Rect(0, 0, 5, 3))
+		for j := 0; j < len(m.Pix); j += 4 {
+			m.Pix[j], m.Pix[j+1], m.Pix[j+2], m.Pix[j+3] = c.R, c.G, c.B, c.A
+		}
+		a.Images = append(a.Images, m)
+		a.Durations = append(a.Durations, 50*time.Millisecond)
+	}
+	var buf bytes.Buffer
+	if err := goapng.EncodeAll(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+	g, err := DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Image) != 2 || g.Config.Width != 5 || g.Config.Height != 3 {
+		t.Fatalf("got %d frames on a %dx%d canvas, want 2 on 5x3", len(g.Image), g.Config.Width, g.Config.Height)
+	}
+	if g.LoopCount != -1 {
+		t.Errorf("loop count %d, want -1 for a single play", g.LoopCount)
+	}
+	if g.Delay[0] != 5 || g.Delay[1] != 5 {
+		t.Errorf("delays %v, want [5 5]", g.Delay)
+	}
+	if got := color.RGBAModel.Convert(g.Image[1].At(2, 1)); got != (color.RGBA{0, 0, 0xff, 0xff}) {
+		t.Errorf("frame 1: color %v, want blue", got)
+	}
+
+	if _, err := DecodeAll(bytes.NewReader(buf.Bytes()[:30])); err == nil {
+		t.Error("truncated file: got no error")
+	}
+}
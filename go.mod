@@ -1 +1,3 @@
 module github.com/cia-rana/goapng
+
+go 1.20
@@ -0,0 +1,220 @@
+package goapng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+// testAPNG returns an animation of n w x h frames, each a different solid
+// color, shown for 100ms each.
+func testAPNG(n, w, h int) *APNG {
+	a := &APNG{}
+	for i := 0; i < n; i++ {
+		a.Images = append(a.Images, solid(w, h, color.NRGBA{uint8(40 * i), uint8(255 - 40*i), uint8(i), 0xff}))
+		a.Durations = append(a.Durations, 100*time.Millisecond)
+	}
+	return a
+}
+
+// solid returns a w x h image of color c.
+func solid(w, h int, c color.NRGBA) *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < len(m.Pix); i += 4 {
+		m.Pix[i], m.Pix[i+1], m.Pix[i+2], m.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	return m
+}
+
+// samePixels reports whether m1 and m2 have the same bounds and colors.
+func samePixels(m1, m2 image.Image) bool {
+	b := m1.Bounds()
+	if b != m2.Bounds() {
+		return false
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if color.NRGBA64Model.Convert(m1.At(x, y)) != color.NRGBA64Model.Convert(m2.At(x, y)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// testChunk is a chunk of a PNG stream, as split by readTestChunks.
+type testChunk struct {
+	name string
+	data []byte
+}
+
+func readTestChunks(t *testing.T, b []byte) []testChunk {
+	t.Helper()
+	cr := newChunkReader(bytes.NewReader(b))
+	if err := cr.readSignature(); err != nil {
+		t.Fatal(err)
+	}
+	var chunks []testChunk
+	for {
+		name, data, err := cr.next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, testChunk{name, data})
+		if name == "IEND" {
+			return chunks
+		}
+	}
+}
+
+// rewriteChunks returns b with each chunk passed through fn, which may
+// change it or report false to drop it. The checksums are recomputed.
+func rewriteChunks(t *testing.T, b []byte, fn func(c *testChunk) bool) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	cw := NewChunkWriter(&out)
+	for _, c := range readTestChunks(t, b) {
+		if fn(&c) {
+			cw.WriteChunk(c.name, c.data)
+		}
+	}
+	return out.Bytes()
+}
+
+// separateDefault returns the APNG file of a with def as its default image,
+// kept apart from the animation, so that frame 0 is stored in fdAT chunks.
+// def must encode with the same IHDR as a.
+func separateDefault(t *testing.T, a *APNG, def image.Image) []byte {
+	t.Helper()
+	var anim, static bytes.Buffer
+	if err := EncodeAll(&anim, a); err != nil {
+		t.Fatal(err)
+	}
+	if err := EncodeAll(&static, &APNG{Images: []image.Image{def}, Delays: []uint16{0}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	cw := NewChunkWriter(&out)
+	seq := uint32(0)
+	for _, c := range readTestChunks(t, anim.Bytes()) {
+		switch c.name {
+		case "fcTL":
+			if seq == 0 {
+				for _, s := range readTestChunks(t, static.Bytes()) {
+					if s.name == "IDAT" {
+						cw.WriteChunk("IDAT", s.data)
+					}
+				}
+			}
+			writeUint32(c.data[:4], seq)
+			seq++
+			cw.WriteChunk(c.name, c.data)
+		case "IDAT":
+			data := make([]byte, 4+len(c.data))
+			writeUint32(data[:4], seq)
+			seq++
+			copy(data[4:], c.data)
+			cw.WriteChunk("fdAT", data)
+		case "fdAT":
+			writeUint32(c.data[:4], seq)
+			seq++
+			cw.WriteChunk(c.name, c.data)
+		default:
+			cw.WriteChunk(c.name, c.data)
+		}
+	}
+	return out.Bytes()
+}
+
+// sliceSource serves the frames of a, without a Len method.
+func sliceSource(a *APNG) FrameSource {
+	return FuncSource(func(i int) (image.Image, time.Duration, bool) {
+		if i == len(a.Images) {
+			return nil, 0, false
+		}
+		return a.Images[i], a.Durations[i], true
+	})
+}
+
+// bumpSequence adds 10 to the sequence numbers of b.
+func bumpSequence(t *testing.T, b []byte) []byte {
+	return rewriteChunks(t, b, func(c *testChunk) bool {
+		if c.name == "fcTL" || c.name == "fdAT" {
+			writeUint32(c.data[:4], binary.BigEndian.Uint32(c.data[:4])+10)
+		}
+		return true
+	})
+}
+
+// setNumFrames sets num_frames in the acTL chunk of b to n.
+func setNumFrames(t *testing.T, b []byte, n uint32) []byte {
+	return rewriteChunks(t, b, func(c *testChunk) bool {
+		if c.name == "acTL" {
+			writeUint32(c.data[:4], n)
+		}
+		return true
+	})
+}
+
+// ycbcrAPNG returns an animation like testAPNG, with YCbCr frames.
+func ycbcrAPNG(n, w, h int) *APNG {
+	a := testAPNG(n, w, h)
+	for i := range a.Images {
+		m := image.NewYCbCr(image.Rect(0, 0, w, h), image.YCbCrSubsampleRatio444)
+		for j := range m.Y {
+			m.Y[j], m.Cb[j], m.Cr[j] = uint8(60*i+j), 90, uint8(200-j)
+		}
+		a.Images[i] = m
+	}
+	return a
+}
+
+// copyAPNG returns a copy of a whose per-frame slices can be changed
+// without changing a.
+func copyAPNG(a *APNG) *APNG {
+	b := *a
+	b.Images = append([]image.Image(nil), a.Images...)
+	b.Delays = append([]uint16(nil), a.Delays...)
+	b.DelayDens = append([]uint16(nil), a.DelayDens...)
+	b.Durations = append([]time.Duration(nil), a.Durations...)
+	b.Disposals = append([]byte(nil), a.Disposals...)
+	b.Blends = append([]byte(nil), a.Blends...)
+	return &b
+}
+
+// checkShows checks that b encodes, and that once decoded its frames
+// display frames order of a.
+func checkShows(t *testing.T, b, a *APNG, order []int) {
+	t.Helper()
+	if !isSameColorModel(b.Images) {
+		t.Fatal("frames have different color models")
+	}
+	want, err := Composite(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := EncodeAll(&buf, b); err != nil {
+		t.Fatal(err)
+	}
+	d, err := DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Composite(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(order) {
+		t.Fatalf("got %d frames, want %d", len(got), len(order))
+	}
+	for i, k := range order {
+		if !samePixels(got[i], want[k]) {
+			t.Errorf("frame %d does not show frame %d", i, k)
+		}
+	}
+}
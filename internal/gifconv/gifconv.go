@@ -0,0 +1,22 @@
+// Package gifconv holds the conversions to the timing of image/gif that
+// goapng and gifcompat share.
+package gifconv
+
+import "time"
+
+// LoopCount converts num_plays, where 0 loops forever, to image/gif's
+// LoopCount, the number of repeats after the first play or -1 for none.
+func LoopCount(numPlays uint32) int {
+	switch numPlays {
+	case 0:
+		return 0
+	case 1:
+		return -1
+	}
+	return int(numPlays) - 1
+}
+
+// Hundredths returns d in 100ths of a second, rounded.
+func Hundredths(d time.Duration) int {
+	return int((d + 5*time.Millisecond) / (10 * time.Millisecond))
+}
@@ -0,0 +1,49 @@
+package goapng
+
+import (
+	"image"
+	"image/color"
+	"sync"
+)
+
+// A LazyImage is a frame returned by a lazy Decoder. Its compressed data is
+// decompressed on the first call to ColorModel, At or Decode; Bounds never
+// triggers decompression. A LazyImage is safe for concurrent use.
+type LazyImage struct {
+	d    *decoder
+	f    *rawFrame
+	rect image.Rectangle
+
+	once sync.Once
+	img  image.Image
+	err  error
+}
+
+// Decode decompresses the frame, if not done already, and returns it.
+func (m *LazyImage) Decode() (image.Image, error) {
+	m.once.Do(func() {
+		m.img, m.err = m.d.decodeFrame(m.f)
+		if m.err != nil {
+			m.img = image.NewNRGBA(m.rect)
+		}
+		// The compressed data is no longer needed.
+		m.d, m.f = nil, nil
+	})
+	return m.img, m.err
+}
+
+func (m *LazyImage) ColorModel() color.Model {
+	img, _ := m.Decode()
+	return img.ColorModel()
+}
+
+func (m *LazyImage) Bounds() image.Rectangle {
+	return m.rect
+}
+
+// At returns the color of the pixel at (x, y). If the frame fails to
+// decode, every pixel is transparent.
+func (m *LazyImage) At(x, y int) color.Color {
+	img, _ := m.Decode()
+	return img.At(x, y)
+}
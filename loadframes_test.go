@@ -0,0 +1,86 @@
+package goapng
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadFrames(t *testing.T) {
+	// The number in each name is the gray level of the frame.
+	names := []string{"f10.png", "f2.png", "f1.png", "f02.png", "f0010a.png", "f9.png"}
+	fsys := fstest.MapFS{}
+	for _, name := range names {
+		n := 0
+		for _, c := range name {
+			if '0' <= c && c <= '9' {
+				n = 10*n + int(c-'0')
+			}
+		}
+		m := image.NewGray(image.Rect(0, 0, 2, 2))
+		for i := range m.Pix {
+			m.Pix[i] = uint8(n)
+		}
+		fsys[name] = pngFile(t, m)
+	}
+
+	imgs, err := LoadFrames(fsys, "f*.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Numbers sort by value, then the one with fewer leading zeros first.
+	want := []uint8{1, 2, 2, 9, 10, 10}
+	if len(imgs) != len(want) {
+		t.Fatalf("got %d frames, want %d", len(imgs), len(want))
+	}
+	for i, m := range imgs {
+		g, ok := m.(*image.Gray)
+		if !ok {
+			t.Fatalf("frame %d is %T, want *image.Gray when the models agree", i, m)
+		}
+		if g.Pix[0] != want[i] {
+			t.Errorf("frame %d: gray %d, want %d", i, g.Pix[0], want[i])
+		}
+	}
+
+	// Frames of different models are converted to NRGBA.
+	fsys["f99.png"] = pngFile(t, solid(2, 2, color.NRGBA{1, 2, 3, 4}))
+	imgs, err = LoadFrames(fsys, "f*.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range imgs {
+		if _, ok := m.(*image.NRGBA); !ok {
+			t.Errorf("mixed models: frame %d is %T, want *image.NRGBA", i, m)
+		}
+	}
+
+	if _, err := LoadFrames(fsys, "g*.png"); err == nil {
+		t.Error("no matching files: got no error")
+	}
+	if _, err := LoadFrames(fsys, "[.png"); err == nil {
+		t.Error("bad pattern: got no error")
+	}
+}
+
+func TestNaturalLess(t *testing.T) {
+	tests := []struct {
+		s, t string
+		want bool
+	}{
+		{"frame2.png", "frame10.png", true},
+		{"frame10.png", "frame2.png", false},
+		{"a", "b", true},
+		{"a", "a1", true},
+		{"x007", "x7", false},
+		{"x7", "x007", true},
+		{"x123456789012345678901234567890", "x123456789012345678901234567891", true},
+		{"same", "same", false},
+	}
+	for _, tt := range tests {
+		if got := naturalLess(tt.s, tt.t); got != tt.want {
+			t.Errorf("naturalLess(%q, %q) = %v, want %v", tt.s, tt.t, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package goapng
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"io/fs"
+	"math"
+	"strconv"
+	"time"
+)
+
+// A Manifest describes an animation as a list of image files with their
+// timing, in a JSON form that can be written by hand:
+//
+//	{"loop": 0, "frames": [{"file": "a.png", "delay_ms": 80}, ...]}
+type Manifest struct {
+	Loop   uint32          `json:"loop"` // The number of plays; 0 plays forever.
+	Frames []ManifestFrame `json:"frames"`
+}
+
+// ManifestFrame is one frame of a Manifest. Only File is required.
+type ManifestFrame struct {
+	File    string  `json:"file"`
+	DelayMS float64 `json:"delay_ms"`
+
+	// DelayNum and DelayDen, if DelayDen is set, give the delay exactly,
+	// as a fraction of a second, in place of DelayMS.
+	DelayNum uint16 `json:"delay_num,omitempty"`
+	DelayDen uint16 `json:"delay_den,omitempty"`
+
+	// X and Y are the offset of the frame on the canvas.
+	X int `json:"x,omitempty"`
+	Y int `json:"y,omitempty"`
+
+	// Dispose is "none" (the default), "background" or "previous", and
+	// Blend is "source" (the default) or "over".
+	Dispose string `json:"dispose,omitempty"`
+	Blend   string `json:"blend,omitempty"`
+}
+
+var (
+	disposeOpNames = []string{"none", "background", "previous"}
+	blendOpNames   = []string{"source", "over"}
+)
+
+// EncodeFromManifest reads the JSON manifest name from fsys and writes the
+// animation it describes to w. The frame files are PNG images, named
+// relative to the root of fsys; use os.DirFS to read them from a directory.
+func EncodeFromManifest(w io.Writer, fsys fs.FS, name string) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var m Manifest
+	if err := json.NewDecoder(f).Decode(&m); err != nil {
+		return fmt.Errorf("apng: %s: %w", name, err)
+	}
+	a, err := m.Load(fsys)
+	if err != nil {
+		return err
+	}
+	return EncodeAll(w, a)
+}
+
+// Load decodes the frame files of m from fsys and returns the animation.
+// If the frames don't share a color model, every frame is converted to
+// *image.NRGBA.
+func (m *Manifest) Load(fsys fs.FS) (*APNG, error) {
+	n := len(m.Frames)
+	if n == 0 {
+		return nil, ErrNoFrames
+	}
+	a := &APNG{
+		Images:    make([]image.Image, n),
+		Delays:    make([]uint16, n),
+		DelayDens: make([]uint16, n),
+		Disposals: make([]byte, n),
+		Blends:    make([]byte, n),
+		LoopCount: m.Loop,
+	}
+	for i, mf := range m.Frames {
+		img, err := decodeFile(fsys, mf.File)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 && (mf.X != 0 || mf.Y != 0) {
+			return nil, &FrameError{0, fmt.Errorf("%w: the first frame sets the canvas and must be at 0,0", ErrFrameRegion)}
+		}
+		a.Images[i] = translate(img, image.Pt(mf.X, mf.Y).Sub(img.Bounds().Min))
+
+		if mf.DelayDen != 0 {
+			a.Delays[i], a.DelayDens[i] = mf.DelayNum, mf.DelayDen
+		} else {
+			if mf.DelayMS < 0 || math.IsNaN(mf.DelayMS) {
+				return nil, &FrameError{i, fmt.Errorf("apng: invalid delay_ms %v", mf.DelayMS)}
+			}
+			d := time.Duration(math.Round(math.Min(mf.DelayMS, 1e9) * float64(time.Millisecond)))
+			a.Delays[i], a.DelayDens[i] = DelayFraction(d)
+		}
+
+		if a.Disposals[i], err = parseOp(disposeOpNames, mf.Dispose); err != nil {
+			return nil, &FrameError{i, fmt.Errorf("apng: unknown dispose %q", mf.Dispose)}
+		}
+		if a.Blends[i], err = parseOp(blendOpNames, mf.Blend); err != nil {
+			return nil, &FrameError{i, fmt.Errorf("apng: unknown blend %q", mf.Blend)}
+		}
+	}
+	if !isSameColorModel(a.Images) {
+		for i, img := range a.Images {
+			a.Images[i] = convertLike(nil, img)
+		}
+	}
+	b := a.Images[0].Bounds()
+	a.Config = image.Config{
+		ColorModel: a.Images[0].ColorModel(),
+		Width:      b.Dx(),
+		Height:     b.Dy(),
+	}
+	return a, nil
+}
+
+// NewManifest returns a Manifest describing the frames, timing and loop
+// count of a, with frame i stored in the file name(i). If name is nil, the
+// files are named frame_000.png, frame_001.png and so on, as written by
+// ExtractFrames. Delays are given both in milliseconds and exactly, so that
+// loading the manifest restores them as they were.
+func NewManifest(a *APNG, name func(i int) string) *Manifest {
+	if name == nil {
+		name = frameFileName
+	}
+	m := &Manifest{
+		Loop:   a.LoopCount,
+		Frames: make([]ManifestFrame, len(a.Images)),
+	}
+	ts := a.timeline()
+	for i, img := range a.Images {
+		b := img.Bounds()
+		num, den := a.delayFraction(i)
+		if den == 0 {
+			den = 100
+		}
+		m.Frames[i] = ManifestFrame{
+			File:     name(i),
+			DelayMS:  float64(ts[i+1]-ts[i]) / float64(time.Millisecond),
+			DelayNum: num,
+			DelayDen: den,
+			X:        b.Min.X,
+			Y:        b.Min.Y,
+			Dispose:  opName(disposeOpNames, a.disposal(i)),
+			Blend:    opName(blendOpNames, a.blend(i)),
+		}
+	}
+	return m
+}
+
+// WriteManifest writes m to w as indented JSON.
+func WriteManifest(w io.Writer, m *Manifest) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// DisposeOpName returns the name of the dispose operation op as written in
+// a Manifest, such as "background", or its number if it is unknown.
+func DisposeOpName(op byte) string {
+	return opName(disposeOpNames, op)
+}
+
+// BlendOpName returns the name of the blend operation op as written in a
+// Manifest, such as "over", or its number if it is unknown.
+func BlendOpName(op byte) string {
+	return opName(blendOpNames, op)
+}
+
+// opName returns the name of op, or its number if it is unknown.
+func opName(names []string, op byte) string {
+	if int(op) < len(names) {
+		return names[op]
+	}
+	return strconv.Itoa(int(op))
+}
+
+// parseOp returns the operation named name, the index of name in names or
+// a number. An empty name is the first.
+func parseOp(names []string, name string) (byte, error) {
+	if name == "" {
+		return 0, nil
+	}
+	for i, n := range names {
+		if n == name {
+			return byte(i), nil
+		}
+	}
+	if op, err := strconv.ParseUint(name, 10, 8); err == nil {
+		return byte(op), nil
+	}
+	return 0, fmt.Errorf("unknown operation %q", name)
+}
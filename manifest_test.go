@@ -0,0 +1,109 @@
+package goapng
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// pngFile returns m encoded as a PNG file.
+func pngFile(t *testing.T, m image.Image) *fstest.MapFile {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	return &fstest.MapFile{Data: buf.Bytes()}
+}
+
+func TestEncodeFromManifest(t *testing.T) {
+	a := testAPNG(2, 4, 4)
+	gray := image.NewGray(image.Rect(0, 0, 2, 2))
+	for i := range gray.Pix {
+		gray.Pix[i] = 0x80
+	}
+	fsys := fstest.MapFS{
+		"frames/a.png": pngFile(t, a.Images[0]),
+		"frames/b.png": pngFile(t, a.Images[1]),
+		"frames/c.png": pngFile(t, gray),
+		"anim.json": {Data: []byte(`{"loop": 3, "frames": [
+			{"file": "frames/a.png", "delay_ms": 80},
+			{"file": "frames/c.png", "delay_num": 1, "delay_den": 30, "x": 1, "y": 2, "dispose": "previous", "blend": "over"},
+			{"file": "frames/b.png", "dispose": "background"}
+		]}`)},
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeFromManifest(&buf, fsys, "anim.json"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.LoopCount != 3 || len(got.Images) != 3 {
+		t.Fatalf("got %d frames looping %d times, want 3 looping 3 times", len(got.Images), got.LoopCount)
+	}
+	tests := []struct {
+		bounds   image.Rectangle
+		delay    time.Duration
+		dispose  byte
+		blend    byte
+		wantGray uint8 // The gray of the frame at its top left, or 0 to skip.
+	}{
+		{image.Rect(0, 0, 4, 4), 80 * time.Millisecond, DisposeOpNone, BlendOpSource, 0},
+		{image.Rect(1, 2, 3, 4), time.Second / 30, DisposeOpPrevious, BlendOpOver, 0x80},
+		{image.Rect(0, 0, 4, 4), 0, DisposeOpBackground, BlendOpSource, 0},
+	}
+	for i, tt := range tests {
+		m := got.Images[i]
+		if m.Bounds() != tt.bounds || got.delay(i) != tt.delay || got.Disposals[i] != tt.dispose || got.Blends[i] != tt.blend {
+			t.Errorf("frame %d: %v, %v, dispose_op %d, blend_op %d; want %v, %v, %d, %d",
+				i, m.Bounds(), got.delay(i), got.Disposals[i], got.Blends[i], tt.bounds, tt.delay, tt.dispose, tt.blend)
+		}
+		if tt.wantGray != 0 {
+			if c := color.GrayModel.Convert(m.At(m.Bounds().Min.X, m.Bounds().Min.Y)).(color.Gray); c.Y != tt.wantGray {
+				t.Errorf("frame %d: gray %#x, want %#x", i, c.Y, tt.wantGray)
+			}
+		}
+	}
+	if !samePixels(got.Images[2], a.Images[1]) {
+		t.Error("frame 2 differs from b.png")
+	}
+}
+
+func TestManifestErrors(t *testing.T) {
+	fsys := fstest.MapFS{"a.png": pngFile(t, testAPNG(1, 4, 4).Images[0])}
+	tests := []struct {
+		name     string
+		manifest string
+		wantErr  error  // A sentinel the error wraps, or nil.
+		wantMsg  string // Part of the message.
+	}{
+		{"no frames", `{"frames": []}`, ErrNoFrames, ""},
+		{"bad JSON", `{"frames": [`, nil, "anim.json"},
+		{"missing file", `{"frames": [{"file": "b.png"}]}`, nil, "b.png"},
+		{"first frame offset", `{"frames": [{"file": "a.png", "x": 1}]}`, ErrFrameRegion, ""},
+		{"negative delay", `{"frames": [{"file": "a.png", "delay_ms": -5}]}`, nil, "delay_ms"},
+		{"unknown dispose", `{"frames": [{"file": "a.png", "dispose": "away"}]}`, nil, "away"},
+		{"unknown blend", `{"frames": [{"file": "a.png", "blend": "mix"}]}`, nil, "mix"},
+	}
+	for _, tt := range tests {
+		fsys["anim.json"] = &fstest.MapFile{Data: []byte(tt.manifest)}
+		err := EncodeFromManifest(&bytes.Buffer{}, fsys, "anim.json")
+		switch {
+		case err == nil:
+			t.Errorf("%s: got no error", tt.name)
+		case tt.wantErr != nil && !errors.Is(err, tt.wantErr):
+			t.Errorf("%s: got error %v, want %v", tt.name, err, tt.wantErr)
+		case !strings.Contains(err.Error(), tt.wantMsg):
+			t.Errorf("%s: got error %v, want one mentioning %q", tt.name, err, tt.wantMsg)
+		}
+	}
+}
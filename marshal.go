@@ -0,0 +1,36 @@
+package goapng
+
+import (
+	"bytes"
+	"io"
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler, returning a encoded
+// as an APNG file.
+func (a *APNG) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := EncodeAll(&buf, a); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, replacing a with
+// the animation decoded from the APNG file data.
+func (a *APNG) UnmarshalBinary(data []byte) error {
+	b, err := DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	*a = *b
+	return nil
+}
+
+// WriteTo implements io.WriterTo, writing a to w in APNG format as
+// EncodeAll does. It returns the number of bytes written, which on error
+// counts the bytes written before it.
+func (a *APNG) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	err := EncodeAll(cw, a)
+	return cw.n, err
+}
@@ -0,0 +1,90 @@
+package goapng
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"testing"
+	"testing/fstest"
+)
+
+func TestNewManifest(t *testing.T) {
+	a := testAPNG(3, 6, 6)
+	a.Durations = nil
+	a.Delays = []uint16{1, 7, 1}
+	a.DelayDens = []uint16{30, 100, 3}
+	a.Images[1] = subImage(a.Images[1], image.Rect(1, 2, 4, 5))
+	a.Disposals = []byte{DisposeOpNone, DisposeOpPrevious, DisposeOpBackground}
+	a.Blends = []byte{BlendOpSource, BlendOpOver, BlendOpSource}
+	a.LoopCount = 4
+
+	m := NewManifest(a, nil)
+	if m.Loop != 4 || len(m.Frames) != 3 {
+		t.Fatalf("got %d frames looping %d times, want 3 looping 4 times", len(m.Frames), m.Loop)
+	}
+	want := ManifestFrame{File: "frame_001.png", DelayMS: 70, DelayNum: 7, DelayDen: 100, X: 1, Y: 2, Dispose: "previous", Blend: "over"}
+	if m.Frames[1] != want {
+		t.Errorf("frame 1: %+v, want %+v", m.Frames[1], want)
+	}
+	if f := m.Frames[0]; f.File != "frame_000.png" || f.DelayNum != 1 || f.DelayDen != 30 || f.DelayMS < 33.3 || f.DelayMS > 33.4 {
+		t.Errorf("frame 0: %+v, want a delay of 1/30 s", f)
+	}
+	if m := NewManifest(a, func(i int) string { return fmt.Sprintf("f%d.png", i) }); m.Frames[2].File != "f2.png" {
+		t.Errorf("named frames: frame 2 in %q, want f2.png", m.Frames[2].File)
+	}
+
+	// Writing the manifest and the frames, then loading them, restores the
+	// animation.
+	var js bytes.Buffer
+	if err := WriteManifest(&js, m); err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{"anim.json": {Data: js.Bytes()}}
+	for i, img := range a.Images {
+		fsys[m.Frames[i].File] = pngFile(t, img)
+	}
+	var buf bytes.Buffer
+	if err := EncodeFromManifest(&buf, fsys, "anim.json"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, diffs := EqualFrames(a, b, 0); !ok {
+		t.Errorf("frames differ: %v", diffs)
+	}
+	for i := range a.Images {
+		if b.Delays[i] != a.Delays[i] || b.DelayDens[i] != a.DelayDens[i] {
+			t.Errorf("frame %d: delay %d/%d, want %d/%d", i, b.Delays[i], b.DelayDens[i], a.Delays[i], a.DelayDens[i])
+		}
+	}
+	if b.LoopCount != 4 {
+		t.Errorf("loop count %d, want 4", b.LoopCount)
+	}
+}
+
+func TestOpNames(t *testing.T) {
+	tests := []struct {
+		got, want string
+	}{
+		{DisposeOpName(DisposeOpNone), "none"},
+		{DisposeOpName(DisposeOpBackground), "background"},
+		{DisposeOpName(DisposeOpPrevious), "previous"},
+		{DisposeOpName(7), "7"},
+		{BlendOpName(BlendOpSource), "source"},
+		{BlendOpName(BlendOpOver), "over"},
+		{BlendOpName(2), "2"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got %q, want %q", tt.got, tt.want)
+		}
+	}
+	// Names and numbers both parse back.
+	for _, name := range []string{"previous", "2"} {
+		if op, err := parseOp(disposeOpNames, name); err != nil || op != DisposeOpPrevious {
+			t.Errorf("parseOp(%q) = %d, %v; want %d", name, op, err, DisposeOpPrevious)
+		}
+	}
+}
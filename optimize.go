@@ -0,0 +1,141 @@
+package goapng
+
+import (
+	"image"
+	"image/color"
+)
+
+// Optimization levels for Optimize.
+const (
+	// OptimizeDelta stores only the region of each frame that differs from
+	// the frame displayed before it, and drops frames that change nothing.
+	OptimizeDelta = 1
+	// OptimizePalette also converts the frames to a shared palette when
+	// the animation displays no more than 256 colors.
+	OptimizePalette = 2
+)
+
+// Optimize rewrites the frames of a so that it encodes to fewer bytes while
+// displaying the same images. Every level includes the ones below it. The
+// delays of dropped frames are added to the frame before them. Animations
+// with 16-bit frames are left as they are, as the compositing is done with
+// 8 bits per channel.
+func Optimize(a *APNG, level int) {
+	if level < OptimizeDelta || len(a.Images) == 0 {
+		return
+	}
+	ref := a.Images[0]
+	if l, ok := ref.(*LazyImage); ok {
+		ref, _ = l.Decode()
+	}
+	switch ref.ColorModel() {
+	case color.RGBA64Model, color.NRGBA64Model, color.Gray16Model:
+		return
+	}
+
+	b := a.bounds()
+	c := newCompositor(b.Dx(), b.Dy())
+	var (
+		prev *image.RGBA
+		f    = frameFolder{a: a}
+		imgs []*image.RGBA
+	)
+	for i, img := range a.Images {
+		canvas := c.render(img, a.disposal(i), a.blend(i))
+		r := canvas.Rect
+		if prev != nil {
+			r = diffRect(prev, canvas)
+			if r.Empty() {
+				f.drop(i)
+				continue
+			}
+		}
+		prev = cloneRGBA(canvas)
+		f.keep(i)
+		imgs = append(imgs, prev.SubImage(r).(*image.RGBA))
+	}
+
+	out := make([]image.Image, len(imgs))
+	var p map[color.RGBA]uint8
+	if level >= OptimizePalette {
+		p = sharedPalette(imgs)
+	}
+	if p != nil {
+		pal := make(color.Palette, len(p))
+		for c, i := range p {
+			pal[i] = c
+		}
+		for i, m := range imgs {
+			out[i] = toPaletted(m, pal, p)
+		}
+	} else {
+		for i, m := range imgs {
+			out[i] = convertLike(ref, m)
+		}
+	}
+
+	*a = *a.selectFrames(f.kept)
+	a.setFlat(out)
+}
+
+// diffRect returns the smallest rectangle holding every pixel that differs
+// between m1 and m2, which have the same bounds.
+func diffRect(m1, m2 *image.RGBA) image.Rectangle {
+	r := image.Rectangle{}
+	b := m1.Rect
+	w := b.Dx() * 4
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		o1, o2 := m1.PixOffset(b.Min.X, y), m2.PixOffset(b.Min.X, y)
+		row1, row2 := m1.Pix[o1:o1+w], m2.Pix[o2:o2+w]
+		x0 := 0
+		for x0 < w && row1[x0] == row2[x0] {
+			x0++
+		}
+		if x0 == w {
+			continue
+		}
+		x1 := w - 1
+		for row1[x1] == row2[x1] {
+			x1--
+		}
+		r = r.Union(image.Rect(b.Min.X+x0/4, y, b.Min.X+x1/4+1, y+1))
+	}
+	return r
+}
+
+// sharedPalette returns the index of every color in imgs, or nil if there
+// are more than 256 colors.
+func sharedPalette(imgs []*image.RGBA) map[color.RGBA]uint8 {
+	p := make(map[color.RGBA]uint8)
+	for _, m := range imgs {
+		b := m.Rect
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			row := m.Pix[m.PixOffset(b.Min.X, y):m.PixOffset(b.Max.X, y)]
+			for x := 0; x < len(row); x += 4 {
+				c := color.RGBA{row[x], row[x+1], row[x+2], row[x+3]}
+				if _, ok := p[c]; ok {
+					continue
+				}
+				if len(p) == 256 {
+					return nil
+				}
+				p[c] = uint8(len(p))
+			}
+		}
+	}
+	return p
+}
+
+// toPaletted returns m with its colors replaced by their index in p.
+func toPaletted(m *image.RGBA, pal color.Palette, p map[color.RGBA]uint8) *image.Paletted {
+	b := m.Rect
+	dst := image.NewPaletted(b, pal)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := m.Pix[m.PixOffset(b.Min.X, y):m.PixOffset(b.Max.X, y)]
+		out := dst.Pix[dst.PixOffset(b.Min.X, y):]
+		for x := 0; x < len(row); x += 4 {
+			out[x/4] = p[color.RGBA{row[x], row[x+1], row[x+2], row[x+3]}]
+		}
+	}
+	return dst
+}
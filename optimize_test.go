@@ -0,0 +1,80 @@
+package goapng
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestOptimize(t *testing.T) {
+	a := testAPNG(4, 6, 6)
+	// Frame 1 changes one pixel of frame 0 and frame 2 nothing at all.
+	m := solid(6, 6, a.Images[0].(*image.NRGBA).NRGBAAt(0, 0))
+	m.SetNRGBA(1, 2, color.NRGBA{1, 2, 3, 0xff})
+	a.Images[1], a.Images[2] = m, m
+	a.Durations[2] = 50 * time.Millisecond
+
+	tests := []struct {
+		level     int
+		paletted  bool
+		wantRects []image.Rectangle
+	}{
+		{OptimizeDelta, false, []image.Rectangle{image.Rect(0, 0, 6, 6), image.Rect(1, 2, 2, 3), image.Rect(0, 0, 6, 6)}},
+		{OptimizePalette, true, []image.Rectangle{image.Rect(0, 0, 6, 6), image.Rect(1, 2, 2, 3), image.Rect(0, 0, 6, 6)}},
+	}
+	for _, tt := range tests {
+		b := copyAPNG(a)
+		Optimize(b, tt.level)
+		if len(b.Images) != len(tt.wantRects) {
+			t.Errorf("level %d: got %d frames, want %d", tt.level, len(b.Images), len(tt.wantRects))
+			continue
+		}
+		for i, m := range b.Images {
+			if m.Bounds() != tt.wantRects[i] {
+				t.Errorf("level %d: frame %d: bounds %v, want %v", tt.level, i, m.Bounds(), tt.wantRects[i])
+			}
+			if _, ok := m.(*image.Paletted); ok != tt.paletted {
+				t.Errorf("level %d: frame %d is %T", tt.level, i, m)
+			}
+		}
+		// The dropped frame's delay goes to the frame before it.
+		if d := b.delay(1); d != 150*time.Millisecond {
+			t.Errorf("level %d: frame 1: delay %v, want 150ms", tt.level, d)
+		}
+		want := copyAPNG(a)
+		want.Images = []image.Image{a.Images[0], a.Images[1], a.Images[3]}
+		want.Durations = []time.Duration{100 * time.Millisecond, 150 * time.Millisecond, 100 * time.Millisecond}
+		if ok, diffs := EqualFrames(b, want, 0); !ok {
+			t.Errorf("level %d: frames differ: %v", tt.level, diffs)
+		}
+	}
+}
+
+func TestOptimizeUnchanged(t *testing.T) {
+	// Below OptimizeDelta, and for 16-bit frames, nothing changes.
+	a := testAPNG(2, 6, 6)
+	a.Images[1] = a.Images[0]
+	Optimize(a, 0)
+	if len(a.Images) != 2 {
+		t.Errorf("level 0: got %d frames, want 2", len(a.Images))
+	}
+
+	deep := image.NewNRGBA64(image.Rect(0, 0, 6, 6))
+	a = &APNG{Images: []image.Image{deep, deep}, Durations: []time.Duration{0, 0}}
+	Optimize(a, OptimizePalette)
+	if len(a.Images) != 2 || a.Images[0] != image.Image(deep) {
+		t.Errorf("16-bit frames were rewritten")
+	}
+
+	// Frames of more than 256 colors keep their model.
+	many := image.NewNRGBA(image.Rect(0, 0, 20, 20))
+	for i := 0; i < len(many.Pix); i += 4 {
+		many.Pix[i], many.Pix[i+1], many.Pix[i+3] = uint8(i/4), uint8(i/1024), 0xff
+	}
+	a = &APNG{Images: []image.Image{many}, Durations: []time.Duration{0}}
+	Optimize(a, OptimizePalette)
+	if _, ok := a.Images[0].(*image.NRGBA); !ok {
+		t.Errorf("frame of 400 colors is %T, want *image.NRGBA", a.Images[0])
+	}
+}
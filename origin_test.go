@@ -0,0 +1,114 @@
+package goapng
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestTranslate(t *testing.T) {
+	r := image.Rect(0, 0, 9, 7)
+	ycbcr := func(ratio image.YCbCrSubsampleRatio) image.Image {
+		m := image.NewYCbCr(r, ratio)
+		for i := range m.Y {
+			m.Y[i] = uint8(i * 7)
+		}
+		for i := range m.Cb {
+			m.Cb[i], m.Cr[i] = uint8(i*13), uint8(255-i*5)
+		}
+		return m
+	}
+	fill := func(m draw.Image) image.Image {
+		b := m.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				m.Set(x, y, color.NRGBA{uint8(x * 20), uint8(y * 30), uint8(x * y), uint8(100 + x + y)})
+			}
+		}
+		return m
+	}
+	tests := []struct {
+		name string
+		m    image.Image
+	}{
+		{"RGBA", fill(image.NewRGBA(r))},
+		{"NRGBA64", fill(image.NewNRGBA64(r))},
+		{"Gray", fill(image.NewGray(r))},
+		{"Alpha", fill(image.NewAlpha(r))},
+		{"Alpha16", fill(image.NewAlpha16(r))},
+		{"CMYK", fill(image.NewCMYK(r))},
+		{"YCbCr 4:4:4", ycbcr(image.YCbCrSubsampleRatio444)},
+		{"YCbCr 4:2:2", ycbcr(image.YCbCrSubsampleRatio422)},
+		{"YCbCr 4:2:0", ycbcr(image.YCbCrSubsampleRatio420)},
+		{"YCbCr 4:1:0", ycbcr(image.YCbCrSubsampleRatio410)},
+		{"NYCbCrA", &image.NYCbCrA{YCbCr: *ycbcr(image.YCbCrSubsampleRatio420).(*image.YCbCr), A: make([]uint8, 63), AStride: 9}},
+		{"LazyImage", func() image.Image {
+			var buf bytes.Buffer
+			if err := EncodeAll(&buf, &APNG{Images: []image.Image{fill(image.NewNRGBA(r))}, Delays: []uint16{0}}); err != nil {
+				t.Fatal(err)
+			}
+			a, err := (&Decoder{Lazy: true}).DecodeAll(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return a.Images[0]
+		}()},
+	}
+	sub := image.Rect(1, 3, 8, 6)
+	for _, tt := range tests {
+		// Move an odd part of the image to the origin and back again.
+		for _, p := range []image.Point{sub.Min.Mul(-1), image.Pt(-3, 2)} {
+			m := translate(subImage(tt.m, sub), p)
+			if m.Bounds() != sub.Add(p) {
+				t.Errorf("%s by %v: bounds %v, want %v", tt.name, p, m.Bounds(), sub.Add(p))
+				continue
+			}
+			if !equalColorModel(m.ColorModel(), tt.m.ColorModel()) {
+				t.Errorf("%s by %v: color model changed", tt.name, p)
+			}
+			for y := sub.Min.Y; y < sub.Max.Y; y++ {
+				for x := sub.Min.X; x < sub.Max.X; x++ {
+					if got, want := m.At(x+p.X, y+p.Y), tt.m.At(x, y); got != want {
+						t.Errorf("%s by %v: pixel (%d, %d) = %v, want %v", tt.name, p, x, y, got, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeTranslateOriginYCbCr(t *testing.T) {
+	// Frames cut from a larger YCbCr picture, as from a video, at odd
+	// offsets, so that their chroma is not aligned with the origin.
+	full := image.NewYCbCr(image.Rect(0, 0, 20, 20), image.YCbCrSubsampleRatio420)
+	for i := range full.Y {
+		full.Y[i] = uint8(i * 3)
+	}
+	for i := range full.Cb {
+		full.Cb[i], full.Cr[i] = uint8(i*11), uint8(200-i)
+	}
+	a := &APNG{Delays: []uint16{10, 10, 10}}
+	want := &APNG{Delays: a.Delays}
+	for _, p := range []image.Point{{0, 0}, {1, 1}, {3, 2}} {
+		r := image.Rect(0, 0, 8, 8).Add(p)
+		a.Images = append(a.Images, full.SubImage(r))
+		m := image.NewRGBA(image.Rect(0, 0, 8, 8))
+		draw.Draw(m, m.Rect, full, r.Min, draw.Src)
+		want.Images = append(want.Images, m)
+	}
+
+	var buf bytes.Buffer
+	enc := Encoder{OriginPolicy: TranslateOrigin}
+	if err := enc.EncodeAll(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, diffs := EqualFrames(got, want, 0); !ok {
+		t.Errorf("frames differ: %v", diffs)
+	}
+}
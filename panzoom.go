@@ -0,0 +1,69 @@
+package goapng
+
+import (
+	"errors"
+	"image"
+	"math"
+)
+
+// PanZoom returns an animation of frames frames that moves a viewport over
+// src from the rectangle from to the rectangle to, panning and zooming as
+// a camera would over a still photograph. The frames are the size of from;
+// the viewport is interpolated linearly and sampled bilinearly, with
+// sub-pixel precision so that slow pans don't jitter. The frames are shown
+// at 30 frames per second; use SetSpeed to change that.
+func PanZoom(src image.Image, from, to image.Rectangle, frames int) (*APNG, error) {
+	if frames < 1 || from.Empty() || to.Empty() {
+		return nil, errors.New("apng: invalid pan and zoom")
+	}
+	m := toRGBA(src, src.Bounds())
+	w, h := from.Dx(), from.Dy()
+	imgs := make([]image.Image, frames)
+	for i := range imgs {
+		t := 0.0
+		if frames > 1 {
+			t = float64(i) / float64(frames-1)
+		}
+		lerp := func(a, b int) float64 {
+			return float64(a) + (float64(b)-float64(a))*t
+		}
+		x0, y0 := lerp(from.Min.X, to.Min.X), lerp(from.Min.Y, to.Min.Y)
+		x1, y1 := lerp(from.Max.X, to.Max.X), lerp(from.Max.Y, to.Max.Y)
+		imgs[i] = sampleView(m, x0, y0, (x1-x0)/float64(w), (y1-y0)/float64(h), w, h)
+	}
+	return NewFromFPS(imgs, 30), nil
+}
+
+// sampleView returns a w x h image of the view of m whose top left corner
+// is at (x0, y0) in m, with each destination pixel covering sx x sy source
+// pixels. Pixels beyond the edges of m repeat the edge.
+func sampleView(m *image.RGBA, x0, y0, sx, sy float64, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	b := m.Rect
+	for y := 0; y < h; y++ {
+		fy := y0 + (float64(y)+0.5)*sy - 0.5
+		iy := math.Floor(fy)
+		ty := fy - iy
+		r0 := clampInt(int(iy), b.Min.Y, b.Max.Y-1)
+		r1 := clampInt(int(iy)+1, b.Min.Y, b.Max.Y-1)
+		for x := 0; x < w; x++ {
+			fx := x0 + (float64(x)+0.5)*sx - 0.5
+			ix := math.Floor(fx)
+			tx := fx - ix
+			c0 := clampInt(int(ix), b.Min.X, b.Max.X-1)
+			c1 := clampInt(int(ix)+1, b.Min.X, b.Max.X-1)
+
+			p00 := m.Pix[m.PixOffset(c0, r0):]
+			p01 := m.Pix[m.PixOffset(c1, r0):]
+			p10 := m.Pix[m.PixOffset(c0, r1):]
+			p11 := m.Pix[m.PixOffset(c1, r1):]
+			d := dst.Pix[dst.PixOffset(x, y):]
+			for k := 0; k < 4; k++ {
+				top := float64(p00[k])*(1-tx) + float64(p01[k])*tx
+				bottom := float64(p10[k])*(1-tx) + float64(p11[k])*tx
+				d[k] = uint8(math.Round(top*(1-ty) + bottom*ty))
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,82 @@
+package goapng
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestPanZoom(t *testing.T) {
+	// A gradient, so that every pixel says where it came from.
+	src := image.NewRGBA(image.Rect(0, 0, 16, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			src.SetRGBA(x, y, color.RGBA{uint8(16 * x), uint8(32 * y), 0, 0xff})
+		}
+	}
+	crop := func(r image.Rectangle) image.Image {
+		return translate(toRGBA(src, src.Rect).SubImage(r), r.Min.Mul(-1))
+	}
+
+	// A pan by 8 pixels across and 2 down over 5 frames moves 2 pixels
+	// across and half a pixel down a frame.
+	a, err := PanZoom(src, image.Rect(0, 0, 4, 4), image.Rect(8, 2, 12, 6), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Images) != 5 {
+		t.Fatalf("got %d frames, want 5", len(a.Images))
+	}
+	for i, m := range a.Images {
+		r := image.Rect(0, 0, 4, 4).Add(image.Pt(2*i, i/2))
+		if i%2 == 1 {
+			// Half a pixel down: the rows are blended.
+			continue
+		}
+		if !samePixels(m, crop(r)) {
+			t.Errorf("pan: frame %d is not the view at %v", i, r)
+		}
+	}
+	if got := a.Images[1].(*image.RGBA).RGBAAt(0, 0); got != (color.RGBA{32, 16, 0, 0xff}) {
+		t.Errorf("pan: frame 1 at 0,0: %v, want the rows blended", got)
+	}
+	if d := a.delay(0); d != time.Second/30 {
+		t.Errorf("delay %v, want 1/30 s", d)
+	}
+
+	// Zooming out to twice the area makes each pixel the average of two
+	// source pixels across.
+	a, err = PanZoom(src, image.Rect(0, 0, 4, 2), image.Rect(0, 0, 8, 4), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := a.Images[1].(*image.RGBA)
+	if last.Rect != image.Rect(0, 0, 4, 2) {
+		t.Errorf("zoom: frame bounds %v, want the size of from", last.Rect)
+	}
+	for x := 0; x < 4; x++ {
+		if got := last.RGBAAt(x, 0).R; got != uint8(32*x+8) {
+			t.Errorf("zoom: red at %d,0: %d, want %d", x, got, 32*x+8)
+		}
+	}
+
+	// A single frame shows from.
+	a, err = PanZoom(src, image.Rect(2, 2, 5, 4), image.Rect(0, 0, 1, 1), 1)
+	if err != nil || len(a.Images) != 1 || !samePixels(a.Images[0], crop(image.Rect(2, 2, 5, 4))) {
+		t.Errorf("single frame: got %v, want the view of from", err)
+	}
+
+	for _, bad := range []struct {
+		from, to image.Rectangle
+		frames   int
+	}{
+		{image.Rect(0, 0, 4, 4), image.Rect(0, 0, 4, 4), 0},
+		{image.Rectangle{}, image.Rect(0, 0, 4, 4), 2},
+		{image.Rect(0, 0, 4, 4), image.Rect(3, 3, 3, 5), 2},
+	} {
+		if _, err := PanZoom(src, bad.from, bad.to, bad.frames); err == nil {
+			t.Errorf("PanZoom(%v, %v, %d): got no error", bad.from, bad.to, bad.frames)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package goapng
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// checkPingPong checks that b, a after PingPong, encodes and displays the
+// frames of a forwards and then backwards.
+func checkPingPong(t *testing.T, a, b *APNG) {
+	t.Helper()
+	want, err := Composite(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := EncodeAll(&buf, b); err != nil {
+		t.Fatal(err)
+	}
+	d, err := DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Composite(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := len(want)
+	if len(got) != 2*n-2 {
+		t.Fatalf("got %d frames, want %d", len(got), 2*n-2)
+	}
+	for i, m := range got {
+		j := i
+		if i >= n {
+			j = 2*n - 2 - i
+		}
+		if !samePixels(m, want[j]) {
+			t.Errorf("frame %d does not show frame %d", i, j)
+		}
+	}
+}
+
+func TestPingPong(t *testing.T) {
+	a := testAPNG(4, 6, 6)
+	a.Images[2] = a.Images[2].(*image.NRGBA).SubImage(image.Rect(1, 1, 4, 4))
+	b := &APNG{}
+	*b = *a
+	b.Images = append([]image.Image(nil), a.Images...)
+	PingPong(b)
+	checkPingPong(t, a, b)
+	if b.Images[2] != a.Images[2] {
+		t.Error("an original frame was replaced")
+	}
+}
+
+func TestPingPongYCbCr(t *testing.T) {
+	// The appended canvases can't be YCbCr, so the frames that are kept
+	// must not stay YCbCr either, or the color models differ.
+	a := testAPNG(3, 8, 8)
+	for i := range a.Images {
+		m := image.NewYCbCr(image.Rect(0, 0, 8, 8), image.YCbCrSubsampleRatio444)
+		for j := range m.Y {
+			m.Y[j], m.Cb[j], m.Cr[j] = uint8(60*i+j), 90, uint8(200-j)
+		}
+		a.Images[i] = m
+	}
+	b := &APNG{}
+	*b = *a
+	b.Images = append([]image.Image(nil), a.Images...)
+	PingPong(b)
+	if !isSameColorModel(b.Images) {
+		t.Fatal("frames have different color models")
+	}
+	checkPingPong(t, a, b)
+}
+
+func TestPingPongPaletted(t *testing.T) {
+	// Blending frame 1 over frame 0 shows a color that isn't in the
+	// palette, which must not be rounded to one that is.
+	pal := color.Palette{color.NRGBA{0xff, 0, 0, 0xff}, color.NRGBA{0, 0, 0xff, 0x80}, color.NRGBA{0, 0xff, 0, 0xff}}
+	fill := func(r image.Rectangle, idx uint8) *image.Paletted {
+		m := image.NewPaletted(r, pal)
+		for i := range m.Pix {
+			m.Pix[i] = idx
+		}
+		return m
+	}
+	a := testAPNG(3, 8, 8)
+	a.Images = []image.Image{fill(image.Rect(0, 0, 8, 8), 0), fill(image.Rect(2, 2, 6, 6), 1), fill(image.Rect(0, 0, 2, 2), 2)}
+	a.Blends = []byte{BlendOpSource, BlendOpOver, BlendOpSource}
+	b := &APNG{}
+	*b = *a
+	b.Images = append([]image.Image(nil), a.Images...)
+	b.Blends = append([]byte(nil), a.Blends...)
+	PingPong(b)
+	if !isSameColorModel(b.Images) {
+		t.Fatal("frames have different color models")
+	}
+	checkPingPong(t, a, b)
+}
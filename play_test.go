@@ -0,0 +1,66 @@
+package goapng
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"testing"
+	"time"
+)
+
+func TestPlay(t *testing.T) {
+	a := testAPNG(3, 2, 2)
+	for i := range a.Durations {
+		a.Durations[i] = 20 * time.Millisecond
+	}
+	a.LoopCount = 1
+
+	var times []time.Duration
+	start := time.Now()
+	err := Play(context.Background(), a, func(img image.Image, ts time.Duration) error {
+		// Frames are not shown before they are due.
+		if since := time.Since(start); since < ts {
+			t.Errorf("frame at %v shown after %v", ts, since)
+		}
+		times = append(times, ts)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[0s 20ms 40ms]"; fmt.Sprint(times) != want {
+		t.Errorf("display times %v, want %s", times, want)
+	}
+	// Play returns once the last frame has been shown for its delay.
+	if since := time.Since(start); since < 60*time.Millisecond {
+		t.Errorf("returned after %v, want at least 60ms", since)
+	}
+
+	errStop := errors.New("stop")
+	calls := 0
+	err = Play(context.Background(), a, func(image.Image, time.Duration) error {
+		calls++
+		if calls == 2 {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop || calls != 2 {
+		t.Errorf("fn failing at frame 1: got error %v after %d calls, want errStop after 2", err, calls)
+	}
+
+	a.LoopCount = LoopForever
+	ctx, cancel := context.WithCancel(context.Background())
+	err = Play(ctx, a, func(image.Image, time.Duration) error {
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled: got error %v, want context.Canceled", err)
+	}
+
+	if err := Play(context.Background(), &APNG{}, nil); !errors.Is(err, ErrNoFrames) {
+		t.Errorf("no frames: got error %v, want ErrNoFrames", err)
+	}
+}
@@ -0,0 +1,141 @@
+package goapng
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"time"
+)
+
+// A Player steps through an animation as it is displayed: each frame is
+// composited onto the canvas, and the animation is played as many times as
+// its loop count says, starting each play from an empty canvas.
+type Player struct {
+	// Background, if non-nil, is drawn behind every frame returned by
+	// Next, so that frames come out opaque, as when showing a thumbnail on
+	// a white page. Otherwise the canvas keeps its alpha channel. Setting
+	// it to the animation's own Background honors its bKGD chunk.
+	Background color.Color
+
+	a  *APNG
+	c  *compositor
+	ts []time.Duration
+
+	play int           // The current play, from 0.
+	next int           // The index of the next frame.
+	at   time.Duration // The display time of the last frame returned.
+	out  *image.RGBA   // The canvas over Background.
+}
+
+// NewPlayer returns a Player positioned before the first frame of a. It
+// returns an error if a is not valid.
+func NewPlayer(a *APNG) (*Player, error) {
+	if err := Validate(a); err != nil {
+		return nil, err
+	}
+	b := a.bounds()
+	return &Player{
+		a:  a,
+		c:  newCompositor(b.Dx(), b.Dy()),
+		ts: a.timeline(),
+	}, nil
+}
+
+// Next returns the canvas as the next frame leaves it and how long the
+// frame is displayed. The canvas is reused by the following call to Next.
+// Next returns io.EOF once the last play has ended, which never happens
+// for an animation that loops forever.
+func (p *Player) Next() (*image.RGBA, time.Duration, error) {
+	a := p.a
+	if p.next == len(a.Images) {
+		if a.LoopCount != LoopForever && uint32(p.play+1) >= a.LoopCount {
+			return nil, 0, io.EOF
+		}
+		p.play++
+		p.next = 0
+		b := a.bounds()
+		p.c = newCompositor(b.Dx(), b.Dy())
+	}
+
+	i := p.next
+	if err := a.checkRender(i); err != nil {
+		return nil, 0, err
+	}
+	canvas := p.c.render(a.Images[i], a.disposal(i), a.blend(i))
+	p.next++
+	if p.Background != nil {
+		if p.out == nil {
+			p.out = image.NewRGBA(canvas.Rect)
+		}
+		overBackground(p.out, canvas, p.Background)
+		canvas = p.out
+	}
+
+	p.at = time.Duration(p.play)*p.ts[len(a.Images)] + p.ts[i]
+	return canvas, p.ts[i+1] - p.ts[i], nil
+}
+
+// overBackground draws m over a background of color bg into dst, which has
+// the bounds of m.
+func overBackground(dst, m *image.RGBA, bg color.Color) {
+	draw.Draw(dst, dst.Rect, image.NewUniform(bg), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Rect, m, m.Rect.Min, draw.Over)
+}
+
+// Time returns the time at which the frame last returned by Next is
+// displayed, measured from the start of the first play.
+func (p *Player) Time() time.Duration {
+	return p.at
+}
+
+// Play plays a in real time, calling fn with each frame when it is due to
+// be displayed, together with its display time measured from the start of
+// the first play. The image passed to fn is only valid until fn returns.
+// Frames are scheduled against the clock at which Play started, so a slow
+// fn delays the frames after it without the animation drifting. Play
+// returns once the last frame has been shown for its delay, when ctx is
+// done, or at the first error returned by fn.
+func Play(ctx context.Context, a *APNG, fn func(img image.Image, ts time.Duration) error) error {
+	p, err := NewPlayer(a)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	var end time.Duration
+	for {
+		img, d, err := p.Next()
+		if err == io.EOF {
+			return sleepUntil(ctx, start.Add(end))
+		}
+		if err != nil {
+			return err
+		}
+		if err := sleepUntil(ctx, start.Add(p.Time())); err != nil {
+			return err
+		}
+		if err := fn(img, p.Time()); err != nil {
+			return err
+		}
+		end = p.Time() + d
+	}
+}
+
+// sleepUntil waits until t or until ctx is done, returning ctx.Err() in the
+// latter case.
+func sleepUntil(ctx context.Context, t time.Time) error {
+	d := time.Until(t)
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
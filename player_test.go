@@ -0,0 +1,88 @@
+package goapng
+
+import (
+	"image"
+	"image/color"
+	"io"
+	"testing"
+	"time"
+)
+
+// playerAPNG returns an animation of three frames whose first frame is
+// half transparent and blended over the canvas, so that every play must
+// start from an empty canvas to look the same.
+func playerAPNG() *APNG {
+	a := testAPNG(3, 4, 4)
+	a.Images[0] = solid(4, 4, color.NRGBA{0xff, 0, 0, 0x80})
+	a.Images[1] = translate(solid(2, 2, color.NRGBA{0, 0, 0xff, 0xff}), image.Pt(1, 1))
+	a.Blends = []byte{BlendOpOver, BlendOpSource, BlendOpSource}
+	a.Disposals = []byte{DisposeOpNone, DisposeOpPrevious, DisposeOpNone}
+	a.LoopCount = 2
+	return a
+}
+
+func TestPlayer(t *testing.T) {
+	a := playerAPNG()
+	want, err := Composite(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewPlayer(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 6; i++ {
+		m, d, err := p.Next()
+		if err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+		if !samePixels(m, want[i%3]) {
+			t.Errorf("frame %d differs from composited frame %d", i, i%3)
+		}
+		if d != 100*time.Millisecond || p.Time() != time.Duration(i)*100*time.Millisecond {
+			t.Errorf("frame %d: shown at %v for %v, want at %v for 100ms", i, p.Time(), d, time.Duration(i)*100*time.Millisecond)
+		}
+	}
+	if _, _, err := p.Next(); err != io.EOF {
+		t.Errorf("after two plays: got error %v, want io.EOF", err)
+	}
+
+	// An animation that loops forever never ends.
+	a.LoopCount = LoopForever
+	p, err = NewPlayer(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, _, err := p.Next(); err != nil {
+			t.Fatalf("frame %d of a looping animation: %v", i, err)
+		}
+	}
+}
+
+func TestPlayerBackground(t *testing.T) {
+	p, err := NewPlayer(playerAPNG())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Background = color.White
+	m, _, err := p.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Half-transparent red over white.
+	if got := m.RGBAAt(0, 0); got != (color.RGBA{0xff, 0x7f, 0x7f, 0xff}) {
+		t.Errorf("frame 0 over white: %v, want {255 127 127 255}", got)
+	}
+}
+
+func TestPlayerInvalid(t *testing.T) {
+	a := testAPNG(2, 4, 4)
+	a.Images[1] = translate(a.Images[1], image.Pt(2, 2))
+	if _, err := NewPlayer(a); err == nil {
+		t.Error("frame outside the canvas: got no error")
+	}
+	if _, err := NewPlayer(&APNG{}); err == nil {
+		t.Error("no frames: got no error")
+	}
+}
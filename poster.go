@@ -0,0 +1,24 @@
+package goapng
+
+import (
+	"image/png"
+	"io"
+	"time"
+)
+
+// FlattenToPNG reads an APNG from r and writes the frame displayed at time
+// at, as given by FrameAt, to w as a static PNG. It suits fallback images
+// for viewers without APNG support and link previews. Frames after the
+// one shown are not decompressed.
+func FlattenToPNG(w io.Writer, r io.Reader, at time.Duration) error {
+	dec := Decoder{Lazy: true}
+	a, err := dec.DecodeAll(r)
+	if err != nil {
+		return err
+	}
+	img, err := FrameAt(a, at)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
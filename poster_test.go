@@ -0,0 +1,55 @@
+package goapng
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+	"time"
+)
+
+func TestFlattenToPNG(t *testing.T) {
+	a := testAPNG(4, 6, 6)
+	var buf bytes.Buffer
+	if err := EncodeAll(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+	// Break the data of the last frame. Only flattening that frame
+	// decompresses it.
+	fdATs := 0
+	for _, c := range readTestChunks(t, buf.Bytes()) {
+		if c.name == "fdAT" {
+			fdATs++
+		}
+	}
+	data := rewriteChunks(t, buf.Bytes(), func(c *testChunk) bool {
+		if c.name == "fdAT" {
+			if fdATs--; fdATs == 0 {
+				for i := range c.data[4:] {
+					c.data[4+i] = 0
+				}
+			}
+		}
+		return true
+	})
+
+	for _, tt := range []struct {
+		at   time.Duration
+		want int
+	}{{0, 0}, {150 * time.Millisecond, 1}, {299 * time.Millisecond, 2}} {
+		var out bytes.Buffer
+		if err := FlattenToPNG(&out, bytes.NewReader(data), tt.at); err != nil {
+			t.Errorf("at %v: %v", tt.at, err)
+			continue
+		}
+		m, err := png.Decode(&out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !samePixels(m, a.Images[tt.want]) {
+			t.Errorf("at %v: does not show frame %d", tt.at, tt.want)
+		}
+	}
+	if err := FlattenToPNG(new(bytes.Buffer), bytes.NewReader(data), 300*time.Millisecond); err == nil {
+		t.Error("flattening the broken frame: got no error")
+	}
+}
@@ -0,0 +1,139 @@
+package goapng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+)
+
+// IsAPNG reports whether ra holds an animated PNG: a PNG file with an acTL
+// chunk before its first IDAT chunk. Only the signature and the chunk
+// headers up to the first IDAT are read, so it is cheap enough to run on
+// every upload. A file that is not a PNG is reported as false with a nil
+// error.
+func IsAPNG(ra io.ReaderAt) (bool, error) {
+	var tmp [8]byte
+	if err := readFullAt(ra, tmp[:len(pngHeader)], 0); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return false, nil
+		}
+		return false, err
+	}
+	if string(tmp[:len(pngHeader)]) != pngHeader {
+		return false, nil
+	}
+
+	off := int64(len(pngHeader))
+	for {
+		if err := readFullAt(ra, tmp[:8], off); err != nil {
+			return false, err
+		}
+		switch string(tmp[4:8]) {
+		case "acTL":
+			return true, nil
+		case "IDAT", "IEND":
+			return false, nil
+		}
+		off += 12 + int64(binary.BigEndian.Uint32(tmp[:4]))
+	}
+}
+
+// CountFrames returns the number of frames of the APNG read from r, as
+// given by its acTL chunk, or 1 for a static PNG. It reads no further than
+// the acTL chunk, or the first IDAT chunk of a static PNG, and decompresses
+// nothing. The count is not checked against the frames the file holds; use
+// VerifyStream for that.
+func CountFrames(r io.Reader) (int, error) {
+	cr := newChunkReader(r)
+	if err := cr.readSignature(); err != nil {
+		return 0, err
+	}
+	for {
+		if _, err := io.ReadFull(cr.r, cr.tmp[:8]); err != nil {
+			return 0, unexpectedEOF(err)
+		}
+		length := int64(binary.BigEndian.Uint32(cr.tmp[:4]))
+		switch string(cr.tmp[4:8]) {
+		case "acTL":
+			if length != 8 {
+				return 0, FormatError("bad acTL length")
+			}
+			var b [12]byte
+			if _, err := io.ReadFull(cr.r, b[:]); err != nil {
+				return 0, unexpectedEOF(err)
+			}
+			crc := crc32.NewIEEE()
+			crc.Write(cr.tmp[4:8])
+			crc.Write(b[:8])
+			if crc.Sum32() != binary.BigEndian.Uint32(b[8:]) {
+				return 0, fmt.Errorf("%w in acTL chunk", ErrChecksum)
+			}
+			return int(binary.BigEndian.Uint32(b[:4])), nil
+		case "IDAT":
+			return 1, nil
+		case "IEND":
+			return 0, FormatError("missing IDAT")
+		}
+		if _, err := io.CopyN(io.Discard, cr.r, length+4); err != nil {
+			return 0, unexpectedEOF(err)
+		}
+	}
+}
+
+// DecodeDefaultImage reads the default image of the PNG or APNG read from
+// r: the image stored in its IDAT chunks, which viewers without APNG
+// support show. Reading stops at the header of the chunk that follows the
+// last IDAT chunk, so the frames after it are neither read nor
+// decompressed, and a file cut short after its default image still
+// decodes. The default image is the first frame of the animation unless
+// the file keeps it apart.
+func DecodeDefaultImage(r io.Reader) (image.Image, error) {
+	cr := newChunkReader(r)
+	if err := cr.readSignature(); err != nil {
+		return nil, err
+	}
+
+	// Rebuild a static PNG from the chunks image/png needs.
+	bb := new(bytes.Buffer)
+	e := encoder{w: bb}
+	_, e.err = io.WriteString(bb, pngHeader)
+	seenIDAT := false
+	for {
+		name, length, err := cr.header()
+		if seenIDAT {
+			// The default image is complete once its IDAT chunks end, even
+			// if the file is cut short there.
+			if err == io.EOF || err == io.ErrUnexpectedEOF || err == nil && name != "IDAT" {
+				break
+			}
+		}
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		data, crcOK, err := cr.body(name, length)
+		if err != nil {
+			return nil, err
+		}
+		if !crcOK {
+			return nil, fmt.Errorf("%w in %s chunk", ErrChecksum, name)
+		}
+		switch name {
+		case "IHDR", "PLTE", "tRNS":
+			e.writeChunk(data, name)
+		case "IDAT":
+			e.writeChunk(data, name)
+			seenIDAT = true
+		case "IEND":
+			return nil, FormatError("missing IDAT")
+		}
+	}
+	e.writeIEND()
+	if e.err != nil {
+		return nil, e.err
+	}
+	return png.Decode(bb)
+}
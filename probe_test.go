@@ -0,0 +1,61 @@
+package goapng
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestDecodeDefaultImage(t *testing.T) {
+	a := testAPNG(3, 6, 5)
+	var buf bytes.Buffer
+	if err := EncodeAll(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+	file := buf.Bytes()
+	def := solid(6, 5, color.NRGBA{7, 8, 9, 0xff})
+	separate := separateDefault(t, a, def)
+
+	// endOfIDATs returns the offset just past the last IDAT chunk of b.
+	endOfIDATs := func(b []byte) int {
+		chunks, err := ReadChunks(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		end := 0
+		for _, c := range chunks {
+			if c.Type == "IDAT" {
+				end = int(c.Offset) + 12 + int(c.Length)
+			}
+		}
+		return end
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want image.Image
+	}{
+		{"whole file", file, a.Images[0]},
+		{"cut after the IDAT chunks", file[:endOfIDATs(file)], a.Images[0]},
+		{"cut in the next header", file[:endOfIDATs(file)+5], a.Images[0]},
+		{"cut in the next chunk", file[:endOfIDATs(file)+20], a.Images[0]},
+		{"separate default image", separate, def},
+		{"separate, cut in frame 0", separate[:endOfIDATs(separate)+40], def},
+	}
+	for _, tt := range tests {
+		m, err := DecodeDefaultImage(bytes.NewReader(tt.data))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !samePixels(m, tt.want) {
+			t.Errorf("%s: pixels differ", tt.name)
+		}
+	}
+
+	if _, err := DecodeDefaultImage(bytes.NewReader(file[:endOfIDATs(file)-1])); err == nil {
+		t.Error("cut in the IDAT chunk: got no error")
+	}
+}
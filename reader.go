@@ -0,0 +1,765 @@
+package goapng
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"sync"
+	"time"
+)
+
+// A FormatError reports that the input is not a valid APNG.
+type FormatError string
+
+func (e FormatError) Error() string { return "apng: invalid format: " + string(e) }
+
+// An UnsupportedError reports that the input uses a valid but unimplemented
+// feature.
+type UnsupportedError string
+
+func (e UnsupportedError) Error() string { return "apng: unsupported feature: " + string(e) }
+
+// Decoder configures decoding APNG images.
+type Decoder struct {
+	// ReuseCanvas makes DecodeFrames pass the callback its one reused
+	// canvas, with a second canvas kept for DisposeOpPrevious, instead of a
+	// copy of the canvas per frame, and decompress every frame into a
+	// single reused frame buffer, so that memory use doesn't grow with the
+	// number of frames. The image passed to the callback is then only
+	// valid until the callback returns.
+	ReuseCanvas bool
+
+	// Lazy makes DecodeAll keep each frame's compressed data and return
+	// *LazyImage frames that are only decompressed when their pixels are
+	// first accessed.
+	Lazy bool
+
+	// Concurrency is the number of goroutines DecodeAll uses to decompress
+	// frames. Values below 2 decode frames sequentially. It has no effect
+	// when Lazy is set.
+	Concurrency int
+
+	// Lenient makes the decoder accept chunks that are repeated or out of
+	// order, such as a second acTL or an fdAT before any fcTL, sequence
+	// numbers that skip or repeat, and a num_frames that does not match the
+	// frames present, instead of failing with a FormatError. Chunks that
+	// cannot be used are ignored, as are frames without data. Decoding
+	// stops at IEND either way, so chunks after it are never read.
+	Lenient bool
+
+	// CRCPolicy says what the decoder does with chunks whose checksum does
+	// not match their contents.
+	CRCPolicy CRCPolicy
+
+	// OnWarning, if non-nil, is called with each problem that Lenient or
+	// WarnCRC lets through, and with problems that never stop a decode:
+	// unknown critical chunks, which are skipped, and unknown disposal
+	// methods and blend operations, which are kept as they are.
+	OnWarning func(err error)
+}
+
+// CRCPolicy says how the decoder checks chunk checksums.
+type CRCPolicy int
+
+const (
+	// StrictCRC fails the decode with ErrChecksum.
+	StrictCRC CRCPolicy = iota
+	// WarnCRC passes the problem to Decoder.OnWarning and uses the chunk
+	// as it is.
+	WarnCRC
+	// SkipCRC does not compute checksums at all, which is fastest.
+	SkipCRC
+)
+
+type frameControl struct {
+	seqNum    uint32
+	width     uint32
+	height    uint32
+	xOffset   uint32
+	yOffset   uint32
+	delayNum  uint16
+	delayDen  uint16
+	disposeOp byte
+	blendOp   byte
+}
+
+func parsefcTL(b []byte) (frameControl, error) {
+	if len(b) != 26 {
+		return frameControl{}, FormatError("bad fcTL length")
+	}
+	return frameControl{
+		seqNum:    binary.BigEndian.Uint32(b[0:4]),
+		width:     binary.BigEndian.Uint32(b[4:8]),
+		height:    binary.BigEndian.Uint32(b[8:12]),
+		xOffset:   binary.BigEndian.Uint32(b[12:16]),
+		yOffset:   binary.BigEndian.Uint32(b[16:20]),
+		delayNum:  binary.BigEndian.Uint16(b[20:22]),
+		delayDen:  binary.BigEndian.Uint16(b[22:24]),
+		disposeOp: b[24],
+		blendOp:   b[25],
+	}, nil
+}
+
+// duration returns the frame delay as a time.Duration.
+func (fc *frameControl) duration() time.Duration {
+	den := time.Duration(fc.delayDen)
+	if den == 0 {
+		den = 100
+	}
+	return time.Duration(fc.delayNum) * time.Second / den
+}
+
+func (fc *frameControl) bounds() image.Rectangle {
+	x, y := int(fc.xOffset), int(fc.yOffset)
+	return image.Rect(x, y, x+int(fc.width), y+int(fc.height))
+}
+
+// rawFrame is a frame whose image data is still compressed.
+type rawFrame struct {
+	fc    frameControl
+	idats []idat
+	refs  []chunkRef // Frame data left in decoder.ra, when decoding from an io.ReaderAt.
+}
+
+func (f *rawFrame) hasData() bool { return len(f.idats) > 0 || len(f.refs) > 0 }
+
+// chunkRef locates the data of an IDAT or fdAT chunk in an io.ReaderAt.
+type chunkRef struct {
+	name   string
+	off    int64 // Offset of the chunk data.
+	length int64 // Length of the chunk data.
+}
+
+type decoder struct {
+	ctx context.Context
+	ra  io.ReaderAt // Source of the data referenced by rawFrame.refs; may be nil.
+
+	lenient bool
+	crc     CRCPolicy
+	warn    func(err error)
+
+	ihdr      []byte
+	plte      []byte
+	trns      []byte
+	bkgd      []byte
+	width     int
+	height    int
+	order     chunkOrder
+	numFrames uint32
+	numPlays  uint32
+
+	defaultImage rawFrame // IDAT data, when it is not part of the animation.
+	frames       []*rawFrame
+}
+
+func (dec *Decoder) newDecoder(ctx context.Context) *decoder {
+	return &decoder{ctx: ctx, lenient: dec.Lenient, crc: dec.CRCPolicy, warn: dec.OnWarning}
+}
+
+// readChunks reads the whole stream, keeping the compressed frame data.
+func (d *decoder) readChunks(r io.Reader) error {
+	cr := newChunkReader(r)
+	cr.skipCRC = d.crc == SkipCRC
+	if err := cr.readSignature(); err != nil {
+		return err
+	}
+
+	for {
+		if err := d.ctx.Err(); err != nil {
+			return err
+		}
+		name, data, crcOK, err := cr.nextUnchecked()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		if !crcOK {
+			if err := d.badChecksum(name); err != nil {
+				return err
+			}
+		}
+		if done, err := d.parseChunk(name, data, nil); done || err != nil {
+			return err
+		}
+	}
+}
+
+// readChunksAt indexes the chunks of ra with positioned reads. Only the
+// small control chunks are read; the frame data is left in place and read
+// when a frame is decoded.
+func (d *decoder) readChunksAt(ra io.ReaderAt) error {
+	d.ra = ra
+
+	var tmp [8]byte
+	if err := readFullAt(ra, tmp[:len(pngHeader)], 0); err != nil {
+		return err
+	}
+	if string(tmp[:len(pngHeader)]) != pngHeader {
+		return FormatError("not a PNG file")
+	}
+
+	off := int64(len(pngHeader))
+	for {
+		if err := d.ctx.Err(); err != nil {
+			return err
+		}
+		if err := readFullAt(ra, tmp[:8], off); err != nil {
+			return err
+		}
+		length := int64(binary.BigEndian.Uint32(tmp[:4]))
+		if length > maxChunkLength {
+			return FormatError("chunk is too large")
+		}
+		ref := &chunkRef{name: string(tmp[4:8]), off: off + 8, length: length}
+		off += 8 + length + 4
+
+		var data []byte
+		if ref.name != "IDAT" && ref.name != "fdAT" {
+			var err error
+			if data, err = d.readRef(ref); err != nil {
+				return err
+			}
+			ref = nil
+		}
+		if done, err := d.parseChunk(string(tmp[4:8]), data, ref); done || err != nil {
+			return err
+		}
+	}
+}
+
+// readRef reads the data of the chunk at ref and verifies its checksum
+// according to the decoder's CRCPolicy.
+func (d *decoder) readRef(ref *chunkRef) ([]byte, error) {
+	b := make([]byte, ref.length+4)
+	if err := readFullAt(d.ra, b, ref.off); err != nil {
+		return nil, err
+	}
+	data := b[:ref.length]
+	if d.crc == SkipCRC {
+		return data, nil
+	}
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(ref.name))
+	crc.Write(data)
+	if crc.Sum32() != binary.BigEndian.Uint32(b[ref.length:]) {
+		if err := d.badChecksum(ref.name); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// badChecksum reports a chunk whose checksum does not match, according to
+// the decoder's CRCPolicy. It returns nil if the chunk is to be used anyway.
+func (d *decoder) badChecksum(name string) error {
+	err := fmt.Errorf("%w in %s chunk", ErrChecksum, name)
+	if d.crc == StrictCRC {
+		return err
+	}
+	d.warning(err)
+	return nil
+}
+
+func readFullAt(ra io.ReaderAt, b []byte, off int64) error {
+	n, err := ra.ReadAt(b, off)
+	if n == len(b) {
+		return nil
+	}
+	return unexpectedEOF(err)
+}
+
+// parseChunk updates the decoder state with one chunk. Frame data is given
+// either as data or, when decoding from an io.ReaderAt, as ref. It reports
+// whether IEND has been reached.
+func (d *decoder) parseChunk(name string, data []byte, ref *chunkRef) (bool, error) {
+	head := data
+	if ref != nil && name == "fdAT" && ref.length >= 4 {
+		head = make([]byte, 4)
+		if err := readFullAt(d.ra, head, ref.off); err != nil {
+			return false, err
+		}
+	}
+	problems, skip := d.order.check(name, head)
+	for _, p := range problems {
+		if err := d.malformed(p); err != nil {
+			return false, err
+		}
+	}
+	if skip {
+		return false, nil
+	}
+
+	switch name {
+	case "IHDR":
+		if len(data) != 13 {
+			return false, FormatError("bad IHDR length")
+		}
+		if err := checkDepth(data[8], data[9]); err != nil {
+			return false, err
+		}
+		d.ihdr = data
+		d.width = int(binary.BigEndian.Uint32(data[0:4]))
+		d.height = int(binary.BigEndian.Uint32(data[4:8]))
+	case "acTL":
+		if len(data) != 8 {
+			return false, FormatError("bad acTL length")
+		}
+		d.numFrames = binary.BigEndian.Uint32(data[0:4])
+		d.numPlays = binary.BigEndian.Uint32(data[4:8])
+		if d.numPlays > maxLoopCount {
+			return false, FormatError("bad num_plays")
+		}
+	case "PLTE":
+		d.plte = data
+	case "bKGD":
+		d.bkgd = data
+	case "tRNS":
+		d.trns = data
+	case "fcTL":
+		fc, err := parsefcTL(data)
+		if err != nil {
+			return false, err
+		}
+		if b := fc.bounds(); b.Empty() || !b.In(image.Rect(0, 0, d.width, d.height)) {
+			return false, FormatError("frame region outside the image")
+		}
+		if fc.disposeOp > DisposeOpPrevious {
+			d.warning(FormatError(fmt.Sprintf("frame %d has unknown dispose_op %d", len(d.frames), fc.disposeOp)))
+		}
+		if fc.blendOp > BlendOpOver {
+			d.warning(FormatError(fmt.Sprintf("frame %d has unknown blend_op %d", len(d.frames), fc.blendOp)))
+		}
+		// The order check has reported a frame with no data; it is dropped.
+		if n := len(d.frames); n > 0 && !d.frames[n-1].hasData() {
+			d.frames = d.frames[:n-1]
+		}
+		d.frames = append(d.frames, &rawFrame{fc: fc})
+	case "IDAT":
+		if d.ihdr == nil {
+			return false, FormatError("missing IHDR")
+		}
+		f := &d.defaultImage
+		if d.order.idatFrame {
+			f = d.frames[0]
+		}
+		if ref != nil {
+			f.refs = append(f.refs, *ref)
+		} else {
+			f.idats = append(f.idats, data)
+		}
+	case "fdAT":
+		f := d.frames[len(d.frames)-1]
+		if ref != nil {
+			if ref.length < 4 {
+				return false, FormatError("bad fdAT length")
+			}
+			f.refs = append(f.refs, *ref)
+		} else {
+			if len(data) < 4 {
+				return false, FormatError("bad fdAT length")
+			}
+			f.idats = append(f.idats, data[4:])
+		}
+	case "IEND":
+		if !d.order.seenIDAT {
+			return false, FormatError("missing IDAT")
+		}
+		if !d.order.seenacTL {
+			// A static PNG is a single-frame animation.
+			f := d.defaultImage
+			f.fc = frameControl{
+				width:  uint32(d.width),
+				height: uint32(d.height),
+			}
+			d.frames = []*rawFrame{&f}
+		}
+		if n := len(d.frames); n > 0 && !d.frames[n-1].hasData() {
+			d.frames = d.frames[:n-1]
+		}
+		if d.order.seenacTL && d.numFrames != uint32(len(d.frames)) {
+			msg := fmt.Sprintf("num_frames is %d but there are %d frames", d.numFrames, len(d.frames))
+			if err := d.malformed(msg); err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	default:
+		// The case of the first letter marks the chunks a decoder must
+		// understand; they are skipped all the same.
+		if name[0] >= 'A' && name[0] <= 'Z' {
+			d.warning(FormatError("unknown critical chunk " + name))
+		}
+	}
+	return false, nil
+}
+
+// warning passes err to the warning callback, if there is one.
+func (d *decoder) warning(err error) {
+	if d.warn != nil {
+		d.warn(err)
+	}
+}
+
+// malformed reports a problem that Decoder.Lenient lets through, such as a
+// chunk that is repeated or out of order. Unless the decoder is lenient
+// this is an error; otherwise the problem is passed to the warning callback
+// and nil is returned, and the caller carries on, ignoring the chunk where
+// it cannot be used.
+func (d *decoder) malformed(msg string) error {
+	err := FormatError(msg)
+	if !d.lenient {
+		return err
+	}
+	d.warning(err)
+	return nil
+}
+
+// frameData returns the compressed data of f, reading it from d.ra if it
+// has not been loaded.
+func (d *decoder) frameData(f *rawFrame) ([]idat, error) {
+	if f.refs == nil {
+		return f.idats, nil
+	}
+	idats := make([]idat, 0, len(f.refs))
+	for i := range f.refs {
+		data, err := d.readRef(&f.refs[i])
+		if err != nil {
+			return nil, err
+		}
+		if f.refs[i].name == "fdAT" {
+			data = data[4:]
+		}
+		idats = append(idats, data)
+	}
+	return idats, nil
+}
+
+// decodeFrame decompresses f by wrapping its data in a standalone PNG
+// stream. The returned image's bounds are the frame region on the canvas.
+func (d *decoder) decodeFrame(f *rawFrame) (image.Image, error) {
+	idats, err := d.frameData(f)
+	if err != nil {
+		return nil, err
+	}
+
+	bb := new(bytes.Buffer)
+	e := encoder{w: bb}
+	_, e.err = io.WriteString(bb, pngHeader)
+
+	ihdr := make([]byte, len(d.ihdr))
+	copy(ihdr, d.ihdr)
+	writeUint32(ihdr[0:4], f.fc.width)
+	writeUint32(ihdr[4:8], f.fc.height)
+	e.writeChunk(ihdr, "IHDR")
+	if d.plte != nil {
+		e.writeChunk(d.plte, "PLTE")
+	}
+	if d.trns != nil {
+		e.writeChunk(d.trns, "tRNS")
+	}
+	for _, id := range idats {
+		e.writeChunk(id, "IDAT")
+	}
+	e.writeIEND()
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	img, err := png.Decode(bb)
+	if err != nil {
+		return nil, err
+	}
+	return translate(img, image.Pt(int(f.fc.xOffset), int(f.fc.yOffset))), nil
+}
+
+// decodeFramesConcurrently decodes every frame into imgs using n goroutines.
+// Frames are independent once their data is indexed, so they can be
+// decompressed in any order.
+func (d *decoder) decodeFramesConcurrently(imgs []image.Image, n int) error {
+	errs := make([]error, len(d.frames))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if errs[i] = d.ctx.Err(); errs[i] == nil {
+					imgs[i], errs[i] = d.decodeFrame(d.frames[i])
+				}
+			}
+		}()
+	}
+	for i := range d.frames {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// translate moves m by p, in place for the image types of the standard
+// library whose pixels are laid out linearly. Other images, such as
+// *image.YCbCr, whose subsampled chroma can't always follow a shifted
+// Rect, are wrapped, which keeps their color model.
+func translate(m image.Image, p image.Point) image.Image {
+	if p == (image.Point{}) {
+		return m
+	}
+	switch m := m.(type) {
+	case *image.Gray:
+		m.Rect = m.Rect.Add(p)
+	case *image.Gray16:
+		m.Rect = m.Rect.Add(p)
+	case *image.RGBA:
+		m.Rect = m.Rect.Add(p)
+	case *image.RGBA64:
+		m.Rect = m.Rect.Add(p)
+	case *image.NRGBA:
+		m.Rect = m.Rect.Add(p)
+	case *image.NRGBA64:
+		m.Rect = m.Rect.Add(p)
+	case *image.Paletted:
+		m.Rect = m.Rect.Add(p)
+	case *image.Alpha:
+		m.Rect = m.Rect.Add(p)
+	case *image.Alpha16:
+		m.Rect = m.Rect.Add(p)
+	case *image.CMYK:
+		m.Rect = m.Rect.Add(p)
+	case *translated:
+		return &translated{m.Image, m.p.Add(p)}
+	default:
+		return &translated{m, p}
+	}
+	return m
+}
+
+// translated is an image moved by p.
+type translated struct {
+	image.Image
+	p image.Point
+}
+
+func (m *translated) Bounds() image.Rectangle { return m.Image.Bounds().Add(m.p) }
+
+func (m *translated) At(x, y int) color.Color { return m.Image.At(x-m.p.X, y-m.p.Y) }
+
+func (m *translated) Opaque() bool { return opaque(m.Image) }
+
+func (m *translated) SubImage(r image.Rectangle) image.Image {
+	return translate(subImage(m.Image, r.Sub(m.p)), m.p)
+}
+
+// DecodeAll reads an APNG image from r and returns the sequential frames
+// and timing information. A default image that is not part of the animation
+// is skipped. A static PNG decodes as a single frame.
+func DecodeAll(r io.Reader) (*APNG, error) {
+	var dec Decoder
+	return dec.DecodeAll(r)
+}
+
+// DecodeAll reads an APNG image from r and returns the sequential frames
+// and timing information.
+func (dec *Decoder) DecodeAll(r io.Reader) (*APNG, error) {
+	return dec.DecodeAllContext(context.Background(), r)
+}
+
+// DecodeAllContext is like DecodeAll but stops with ctx.Err() once ctx is
+// done. Cancellation is checked between chunks and between frames.
+func DecodeAllContext(ctx context.Context, r io.Reader) (*APNG, error) {
+	var dec Decoder
+	return dec.DecodeAllContext(ctx, r)
+}
+
+// DecodeAllContext is like DecodeAll but stops with ctx.Err() once ctx is
+// done.
+func (dec *Decoder) DecodeAllContext(ctx context.Context, r io.Reader) (*APNG, error) {
+	d := dec.newDecoder(ctx)
+	if err := d.readChunks(r); err != nil {
+		return nil, err
+	}
+	return dec.decodeAll(d)
+}
+
+// DecodeAllAt is like DecodeAll but reads from ra with positioned reads,
+// such as an *os.File, instead of consuming a stream. Only the chunk headers
+// and control chunks are read up front; combined with Lazy, frame data is
+// read only for the frames that are used, in which case ra must remain
+// readable for as long as the frames are.
+func DecodeAllAt(ra io.ReaderAt) (*APNG, error) {
+	var dec Decoder
+	return dec.DecodeAllAt(ra)
+}
+
+// DecodeAllAt is like DecodeAll but reads from ra with positioned reads.
+func (dec *Decoder) DecodeAllAt(ra io.ReaderAt) (*APNG, error) {
+	d := dec.newDecoder(context.Background())
+	if err := d.readChunksAt(ra); err != nil {
+		return nil, err
+	}
+	return dec.decodeAll(d)
+}
+
+// decodeAll builds an APNG from the frames indexed by d.
+func (dec *Decoder) decodeAll(d *decoder) (*APNG, error) {
+	ctx := d.ctx
+	a := &APNG{
+		Images:    make([]image.Image, len(d.frames)),
+		Delays:    make([]uint16, len(d.frames)),
+		DelayDens: make([]uint16, len(d.frames)),
+		Disposals: make([]byte, len(d.frames)),
+		Blends:    make([]byte, len(d.frames)),
+		LoopCount: d.numPlays,
+		Config: image.Config{
+			Width:  d.width,
+			Height: d.height,
+		},
+		Background: d.background(),
+	}
+	switch {
+	case dec.Lazy:
+		for i, f := range d.frames {
+			a.Images[i] = &LazyImage{d: d, f: f, rect: f.fc.bounds()}
+		}
+	case dec.Concurrency > 1:
+		if err := d.decodeFramesConcurrently(a.Images, dec.Concurrency); err != nil {
+			return nil, err
+		}
+	default:
+		for i, f := range d.frames {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			img, err := d.decodeFrame(f)
+			if err != nil {
+				return nil, err
+			}
+			a.Images[i] = img
+		}
+	}
+	for i, f := range d.frames {
+		a.Delays[i] = f.fc.delayNum
+		a.DelayDens[i] = f.fc.delayDen
+		a.Disposals[i] = f.fc.disposeOp
+		a.Blends[i] = f.fc.blendOp
+	}
+	if len(a.Images) > 0 {
+		a.Config.ColorModel = a.Images[0].ColorModel()
+	}
+	return a, nil
+}
+
+// checkDepth returns an error unless the PNG spec allows the bit depth
+// with the color type, which the rest of the decoder relies on.
+func checkDepth(depth, colorType byte) error {
+	ok := false
+	switch colorType {
+	case ctGrayscale:
+		ok = depth == 1 || depth == 2 || depth == 4 || depth == 8 || depth == 16
+	case ctPaletted:
+		ok = depth == 1 || depth == 2 || depth == 4 || depth == 8
+	case ctTrueColor, ctGrayscaleAlpha, ctTrueColorAlpha:
+		ok = depth == 8 || depth == 16
+	default:
+		return FormatError("bad color type")
+	}
+	if !ok {
+		return FormatError("bad bit depth")
+	}
+	return nil
+}
+
+// background returns the color of the bKGD chunk, or nil if there is none
+// or it does not fit the image's color type.
+func (d *decoder) background() color.Color {
+	b := d.bkgd
+	depth, colorType := d.ihdr[8], d.ihdr[9]
+	// sample scales a depth-bit sample to 16 bits.
+	sample := func(b []byte) uint16 {
+		v := uint32(binary.BigEndian.Uint16(b))
+		if depth >= 16 {
+			return uint16(v)
+		}
+		return uint16(v * 0xffff / (1<<depth - 1))
+	}
+	switch colorType {
+	case 0, 4: // Grayscale, with or without alpha.
+		if len(b) == 2 {
+			return color.Gray16{sample(b)}
+		}
+	case 2, 6: // Truecolor, with or without alpha.
+		if len(b) == 6 {
+			return color.RGBA64{sample(b[0:2]), sample(b[2:4]), sample(b[4:6]), 0xffff}
+		}
+	case 3: // Indexed.
+		if len(b) == 1 && 3*int(b[0])+3 <= len(d.plte) {
+			p := d.plte[3*int(b[0]):]
+			return color.RGBA{p[0], p[1], p[2], 0xff}
+		}
+	}
+	return nil
+}
+
+// DecodeFrames reads an APNG image from r and calls fn with each frame as
+// it is displayed, that is, composited onto the canvas according to the
+// disposal and blend operations, together with the frame's delay. Only one
+// decoded frame is held in memory at a time. Decoding stops at the first
+// error returned by fn.
+func DecodeFrames(r io.Reader, fn func(i int, img *image.RGBA, delay time.Duration) error) error {
+	var dec Decoder
+	return dec.DecodeFrames(r, fn)
+}
+
+// DecodeFrames reads an APNG image from r and calls fn with each composited
+// frame and its delay.
+func (dec *Decoder) DecodeFrames(r io.Reader, fn func(i int, img *image.RGBA, delay time.Duration) error) error {
+	d := dec.newDecoder(context.Background())
+	if err := d.readChunks(r); err != nil {
+		return err
+	}
+
+	c := newCompositor(d.width, d.height)
+	var fd *frameDecoder
+	if dec.ReuseCanvas {
+		fd = newFrameDecoder(d)
+	}
+	for i, f := range d.frames {
+		var img image.Image
+		var err error
+		if fd != nil {
+			img, err = fd.decode(f)
+		} else {
+			img, err = d.decodeFrame(f)
+		}
+		if err != nil {
+			return err
+		}
+		out := c.render(img, f.fc.disposeOp, f.fc.blendOp)
+		if !dec.ReuseCanvas {
+			out = cloneRGBA(out)
+		}
+		if err := fn(i, out, f.fc.duration()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cloneRGBA(m *image.RGBA) *image.RGBA {
+	c := *m
+	c.Pix = make([]uint8, len(m.Pix))
+	copy(c.Pix, m.Pix)
+	return &c
+}
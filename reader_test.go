@@ -0,0 +1,61 @@
+package goapng
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// withIHDR returns the APNG file of a with the bit depth and color type of
+// its IHDR chunk replaced and a bKGD chunk added after it.
+func withIHDR(t *testing.T, a *APNG, depth, colorType byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := EncodeAll(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	cw := NewChunkWriter(&out)
+	for _, c := range readTestChunks(t, buf.Bytes()) {
+		if c.name == "IHDR" {
+			c.data[8], c.data[9] = depth, colorType
+		}
+		cw.WriteChunk(c.name, c.data)
+		if c.name == "IHDR" {
+			cw.WriteChunk("bKGD", []byte{0, 1, 0, 2, 0, 3})
+		}
+	}
+	return out.Bytes()
+}
+
+func TestDecodeBadIHDR(t *testing.T) {
+	tests := []struct {
+		depth, colorType byte
+	}{
+		{0, 2},
+		{0, 0},
+		{3, 0},
+		{4, 2},
+		{16, 3},
+		{8, 5},
+		{8, 7},
+	}
+	a := testAPNG(2, 4, 4)
+	for _, tt := range tests {
+		b := withIHDR(t, a, tt.depth, tt.colorType)
+		decoders := []struct {
+			name   string
+			decode func() error
+		}{
+			{"DecodeAll", func() error { _, err := DecodeAll(bytes.NewReader(b)); return err }},
+			{"DecodeAllAt", func() error { _, err := DecodeAllAt(bytes.NewReader(b)); return err }},
+			{"Lenient", func() error { _, err := (&Decoder{Lenient: true}).DecodeAll(bytes.NewReader(b)); return err }},
+		}
+		for _, d := range decoders {
+			var fe FormatError
+			if err := d.decode(); !errors.As(err, &fe) {
+				t.Errorf("depth %d, color type %d: %s returned %v, want a FormatError", tt.depth, tt.colorType, d.name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package goapng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// RemuxOptions configures Remux.
+type RemuxOptions struct {
+	// Delay, if non-nil, is called with the delay_num and delay_den of
+	// frame i and returns the ones to write instead.
+	Delay func(i int, num, den uint16) (uint16, uint16)
+
+	// LoopCount, if non-nil, replaces the loop count (num_plays).
+	LoopCount *uint32
+
+	// Drop, if non-nil, reports whether frame i is removed. Sequence
+	// numbers and num_frames are rewritten to match. Frames are removed
+	// as they are, without decoding, so frames that draw onto a removed
+	// frame may render differently. Dropping the first frame keeps its
+	// image as the default image shown by viewers without APNG support.
+	Drop func(i int) bool
+
+	// FoldDelays adds the delay of each dropped frame to the preceding
+	// kept frame, or to the following one for frames at the start.
+	FoldDelays bool
+
+	// Renumber rewrites the sequence numbers of the fcTL and fdAT chunks
+	// to count up from 0, as they are whenever frames are dropped.
+	Renumber bool
+
+	// FixNumFrames sets num_frames in the acTL chunk to the number of
+	// frames written, repairing files whose acTL disagrees with their fcTL
+	// chunks. The output is then held in memory until the end of the input,
+	// as acTL precedes the frames.
+	FixNumFrames bool
+}
+
+type remuxChunk struct {
+	name string
+	data []byte
+}
+
+type remuxer struct {
+	opts *RemuxOptions
+	e    encoder
+
+	seqNum     uint32
+	frameIndex int  // Index of the current input frame; -1 before the first fcTL.
+	dropping   bool // Whether the current frame is dropped.
+
+	// With FixNumFrames, the output is held in buf and the acTL chunk at
+	// acTLOff is rewritten once numFrames is known.
+	buf       *bytes.Buffer
+	acTLOff   int
+	numFrames int
+
+	// With FoldDelays, the chunks of the last kept frame, and any chunks
+	// that follow it, are held back so the delays of the frames dropped
+	// after it can still be added.
+	pending []remuxChunk
+	extra   time.Duration // Dropped delay to add to the next kept frame.
+}
+
+// Remux copies the APNG read from r to w, rewriting only the control
+// chunks selected by opts. Compressed frame data is copied verbatim, so
+// changing the speed or loop count of an animation costs little more than
+// copying the file.
+func Remux(w io.Writer, r io.Reader, opts *RemuxOptions) error {
+	if opts == nil {
+		opts = new(RemuxOptions)
+	}
+
+	cr := newChunkReader(r)
+	if err := cr.readSignature(); err != nil {
+		return err
+	}
+	m := &remuxer{
+		opts:       opts,
+		e:          encoder{w: w},
+		frameIndex: -1,
+		acTLOff:    -1,
+	}
+	if opts.FixNumFrames {
+		m.buf = new(bytes.Buffer)
+		m.e.w = m.buf
+	}
+	_, m.e.err = io.WriteString(m.e.w, pngHeader)
+
+	for m.e.err == nil {
+		name, data, err := cr.next()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		if err := m.chunk(name, data); err != nil {
+			return err
+		}
+		if name == "IEND" {
+			break
+		}
+	}
+	if m.e.err != nil || m.buf == nil {
+		return m.e.err
+	}
+	if m.acTLOff >= 0 {
+		b := m.buf.Bytes()[m.acTLOff:]
+		copy(b, acTLChunk(m.numFrames, binary.BigEndian.Uint32(b[12:16])))
+	}
+	_, err := m.buf.WriteTo(w)
+	return err
+}
+
+func (m *remuxer) chunk(name string, data []byte) error {
+	switch name {
+	case "acTL":
+		if len(data) != 8 {
+			return FormatError("bad acTL length")
+		}
+		if m.opts.LoopCount != nil {
+			if *m.opts.LoopCount > maxLoopCount {
+				return ErrLoopCount
+			}
+			writeUint32(data[4:8], *m.opts.LoopCount)
+		}
+		if m.opts.Drop != nil {
+			n := binary.BigEndian.Uint32(data[0:4])
+			kept := uint32(0)
+			for i := uint32(0); i < n; i++ {
+				if !m.opts.Drop(int(i)) {
+					kept++
+				}
+			}
+			writeUint32(data[0:4], kept)
+		}
+		if m.buf != nil {
+			m.acTLOff = m.buf.Len()
+		}
+		m.write(name, data)
+	case "fcTL":
+		if len(data) != 26 {
+			return FormatError("bad fcTL length")
+		}
+		m.frameIndex++
+		if m.opts.Delay != nil {
+			num, den := m.opts.Delay(m.frameIndex, binary.BigEndian.Uint16(data[20:22]), binary.BigEndian.Uint16(data[22:24]))
+			writeUint16(data[20:22], num)
+			writeUint16(data[22:24], den)
+		}
+		m.dropping = m.opts.Drop != nil && m.opts.Drop(m.frameIndex)
+
+		if m.dropping {
+			if m.opts.FoldDelays {
+				fc, _ := parsefcTL(data)
+				if len(m.pending) > 0 {
+					addDelay(m.pending[0].data, fc.duration())
+				} else {
+					m.extra += fc.duration()
+				}
+			}
+			return nil
+		}
+		m.flush()
+		if m.extra > 0 {
+			addDelay(data, m.extra)
+			m.extra = 0
+		}
+		m.frame(name, data)
+	case "IDAT":
+		if m.frameIndex == 0 && !m.dropping {
+			m.frame(name, data)
+		} else {
+			m.write(name, data)
+		}
+	case "fdAT":
+		if !m.dropping {
+			m.frame(name, data)
+		}
+	default:
+		if len(m.pending) > 0 && name != "IEND" {
+			m.pending = append(m.pending, remuxChunk{name, data})
+			return nil
+		}
+		m.flush()
+		m.write(name, data)
+	}
+	return nil
+}
+
+// frame writes a chunk of a kept frame, or holds it back with FoldDelays.
+func (m *remuxer) frame(name string, data []byte) {
+	if m.opts.FoldDelays {
+		m.pending = append(m.pending, remuxChunk{name, data})
+		return
+	}
+	m.write(name, data)
+}
+
+func (m *remuxer) flush() {
+	for _, c := range m.pending {
+		m.write(c.name, c.data)
+	}
+	m.pending = m.pending[:0]
+}
+
+// write writes a chunk, renumbering it if frames are being dropped.
+func (m *remuxer) write(name string, data []byte) {
+	if (m.opts.Drop != nil || m.opts.Renumber) && (name == "fcTL" || name == "fdAT") && len(data) >= 4 {
+		writeUint32(data[0:4], m.seqNum)
+		m.seqNum++
+	}
+	if name == "fcTL" {
+		m.numFrames++
+	}
+	m.e.writeChunk(data, name)
+}
+
+// addDelay adds d to the delay in the fcTL data, keeping its denominator.
+func addDelay(fctl []byte, d time.Duration) {
+	num := time.Duration(binary.BigEndian.Uint16(fctl[20:22]))
+	den := time.Duration(binary.BigEndian.Uint16(fctl[22:24]))
+	if den == 0 {
+		den = 100
+		writeUint16(fctl[22:24], 100)
+	}
+	num += (d*den + time.Second/2) / time.Second
+	if num > 0xffff {
+		num = 0xffff
+	}
+	writeUint16(fctl[20:22], uint16(num))
+}
+
+// TrimStream copies the APNG read from r to w without frames from through
+// to-1. It is the streaming counterpart of Trim: no pixel data is decoded,
+// so frames that drew onto the removed ones may render differently. The
+// range is checked as Trim checks it, against the frame count of the acTL
+// chunk.
+func TrimStream(w io.Writer, r io.Reader, from, to int, foldDelays bool) error {
+	// acTL precedes the frames, so only the head of the stream is held
+	// while the range is checked.
+	var head bytes.Buffer
+	n, err := CountFrames(io.TeeReader(r, &head))
+	if err != nil {
+		return err
+	}
+	if err := checkTrim(from, to, n); err != nil {
+		return err
+	}
+	return Remux(w, io.MultiReader(&head, r), &RemuxOptions{
+		Drop: func(i int) bool {
+			return from <= i && i < to
+		},
+		FoldDelays: foldDelays,
+	})
+}
+
+// RepairSequenceNumbers copies the APNG read from r to w with the sequence
+// numbers of its fcTL and fdAT chunks rewritten to count up from 0, in the
+// order the chunks appear. Browsers refuse to play animations with gaps or
+// repeats in the sequence; this fixes files that are otherwise intact.
+func RepairSequenceNumbers(w io.Writer, r io.Reader) error {
+	return Remux(w, r, &RemuxOptions{Renumber: true})
+}
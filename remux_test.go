@@ -0,0 +1,89 @@
+package goapng
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRemux(t *testing.T) {
+	a := testAPNG(4, 6, 6)
+	a.LoopCount = 3
+	var buf bytes.Buffer
+	if err := EncodeAll(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+	file := buf.Bytes()
+	ms := func(ds ...int) []time.Duration {
+		var out []time.Duration
+		for _, d := range ds {
+			out = append(out, time.Duration(d)*time.Millisecond)
+		}
+		return out
+	}
+	five := uint32(5)
+	// A tEXt chunk between each pair of frames.
+	fcTLs := 0
+	var texts bytes.Buffer
+	cw := NewChunkWriter(&texts)
+	for _, c := range readTestChunks(t, file) {
+		if c.name == "fcTL" {
+			if fcTLs > 0 {
+				cw.WriteChunk("tEXt", []byte(fmt.Sprintf("Comment\x00before frame %d", fcTLs)))
+			}
+			fcTLs++
+		}
+		cw.WriteChunk(c.name, c.data)
+	}
+
+	tests := []struct {
+		name      string
+		in        []byte
+		opts      *RemuxOptions
+		wantDurs  []time.Duration
+		wantLoops uint32
+	}{
+		{"no options", file, nil, ms(100, 100, 100, 100), 3},
+		{"delay", file, &RemuxOptions{Delay: func(i int, num, den uint16) (uint16, uint16) {
+			return num * uint16(i+1), den
+		}}, ms(100, 200, 300, 400), 3},
+		{"loop count", file, &RemuxOptions{LoopCount: &five}, ms(100, 100, 100, 100), 5},
+		{"drop", file, &RemuxOptions{Drop: func(i int) bool { return i == 2 }}, ms(100, 100, 100), 3},
+		{"drop and fold", file, &RemuxOptions{Drop: func(i int) bool { return i == 1 || i == 2 }, FoldDelays: true}, ms(300, 100), 3},
+		{"drop the first and fold", file, &RemuxOptions{Drop: func(i int) bool { return i == 0 }, FoldDelays: true}, ms(200, 100, 100), 3},
+		{"drop and fold, with tEXt", texts.Bytes(), &RemuxOptions{Drop: func(i int) bool { return i == 1 || i == 2 }, FoldDelays: true}, ms(300, 100), 3},
+		{"drop the last and fold, with tEXt", texts.Bytes(), &RemuxOptions{Drop: func(i int) bool { return i == 3 }, FoldDelays: true}, ms(100, 100, 200), 3},
+		{"renumber", bumpSequence(t, file), &RemuxOptions{Renumber: true}, ms(100, 100, 100, 100), 3},
+		{"fix num_frames", setNumFrames(t, file, 9), &RemuxOptions{FixNumFrames: true}, ms(100, 100, 100, 100), 3},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := Remux(&out, bytes.NewReader(tt.in), tt.opts); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if tt.opts == nil && !bytes.Equal(out.Bytes(), file) {
+			t.Errorf("%s: output differs from the input", tt.name)
+		}
+		report, err := VerifyStream(bytes.NewReader(out.Bytes()))
+		if err != nil || !report.OK() {
+			t.Errorf("%s: VerifyStream: %v, %v", tt.name, report.Problems, err)
+		}
+		b, err := DecodeAll(&out)
+		if err != nil {
+			t.Errorf("%s: decoding the result: %v", tt.name, err)
+			continue
+		}
+		var durs []time.Duration
+		for i := range b.Images {
+			durs = append(durs, b.delay(i))
+		}
+		if fmt.Sprint(durs) != fmt.Sprint(tt.wantDurs) {
+			t.Errorf("%s: got delays %v, want %v", tt.name, durs, tt.wantDurs)
+		}
+		if b.LoopCount != tt.wantLoops {
+			t.Errorf("%s: loop count %d, want %d", tt.name, b.LoopCount, tt.wantLoops)
+		}
+	}
+}
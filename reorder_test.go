@@ -0,0 +1,39 @@
+package goapng
+
+import (
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestReorder(t *testing.T) {
+	a := testAPNG(4, 6, 6)
+	a.Images[1] = a.Images[1].(*image.NRGBA).SubImage(image.Rect(1, 1, 4, 4))
+	a.Images[3] = a.Images[3].(*image.NRGBA).SubImage(image.Rect(0, 2, 6, 5))
+	for _, order := range [][]int{{0, 1, 2, 3}, {3, 2, 1, 0}, {1, 1, 3}, {2, 3, 0, 1}} {
+		b := copyAPNG(a)
+		if err := Reorder(b, order); err != nil {
+			t.Fatal(err)
+		}
+		checkShows(t, b, a, order)
+	}
+
+	if err := Reorder(copyAPNG(a), []int{0, 4}); !errors.Is(err, ErrFrameIndex) {
+		t.Errorf("got %v, want ErrFrameIndex", err)
+	}
+	if err := Reorder(copyAPNG(a), nil); !errors.Is(err, ErrNoFrames) {
+		t.Errorf("got %v, want ErrNoFrames", err)
+	}
+}
+
+func TestReorderYCbCr(t *testing.T) {
+	// Frame 1 still follows frame 0 and stays as it is, next to frames
+	// replaced by canvases that can't be YCbCr.
+	a := ycbcrAPNG(3, 8, 8)
+	a.Images[1] = a.Images[1].(*image.YCbCr).SubImage(image.Rect(2, 2, 6, 6))
+	b := copyAPNG(a)
+	if err := Reorder(b, []int{2, 0, 1}); err != nil {
+		t.Fatal(err)
+	}
+	checkShows(t, b, a, []int{2, 0, 1})
+}
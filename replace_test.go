@@ -0,0 +1,103 @@
+package goapng
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestReplaceFrameStream(t *testing.T) {
+	a := testAPNG(4, 16, 8)
+	def := solid(16, 8, color.NRGBA{9, 9, 9, 0xff})
+	var plain bytes.Buffer
+	if err := EncodeAll(&plain, a); err != nil {
+		t.Fatal(err)
+	}
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"default image is frame 0", plain.Bytes()},
+		{"separate default image", separateDefault(t, a, def)},
+	}
+	repl := solid(16, 8, color.NRGBA{1, 2, 3, 0xff})
+
+	for _, f := range files {
+		for n := range a.Images {
+			var out bytes.Buffer
+			if err := ReplaceFrameStream(&out, bytes.NewReader(f.data), n, repl); err != nil {
+				t.Errorf("%s, frame %d: %v", f.name, n, err)
+				continue
+			}
+			got, err := DecodeAll(bytes.NewReader(out.Bytes()))
+			if err != nil {
+				t.Errorf("%s, frame %d: decoding the result: %v", f.name, n, err)
+				continue
+			}
+			want := *a
+			want.Images = append([]image.Image(nil), a.Images...)
+			want.Images[n] = repl
+			if ok, diffs := EqualFrames(got, &want, 0); !ok {
+				t.Errorf("%s, frame %d: frames differ: %v", f.name, n, diffs)
+			}
+		}
+	}
+
+	for _, n := range []int{-1, len(a.Images)} {
+		if err := ReplaceFrameStream(new(bytes.Buffer), bytes.NewReader(plain.Bytes()), n, repl); err != ErrFrameIndex {
+			t.Errorf("frame %d: got error %v, want ErrFrameIndex", n, err)
+		}
+	}
+}
+
+func TestReplaceFrameColorModel(t *testing.T) {
+	pal := color.Palette{color.NRGBA{0xff, 0, 0, 0xff}, color.NRGBA{0, 0xff, 0, 0xff}}
+	paletted := testAPNG(3, 8, 8)
+	for i := range paletted.Images {
+		m := image.NewPaletted(image.Rect(0, 0, 8, 8), pal)
+		for j := range m.Pix {
+			m.Pix[j] = uint8(i % 2)
+		}
+		paletted.Images[i] = m
+	}
+	tests := []struct {
+		name string
+		a    *APNG
+	}{
+		{"YCbCr", ycbcrAPNG(3, 8, 8)},
+		// The replacement's color is not in the palette.
+		{"Paletted", paletted},
+	}
+	repl := solid(8, 8, color.NRGBA{1, 2, 3, 0xff})
+	for _, tt := range tests {
+		for n := range tt.a.Images {
+			b := copyAPNG(tt.a)
+			if err := ReplaceFrame(b, n, repl); err != nil {
+				t.Fatal(err)
+			}
+			if !isSameColorModel(b.Images) {
+				t.Errorf("%s, frame %d: frames have different color models", tt.name, n)
+				continue
+			}
+			var buf bytes.Buffer
+			if err := EncodeAll(&buf, b); err != nil {
+				t.Errorf("%s, frame %d: %v", tt.name, n, err)
+				continue
+			}
+			got, err := DecodeAll(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, m := range got.Images {
+				want := tt.a.Images[i]
+				if i == n {
+					want = repl
+				}
+				if !samePixels(toRGBA(m, m.Bounds()), toRGBA(want, want.Bounds())) {
+					t.Errorf("%s, frame %d: frame %d differs", tt.name, n, i)
+				}
+			}
+		}
+	}
+}
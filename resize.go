@@ -0,0 +1,225 @@
+package goapng
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"math"
+)
+
+// A Filter selects the interpolation kernel used by Resize.
+type Filter int
+
+const (
+	NearestNeighbor Filter = iota // Fastest, blocky.
+	Bilinear                      // Smooth, slightly blurry.
+	CatmullRom                    // Sharp cubic; the slowest.
+)
+
+// support returns the radius of the kernel of f, in source pixels.
+func (f Filter) support() float64 {
+	switch f {
+	case Bilinear:
+		return 1
+	case CatmullRom:
+		return 2
+	}
+	return 0
+}
+
+func (f Filter) at(t float64) float64 {
+	t = math.Abs(t)
+	switch f {
+	case Bilinear:
+		if t < 1 {
+			return 1 - t
+		}
+	case CatmullRom:
+		if t < 1 {
+			return (3*t-5)*t*t/2 + 1
+		}
+		if t < 2 {
+			return ((5-t)*t-8)*t/2 + 2
+		}
+	}
+	return 0
+}
+
+// contrib lists the source pixels, starting at start, and their weights
+// that make up one destination pixel.
+type contrib struct {
+	start   int
+	weights []float64
+}
+
+// contribs computes the contributions for the destination pixels dmin
+// through dmax-1, scaled by scale from source pixels smin through smax-1.
+func (f Filter) contribs(dmin, dmax, smin, smax int, scale float64) []contrib {
+	out := make([]contrib, dmax-dmin)
+	support, kscale := f.support(), 1.0
+	if scale < 1 {
+		// Widen the kernel when shrinking, so that every source pixel
+		// contributes.
+		support /= scale
+		kscale = scale
+	}
+
+	for x := dmin; x < dmax; x++ {
+		center := (float64(x)+0.5)/scale - 0.5
+		nearest := clampInt(int(math.Floor(center+0.5)), smin, smax-1)
+		lo := clampInt(int(math.Ceil(center-support)), smin, smax-1)
+		hi := clampInt(int(math.Floor(center+support)), smin, smax-1)
+		if support == 0 || hi < lo {
+			out[x-dmin] = contrib{nearest, []float64{1}}
+			continue
+		}
+
+		ws := make([]float64, hi-lo+1)
+		sum := 0.0
+		for i := range ws {
+			ws[i] = f.at((float64(lo+i) - center) * kscale)
+			sum += ws[i]
+		}
+		if sum == 0 {
+			lo, ws = nearest, []float64{1}
+		} else {
+			for i := range ws {
+				ws[i] /= sum
+			}
+		}
+		out[x-dmin] = contrib{lo, ws}
+	}
+	return out
+}
+
+// scaleRect maps r from the source to the destination canvas. Edges are
+// mapped independently so that adjacent regions stay adjacent.
+func scaleRect(r image.Rectangle, sx, sy float64, canvas image.Rectangle) image.Rectangle {
+	s := image.Rect(
+		int(math.Floor(float64(r.Min.X)*sx+0.5)),
+		int(math.Floor(float64(r.Min.Y)*sy+0.5)),
+		int(math.Floor(float64(r.Max.X)*sx+0.5)),
+		int(math.Floor(float64(r.Max.Y)*sy+0.5)),
+	)
+	// Keep at least one pixel of every frame.
+	s.Min.X = clampInt(s.Min.X, canvas.Min.X, canvas.Max.X-1)
+	s.Min.Y = clampInt(s.Min.Y, canvas.Min.Y, canvas.Max.Y-1)
+	if s.Max.X <= s.Min.X {
+		s.Max.X = s.Min.X + 1
+	}
+	if s.Max.Y <= s.Min.Y {
+		s.Max.Y = s.Min.Y + 1
+	}
+	return s.Intersect(canvas)
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// resample scales m, a frame on the source canvas, by sx and sy into the
+// region dr of the destination canvas.
+func resample(m image.Image, dr image.Rectangle, sx, sy float64, f Filter) *image.RGBA64 {
+	sr := m.Bounds()
+	cx := f.contribs(dr.Min.X, dr.Max.X, sr.Min.X, sr.Max.X, sx)
+	cy := f.contribs(dr.Min.Y, dr.Max.Y, sr.Min.Y, sr.Max.Y, sy)
+
+	// Horizontal pass over the source rows, in premultiplied color.
+	tmp := make([][4]float64, sr.Dy()*dr.Dx())
+	row := make([][4]float64, sr.Dx())
+	for y := sr.Min.Y; y < sr.Max.Y; y++ {
+		for x := sr.Min.X; x < sr.Max.X; x++ {
+			r, g, b, a := m.At(x, y).RGBA()
+			row[x-sr.Min.X] = [4]float64{float64(r), float64(g), float64(b), float64(a)}
+		}
+		t := tmp[(y-sr.Min.Y)*dr.Dx():]
+		for i, c := range cx {
+			var p [4]float64
+			for j, w := range c.weights {
+				s := &row[c.start+j-sr.Min.X]
+				p[0] += s[0] * w
+				p[1] += s[1] * w
+				p[2] += s[2] * w
+				p[3] += s[3] * w
+			}
+			t[i] = p
+		}
+	}
+
+	// Vertical pass into the destination.
+	dst := image.NewRGBA64(dr)
+	for j, c := range cy {
+		for i := 0; i < dr.Dx(); i++ {
+			var p [4]float64
+			for k, w := range c.weights {
+				s := &tmp[(c.start+k-sr.Min.Y)*dr.Dx()+i]
+				p[0] += s[0] * w
+				p[1] += s[1] * w
+				p[2] += s[2] * w
+				p[3] += s[3] * w
+			}
+			a := clamp16(p[3], 0xffff)
+			dst.SetRGBA64(dr.Min.X+i, dr.Min.Y+j, color.RGBA64{
+				R: clamp16(p[0], float64(a)),
+				G: clamp16(p[1], float64(a)),
+				B: clamp16(p[2], float64(a)),
+				A: a,
+			})
+		}
+	}
+	return dst
+}
+
+// clamp16 rounds v into the range [0, max].
+func clamp16(v, max float64) uint16 {
+	if v < 0 {
+		return 0
+	}
+	if v > max {
+		return uint16(max)
+	}
+	return uint16(v + 0.5)
+}
+
+// Resize scales the canvas of a to width x height, resampling every frame
+// with the interpolation kernel f. The frames keep their image type unless
+// it cannot hold the resampled colors, as when they fall between the
+// colors of a palette; they are then converted to *image.NRGBA, or
+// *image.NRGBA64 for 16-bit frames.
+func Resize(a *APNG, width, height int, f Filter) error {
+	if len(a.Images) == 0 {
+		return ErrNoFrames
+	}
+	if width <= 0 || height <= 0 {
+		return errors.New("apng: invalid size")
+	}
+
+	b := a.bounds()
+	sx := float64(width) / float64(b.Dx())
+	sy := float64(height) / float64(b.Dy())
+	canvas := image.Rect(0, 0, width, height)
+	ms := make([]image.Image, len(a.Images))
+	for i, img := range a.Images {
+		dr := canvas
+		if i > 0 {
+			dr = scaleRect(img.Bounds(), sx, sy, canvas)
+		}
+		ms[i] = resample(img, dr, sx, sy, f)
+	}
+	imgs, ok := convertExact(a.Images[0], ms)
+	if !ok {
+		wide := wideLike(a.Images[0])
+		for i, m := range ms {
+			imgs[i] = convertLike(wide, m)
+		}
+	}
+	a.Images = imgs
+	a.Config.Width, a.Config.Height = width, height
+	return nil
+}
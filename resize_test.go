@@ -0,0 +1,86 @@
+package goapng
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestResize(t *testing.T) {
+	a := testAPNG(3, 8, 4)
+	a.Images[1] = a.Images[1].(*image.NRGBA).SubImage(image.Rect(2, 2, 6, 4))
+	for _, f := range []Filter{NearestNeighbor, Bilinear, CatmullRom} {
+		b := copyAPNG(a)
+		if err := Resize(b, 16, 12, f); err != nil {
+			t.Fatal(err)
+		}
+		if b.Config.Width != 16 || b.Config.Height != 12 {
+			t.Errorf("filter %d: config %dx%d, want 16x12", f, b.Config.Width, b.Config.Height)
+		}
+		for i, want := range []image.Rectangle{image.Rect(0, 0, 16, 12), image.Rect(4, 6, 12, 12), image.Rect(0, 0, 16, 12)} {
+			if got := b.Images[i].Bounds(); got != want {
+				t.Errorf("filter %d, frame %d: bounds %v, want %v", f, i, got, want)
+			}
+		}
+		if err := Validate(b); err != nil {
+			t.Errorf("filter %d: %v", f, err)
+		}
+		// Solid frames stay solid.
+		if c := b.Images[2].At(7, 5); c != a.Images[2].At(0, 0) {
+			t.Errorf("filter %d: got %v, want %v", f, c, a.Images[2].At(0, 0))
+		}
+	}
+
+	if err := Resize(copyAPNG(a), 0, 4, Bilinear); err == nil {
+		t.Error("resizing to zero width: got no error")
+	}
+	if err := Resize(&APNG{}, 4, 4, Bilinear); !errors.Is(err, ErrNoFrames) {
+		t.Errorf("got %v, want ErrNoFrames", err)
+	}
+}
+
+func TestResizeColorModel(t *testing.T) {
+	// Two stripes, red and green, from a palette that holds neither of
+	// the colors between them.
+	pal := color.Palette{color.NRGBA{0xff, 0, 0, 0xff}, color.NRGBA{0, 0xff, 0, 0xff}}
+	stripes := func() image.Image {
+		m := image.NewPaletted(image.Rect(0, 0, 4, 4), pal)
+		for i := range m.Pix {
+			m.Pix[i] = uint8(i % 4 / 2)
+		}
+		return m
+	}
+	gray := func() image.Image {
+		m := image.NewGray(image.Rect(0, 0, 4, 4))
+		for i := range m.Pix {
+			m.Pix[i] = uint8(i * 16)
+		}
+		return m
+	}
+	tests := []struct {
+		name  string
+		frame func() image.Image
+		f     Filter
+		want  color.Model
+	}{
+		{"Paletted, nearest", stripes, NearestNeighbor, pal},
+		{"Paletted, bilinear", stripes, Bilinear, color.NRGBAModel},
+		{"Gray, bilinear", gray, Bilinear, color.GrayModel},
+		{"YCbCr, bilinear", func() image.Image { return ycbcrAPNG(1, 4, 4).Images[0] }, Bilinear, color.NRGBAModel},
+	}
+	for _, tt := range tests {
+		a := &APNG{Images: []image.Image{tt.frame(), tt.frame()}, Delays: []uint16{1, 1}}
+		if err := Resize(a, 7, 7, tt.f); err != nil {
+			t.Fatal(err)
+		}
+		for i, m := range a.Images {
+			if !equalColorModel(m.ColorModel(), tt.want) {
+				t.Errorf("%s: frame %d has color model %T, want %T", tt.name, i, m.ColorModel(), tt.want)
+			}
+		}
+		if err := Validate(a); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+}
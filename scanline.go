@@ -0,0 +1,438 @@
+package goapng
+
+import (
+	"compress/zlib"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+)
+
+// PNG color types.
+const (
+	ctGrayscale      = 0
+	ctTrueColor      = 2
+	ctPaletted       = 3
+	ctGrayscaleAlpha = 4
+	ctTrueColorAlpha = 6
+)
+
+// scanlineFormat is the pixel layout of the frames of an animation, chosen
+// once from the first frame so that every frame shares the IHDR.
+type scanlineFormat struct {
+	colorType byte
+	depth     byte
+	bpp       int // Bytes per complete pixel, rounded up to one.
+	palette   color.Palette
+	interlace bool // Whether the rows are stored in the seven passes of Adam7.
+}
+
+// adam7 holds the column and row offsets and steps of the passes of Adam7
+// interlacing.
+var adam7 = [7]struct{ xOff, yOff, xStep, yStep int }{
+	{0, 0, 8, 8},
+	{4, 0, 8, 8},
+	{0, 4, 4, 8},
+	{2, 0, 4, 4},
+	{0, 2, 2, 4},
+	{1, 0, 2, 2},
+	{0, 1, 1, 2},
+}
+
+// adam7Size returns the size of pass p of a width x height image, which
+// is empty for passes that take no pixels from small images.
+func adam7Size(width, height, p int) (int, int) {
+	a := adam7[p]
+	return (width - a.xOff + a.xStep - 1) / a.xStep, (height - a.yOff + a.yStep - 1) / a.yStep
+}
+
+// adam7Pass returns the pixels of m taken by pass p of Adam7 interlacing,
+// as an image of the same kind, or nil if the pass is empty.
+func adam7Pass(m image.Image, p int) image.Image {
+	b := m.Bounds()
+	w, h := adam7Size(b.Dx(), b.Dy(), p)
+	if w <= 0 || h <= 0 {
+		return nil
+	}
+	a := adam7[p]
+	r := image.Rect(0, 0, w, h)
+	if pm, ok := m.(*image.Paletted); ok {
+		// Copy the indices, as looking colors up again would not survive
+		// a palette with repeated colors.
+		dst := image.NewPaletted(r, pm.Palette)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				dst.SetColorIndex(x, y, pm.ColorIndexAt(b.Min.X+a.xOff+x*a.xStep, b.Min.Y+a.yOff+y*a.yStep))
+			}
+		}
+		return dst
+	}
+	dst := newLike(m, r)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dst.Set(x, y, m.At(b.Min.X+a.xOff+x*a.xStep, b.Min.Y+a.yOff+y*a.yStep))
+		}
+	}
+	return dst
+}
+
+// opaque reports whether every pixel of m is fully opaque.
+func opaque(m image.Image) bool {
+	if o, ok := m.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	b := m.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if _, _, _, a := m.At(x, y).RGBA(); a != 0xffff {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// mixedOpacity reports whether image/png would choose different color types
+// for the frames in imgs, which happens when it drops the alpha channel of
+// only some of them because they are opaque.
+func mixedOpacity(imgs []image.Image) bool {
+	if _, ok := imgs[0].ColorModel().(color.Palette); ok {
+		return false
+	}
+	switch imgs[0].ColorModel() {
+	case color.GrayModel, color.Gray16Model:
+		return false
+	}
+
+	first := opaque(imgs[0])
+	for _, m := range imgs[1:] {
+		if opaque(m) != first {
+			return true
+		}
+	}
+	return false
+}
+
+// chooseFormat picks the scanline format for the frames in imgs, all of
+// which share the color model of imgs[0].
+func chooseFormat(imgs []image.Image) scanlineFormat {
+	switch m := imgs[0].(type) {
+	case *image.Paletted:
+		if len(m.Palette) <= 256 {
+			return scanlineFormat{colorType: ctPaletted, depth: 8, bpp: 1, palette: m.Palette}
+		}
+	case *image.Gray:
+		return scanlineFormat{colorType: ctGrayscale, depth: 8, bpp: 1}
+	case *image.Gray16:
+		return scanlineFormat{colorType: ctGrayscale, depth: 16, bpp: 2}
+	}
+
+	allOpaque := true
+	for _, m := range imgs {
+		if !opaque(m) {
+			allOpaque = false
+			break
+		}
+	}
+
+	switch imgs[0].ColorModel() {
+	case color.RGBA64Model, color.NRGBA64Model:
+		if allOpaque {
+			return scanlineFormat{colorType: ctTrueColor, depth: 16, bpp: 6}
+		}
+		return scanlineFormat{colorType: ctTrueColorAlpha, depth: 16, bpp: 8}
+	}
+	if allOpaque {
+		return scanlineFormat{colorType: ctTrueColor, depth: 8, bpp: 3}
+	}
+	return scanlineFormat{colorType: ctTrueColorAlpha, depth: 8, bpp: 4}
+}
+
+func (f *scanlineFormat) ihdr(b image.Rectangle) []byte {
+	ihdr := make([]byte, 13)
+	writeUint32(ihdr[0:4], uint32(b.Dx()))
+	writeUint32(ihdr[4:8], uint32(b.Dy()))
+	ihdr[8] = f.depth
+	ihdr[9] = f.colorType
+	if f.interlace {
+		ihdr[12] = 1
+	}
+	return ihdr
+}
+
+// formatFromIHDR returns the scanline format of an existing image, so that
+// new frames can be encoded to match it.
+func formatFromIHDR(ihdr, plte, trns []byte) (scanlineFormat, error) {
+	if len(ihdr) != 13 {
+		return scanlineFormat{}, FormatError("bad IHDR length")
+	}
+	if ihdr[12] > 1 {
+		return scanlineFormat{}, FormatError("bad interlace method")
+	}
+
+	f := scanlineFormat{colorType: ihdr[9], depth: ihdr[8], interlace: ihdr[12] == 1}
+	channels := 0
+	switch f.colorType {
+	case ctGrayscale:
+		channels = 1
+	case ctTrueColor:
+		channels = 3
+	case ctGrayscaleAlpha:
+		channels = 2
+	case ctTrueColorAlpha:
+		channels = 4
+	case ctPaletted:
+		if f.depth != 1 && f.depth != 2 && f.depth != 4 && f.depth != 8 {
+			return scanlineFormat{}, FormatError("bad bit depth")
+		}
+		if len(plte)%3 != 0 || len(plte) == 0 {
+			return scanlineFormat{}, FormatError("bad PLTE length")
+		}
+		f.bpp = 1
+		f.palette = make(color.Palette, len(plte)/3)
+		for i := range f.palette {
+			a := uint8(0xff)
+			if i < len(trns) {
+				a = trns[i]
+			}
+			f.palette[i] = color.NRGBA{plte[3*i], plte[3*i+1], plte[3*i+2], a}
+		}
+		return f, nil
+	default:
+		return scanlineFormat{}, FormatError("bad color type")
+	}
+	if f.depth != 8 && f.depth != 16 {
+		return scanlineFormat{}, UnsupportedError("bit depth")
+	}
+	f.bpp = channels * int(f.depth) / 8
+	return f, nil
+}
+
+// plteAndtRNS returns the PLTE and tRNS chunk data of a paletted format.
+func (f *scanlineFormat) plteAndtRNS() (plte, trns []byte) {
+	if f.colorType != ctPaletted {
+		return nil, nil
+	}
+	plte = make([]byte, 0, 3*len(f.palette))
+	alpha := make([]byte, len(f.palette))
+	last := -1
+	for i, c := range f.palette {
+		nc := color.NRGBAModel.Convert(c).(color.NRGBA)
+		plte = append(plte, nc.R, nc.G, nc.B)
+		alpha[i] = nc.A
+		if nc.A != 0xff {
+			last = i
+		}
+	}
+	if last >= 0 {
+		trns = alpha[:last+1]
+	}
+	return plte, trns
+}
+
+// writeRow converts row y of m into dst in format f.
+func (f *scanlineFormat) writeRow(dst []byte, m image.Image, y int) {
+	b := m.Bounds()
+	switch f.colorType {
+	case ctPaletted:
+		if p, ok := m.(*image.Paletted); ok && f.depth == 8 {
+			copy(dst, p.Pix[p.PixOffset(b.Min.X, y):])
+			return
+		}
+		if f.depth < 8 {
+			for i := range dst {
+				dst[i] = 0
+			}
+		}
+		perByte := 8 / int(f.depth)
+		for i, x := 0, b.Min.X; x < b.Max.X; i, x = i+1, x+1 {
+			var ci uint8
+			if p, ok := m.(*image.Paletted); ok {
+				ci = p.ColorIndexAt(x, y)
+			} else {
+				ci = uint8(f.palette.Index(m.At(x, y)))
+			}
+			if f.depth == 8 {
+				dst[i] = ci
+			} else {
+				shift := uint(8 - int(f.depth)*(i%perByte+1))
+				dst[i/perByte] |= ci << shift
+			}
+		}
+		return
+	case ctGrayscale:
+		switch m := m.(type) {
+		case *image.Gray:
+			if f.depth == 8 {
+				copy(dst, m.Pix[m.PixOffset(b.Min.X, y):])
+				return
+			}
+		case *image.Gray16:
+			if f.depth == 16 {
+				copy(dst, m.Pix[m.PixOffset(b.Min.X, y):])
+				return
+			}
+		}
+		i := 0
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if f.depth == 16 {
+				c := color.Gray16Model.Convert(m.At(x, y)).(color.Gray16)
+				dst[i], dst[i+1] = uint8(c.Y>>8), uint8(c.Y)
+				i += 2
+			} else {
+				dst[i] = color.GrayModel.Convert(m.At(x, y)).(color.Gray).Y
+				i++
+			}
+		}
+		return
+	case ctGrayscaleAlpha:
+		i := 0
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBA64Model.Convert(m.At(x, y)).(color.NRGBA64)
+			g := color.Gray16Model.Convert(color.NRGBA64{c.R, c.G, c.B, 0xffff}).(color.Gray16).Y
+			if f.depth == 16 {
+				dst[i+0], dst[i+1] = uint8(g>>8), uint8(g)
+				dst[i+2], dst[i+3] = uint8(c.A>>8), uint8(c.A)
+				i += 4
+			} else {
+				dst[i+0], dst[i+1] = uint8(g>>8), uint8(c.A>>8)
+				i += 2
+			}
+		}
+		return
+	}
+
+	alpha := f.colorType == ctTrueColorAlpha
+	if f.depth == 16 {
+		i := 0
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBA64Model.Convert(m.At(x, y)).(color.NRGBA64)
+			dst[i+0], dst[i+1] = uint8(c.R>>8), uint8(c.R)
+			dst[i+2], dst[i+3] = uint8(c.G>>8), uint8(c.G)
+			dst[i+4], dst[i+5] = uint8(c.B>>8), uint8(c.B)
+			i += 6
+			if alpha {
+				dst[i+0], dst[i+1] = uint8(c.A>>8), uint8(c.A)
+				i += 2
+			}
+		}
+		return
+	}
+
+	if n, ok := m.(*image.NRGBA); ok && alpha {
+		copy(dst, n.Pix[n.PixOffset(b.Min.X, y):])
+		return
+	}
+	i := 0
+	for x := b.Min.X; x < b.Max.X; x++ {
+		var c color.NRGBA
+		switch m := m.(type) {
+		case *image.NRGBA:
+			j := m.PixOffset(x, y)
+			c = color.NRGBA{m.Pix[j], m.Pix[j+1], m.Pix[j+2], m.Pix[j+3]}
+		default:
+			c = color.NRGBAModel.Convert(m.At(x, y)).(color.NRGBA)
+		}
+		dst[i+0], dst[i+1], dst[i+2] = c.R, c.G, c.B
+		i += 3
+		if alpha {
+			dst[i] = c.A
+			i++
+		}
+	}
+}
+
+// rowBytes returns the length of a row of width pixels, without the filter
+// type byte.
+func (f *scanlineFormat) rowBytes(width int) int {
+	if f.depth < 8 {
+		return (width*int(f.depth) + 7) / 8
+	}
+	return width * f.bpp
+}
+
+// encodeScanlines filters the rows of m in format f and compresses them
+// with c, returning the chunks of a standalone PNG. If buf is non-nil, its
+// buffers are used, and the frame data returned is only valid until buf is
+// next used.
+func encodeScanlines(m image.Image, f *scanlineFormat, c Compressor, level CompressionLevel, buf *encodeBuffers) (*pngChunk, error) {
+	if buf == nil {
+		buf = new(encodeBuffers)
+	}
+	b := m.Bounds()
+	if p, ok := m.(*image.Paletted); ok && f.colorType == ctPaletted && !equalColorModel(p.Palette, f.palette) {
+		// Map the pixels onto the palette of the format.
+		q := image.NewPaletted(b, f.palette)
+		draw.Draw(q, b, p, b.Min, draw.Src)
+		m = q
+	}
+	pc := &pngChunk{ihdr: f.ihdr(b)}
+	pc.plte, pc.trns = f.plteAndtRNS()
+
+	bb := &buf.out
+	bb.Reset()
+	var zw io.WriteCloser
+	if _, ok := c.(ZlibCompressor); ok && buf.zw != nil && buf.zlevel == level {
+		buf.zw.Reset(bb)
+		zw = buf.zw
+	} else {
+		var err error
+		if zw, err = c.NewWriter(bb, level); err != nil {
+			return nil, err
+		}
+		if z, ok := zw.(*zlib.Writer); ok {
+			buf.zw, buf.zlevel = z, level
+		}
+	}
+
+	// As in image/png, paletted rows and uncompressed output are left
+	// unfiltered.
+	useFilter := f.colorType != ctPaletted && level != NoCompression
+
+	rows := buf.rowBuffers(filterNum+2, 1+f.rowBytes(b.Dx()))
+	writeRows := func(m image.Image) error {
+		b := m.Bounds()
+		n := f.rowBytes(b.Dx())
+		var cr [filterNum][]byte
+		for i := range cr {
+			cr[i] = rows[i][:n]
+		}
+		pr, row := rows[filterNum][:n], rows[filterNum+1][:1+n]
+		// Filters see a row of zeros above the first row.
+		for i := range pr {
+			pr[i] = 0
+		}
+
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			f.writeRow(cr[ftNone], m, y)
+			ft := ftNone
+			if useFilter {
+				ft = filter(&cr, pr, f.bpp)
+			}
+			row[0] = byte(ft)
+			copy(row[1:], cr[ft])
+			if _, err := zw.Write(row); err != nil {
+				return err
+			}
+			pr, cr[ftNone] = cr[ftNone], pr
+		}
+		return nil
+	}
+
+	if f.interlace {
+		for p := range adam7 {
+			if pm := adam7Pass(m, p); pm != nil {
+				if err := writeRows(pm); err != nil {
+					return nil, err
+				}
+			}
+		}
+	} else if err := writeRows(m); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	pc.idats = []idat{bb.Bytes()}
+	return pc, nil
+}
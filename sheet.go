@@ -0,0 +1,73 @@
+package goapng
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"math"
+	"time"
+)
+
+// SpriteFrame describes where a frame is in a sprite sheet written by
+// ToSpriteSheet, and how long it is displayed.
+type SpriteFrame struct {
+	Rect     image.Rectangle
+	Duration time.Duration
+}
+
+// FromSpriteSheet slices img, a grid of frames of frameW by frameH pixels,
+// into an animation played at fps frames per second. Frames are taken row
+// by row, left to right; cells cut off by the right or bottom edge of img
+// are ignored. The frames share pixels with img when possible.
+func FromSpriteSheet(img image.Image, frameW, frameH int, fps float64) (*APNG, error) {
+	if frameW <= 0 || frameH <= 0 {
+		return nil, errors.New("apng: frame size must be positive")
+	}
+	b := img.Bounds()
+	cols, rows := b.Dx()/frameW, b.Dy()/frameH
+	if cols == 0 || rows == 0 {
+		return nil, errors.New("apng: frame size larger than the sprite sheet")
+	}
+
+	frames := make([]image.Image, 0, cols*rows)
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols; x++ {
+			min := b.Min.Add(image.Pt(x*frameW, y*frameH))
+			m := subImage(img, image.Rectangle{min, min.Add(image.Pt(frameW, frameH))})
+			frames = append(frames, translate(m, min.Mul(-1)))
+		}
+	}
+	return NewFromFPS(frames, fps), nil
+}
+
+// ToSpriteSheet renders the frames of a, as they are displayed, into a grid
+// cols frames wide, filled row by row. If cols is not positive, the grid is
+// made about square. The returned frames give the position and display time
+// of each frame in the sheet.
+func ToSpriteSheet(a *APNG, cols int) (*image.RGBA, []SpriteFrame, error) {
+	n := len(a.Images)
+	if n == 0 {
+		return nil, nil, ErrNoFrames
+	}
+	if cols <= 0 {
+		cols = int(math.Ceil(math.Sqrt(float64(n))))
+	}
+	if cols > n {
+		cols = n
+	}
+	rows := (n + cols - 1) / cols
+
+	b := a.bounds()
+	w, h := b.Dx(), b.Dy()
+	sheet := image.NewRGBA(image.Rect(0, 0, cols*w, rows*h))
+	frames := make([]SpriteFrame, n)
+	c := newCompositor(w, h)
+	ts := a.timeline()
+	for i, img := range a.Images {
+		canvas := c.render(img, a.disposal(i), a.blend(i))
+		r := canvas.Rect.Add(image.Pt(i%cols*w, i/cols*h))
+		draw.Draw(sheet, r, canvas, image.Point{}, draw.Src)
+		frames[i] = SpriteFrame{Rect: r, Duration: ts[i+1] - ts[i]}
+	}
+	return sheet, frames, nil
+}
@@ -0,0 +1,61 @@
+package goapng
+
+import (
+	"context"
+	"io"
+)
+
+// countingWriter counts the bytes written to w. A nil w discards the data.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(b []byte) (int, error) {
+	if cw.w == nil {
+		cw.n += int64(len(b))
+		return len(b), nil
+	}
+	n, err := cw.w.Write(b)
+	cw.n += int64(n)
+	return n, err
+}
+
+// EstimateSize returns the number of bytes EncodeAll would write for a,
+// without writing anything.
+func EstimateSize(a *APNG) (int64, error) {
+	var enc Encoder
+	return enc.EstimateSize(a)
+}
+
+// EstimateSize returns the number of bytes enc.EncodeAll would write for a,
+// without writing anything. It performs a full encode, so the result is exact.
+func (enc *Encoder) EstimateSize(a *APNG) (int64, error) {
+	cw := new(countingWriter)
+	if err := enc.EncodeAll(cw, a); err != nil {
+		return 0, err
+	}
+	return cw.n, nil
+}
+
+// EncodeAllStats is like EncodeAll but also returns statistics about the
+// encoded image.
+func EncodeAllStats(w io.Writer, a *APNG) (*EncodeStats, error) {
+	var enc Encoder
+	return enc.EncodeAllStats(w, a)
+}
+
+// EncodeAllStats is like EncodeAll but also returns statistics about the
+// encoded image. If enc.OnFrame is set, it is still called for each frame.
+func (enc *Encoder) EncodeAllStats(w io.Writer, a *APNG) (*EncodeStats, error) {
+	stats := new(EncodeStats)
+	cw := &countingWriter{w: w}
+	err := enc.encodeAll(context.Background(), cw, a, func(i int, fs FrameStats) {
+		stats.Frames = append(stats.Frames, fs)
+		if enc.OnFrame != nil {
+			enc.OnFrame(i, fs)
+		}
+	})
+	stats.Bytes = cw.n
+	return stats, err
+}
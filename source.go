@@ -0,0 +1,362 @@
+package goapng
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"io"
+	"time"
+)
+
+// FrameSource supplies the frames of an animation one at a time, such as
+// frames decoded from a video pipe or a camera. Next returns the next frame
+// and how long it is displayed, or io.EOF after the last frame. The first
+// frame sets the canvas; later frames must have the same color model and
+// lie within it.
+//
+// If a FrameSource also has a Len() int method returning the number of
+// frames, the animation is written straight through. Otherwise the frame
+// count is patched in afterwards, by seeking if the writer is an
+// io.WriteSeeker and by holding the encoded output in memory if not.
+type FrameSource interface {
+	Next() (image.Image, time.Duration, error)
+}
+
+// EncodeSource writes the frames of src to w in APNG format, holding one
+// frame in memory at a time. Frames are drawn with DisposeOpNone and
+// BlendOpSource.
+func EncodeSource(w io.Writer, src FrameSource, loopCount uint32) error {
+	var enc Encoder
+	return enc.EncodeSource(w, src, loopCount)
+}
+
+// EncodeSource writes the frames of src to w in APNG format.
+func (enc *Encoder) EncodeSource(w io.Writer, src FrameSource, loopCount uint32) error {
+	return enc.EncodeSourceContext(context.Background(), w, src, loopCount)
+}
+
+// EncodeSourceContext is like EncodeSource but stops with ctx.Err() once
+// ctx is done. Cancellation is checked before each frame is requested from
+// src and between chunks.
+func EncodeSourceContext(ctx context.Context, w io.Writer, src FrameSource, loopCount uint32) error {
+	var enc Encoder
+	return enc.EncodeSourceContext(ctx, w, src, loopCount)
+}
+
+// EncodeSourceContext is like EncodeSource but stops with ctx.Err() once
+// ctx is done.
+func (enc *Encoder) EncodeSourceContext(ctx context.Context, w io.Writer, src FrameSource, loopCount uint32) error {
+	if loopCount > maxLoopCount {
+		return ErrLoopCount
+	}
+
+	if l, ok := src.(interface{ Len() int }); ok {
+		_, _, err := enc.encodeSource(ctx, w, src, loopCount, l.Len())
+		return err
+	}
+
+	if ws, ok := w.(io.WriteSeeker); ok {
+		// Seeking fails on pipes, which are handled below.
+		if start, err := ws.Seek(0, io.SeekCurrent); err == nil {
+			off, n, err := enc.encodeSource(ctx, ws, src, loopCount, -1)
+			if err != nil {
+				return err
+			}
+			// Patch the frame count into acTL, then return to the end of
+			// the stream.
+			end, err := ws.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return err
+			}
+			if _, err := ws.Seek(start+off, io.SeekStart); err != nil {
+				return err
+			}
+			if _, err := ws.Write(acTLChunk(n, loopCount)); err != nil {
+				return err
+			}
+			_, err = ws.Seek(end, io.SeekStart)
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	off, n, err := enc.encodeSource(ctx, &buf, src, loopCount, -1)
+	if err != nil {
+		return err
+	}
+	copy(buf.Bytes()[off:], acTLChunk(n, loopCount))
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// FrameFunc produces frame i of an animation and how long it is displayed,
+// or reports false once there are no more frames. It is called for i = 0,
+// 1, 2, ... in turn, so frames can be generated as they are encoded.
+type FrameFunc func(i int) (image.Image, time.Duration, bool)
+
+// FuncSource returns a FrameSource that takes its frames from fn.
+func FuncSource(fn FrameFunc) FrameSource {
+	return &funcSource{fn: fn}
+}
+
+type funcSource struct {
+	fn FrameFunc
+	i  int
+}
+
+func (s *funcSource) Next() (image.Image, time.Duration, error) {
+	img, d, ok := s.fn(s.i)
+	if !ok {
+		return nil, 0, io.EOF
+	}
+	s.i++
+	return img, d, nil
+}
+
+// EncodeFunc writes the frames produced by fn to w in APNG format, as
+// EncodeSource does, so that procedurally generated frames need not all be
+// held in memory.
+func EncodeFunc(w io.Writer, fn FrameFunc, loopCount uint32) error {
+	return EncodeSource(w, FuncSource(fn), loopCount)
+}
+
+// ChanSource returns a FrameSource that receives its frames from ch. The
+// animation ends when ch is closed. The Disposal and Blend of the frames
+// are ignored, as EncodeSource draws every frame with DisposeOpNone and
+// BlendOpSource.
+func ChanSource(ch <-chan Frame) FrameSource {
+	return chanSource(ch)
+}
+
+type chanSource <-chan Frame
+
+func (ch chanSource) Next() (image.Image, time.Duration, error) {
+	f, ok := <-ch
+	if !ok {
+		return nil, 0, io.EOF
+	}
+	return f.image(), f.Delay, nil
+}
+
+// EncodeChan writes the frames received from ch to w in APNG format as
+// they arrive, until ch is closed, as EncodeSource does. If encoding fails,
+// the frames still to come are received and discarded, so that a producer
+// sending on ch is never left blocked.
+func EncodeChan(w io.Writer, ch <-chan Frame, loopCount uint32) error {
+	err := EncodeSource(w, ChanSource(ch), loopCount)
+	if err != nil {
+		for range ch {
+		}
+	}
+	return err
+}
+
+// encodeSource writes the frames of src to w, with numFrames in the acTL
+// chunk, or 0 if numFrames is negative. It returns the offset of the acTL
+// chunk and the number of frames written.
+func (enc *Encoder) encodeSource(ctx context.Context, w io.Writer, src FrameSource, loopCount uint32, numFrames int) (int64, int, error) {
+	if err := enc.checkSettings(); err != nil {
+		return 0, 0, err
+	}
+	cw := &countingWriter{w: w}
+	e := encoder{
+		enc: enc,
+		ctx: ctx,
+		w:   cw,
+		// Later frames may be less opaque than the first, so every frame
+		// is written with the color type chosen for the stream.
+		mixedOpacity: true,
+	}
+	pool := enc.bufferPool()
+	e.buf = pool.Get().(*encodeBuffers)
+	defer pool.Put(e.buf)
+
+	var (
+		off    int64
+		canvas image.Rectangle
+		model  color.Model
+		i      int
+	)
+	_, e.err = io.WriteString(e.w, pngHeader)
+	for ; ; i++ {
+		if err := ctx.Err(); err != nil {
+			return 0, 0, err
+		}
+		img, d, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		if img == nil {
+			return 0, 0, &FrameError{i, ErrNilFrame}
+		}
+		if img, err = enc.atOrigin(i, img); err != nil {
+			return 0, 0, err
+		}
+
+		b := img.Bounds()
+		if i == 0 {
+			if err := checkCanvas(b); err != nil {
+				return 0, 0, &FrameError{0, err}
+			}
+			canvas, model = b, img.ColorModel()
+			f := streamFormat(img)
+			f.interlace = enc.Interlace
+			e.format = &f
+		} else if !equalColorModel(img.ColorModel(), model) {
+			return 0, 0, &FrameError{i, ErrColorModel}
+		} else if err := checkRegion(b, canvas); err != nil {
+			if fr := b.Intersect(regionOf(canvas)); enc.RegionPolicy == FixRegion && !fr.Empty() {
+				if enc.OnWarning != nil {
+					enc.OnWarning(&FrameError{i, err})
+				}
+				img = subImage(img, fr)
+			} else {
+				return 0, 0, &FrameError{i, err}
+			}
+		}
+		if numFrames >= 0 && i >= numFrames {
+			return 0, 0, errors.New("apng: more frames than FrameSource length")
+		}
+
+		// writefcTL and the frame statistics read the frame from e.a, and
+		// errors give its index as e.first plus its index there.
+		e.first = i
+		e.a = &APNG{
+			Images:    []image.Image{img},
+			Durations: []time.Duration{d},
+			LoopCount: loopCount,
+		}
+		if enc.MinDelayPolicy == RejectMinDelay && e.shortDelay(0) {
+			return 0, 0, e.minDelayErr(0)
+		}
+
+		start, n := time.Now(), cw.n
+		pc, err := e.encodeFrame(img)
+		if err != nil {
+			return 0, 0, e.frameError(0, err)
+		}
+		e.ihdr = pc.ihdr
+		e.plte = pc.plte
+		e.trns = pc.trns
+		e.idats = splitIDATs(pc.idats, enc.MaxChunkSize)
+
+		if i == 0 {
+			e.writeIHDR()
+			off = cw.n
+			if numFrames >= 0 {
+				e.writeacTL(numFrames)
+			} else {
+				e.writeacTL(0)
+			}
+			e.writePLTEAndtRNS()
+			e.writefcTL(0)
+			e.writeIDATs()
+		} else {
+			e.writefcTL(0)
+			e.writefdATs()
+		}
+		if e.err != nil {
+			return 0, 0, e.frameErr(0)
+		}
+
+		if enc.OnFrame != nil {
+			enc.OnFrame(i, e.frameStats(0, cw.n-n, time.Since(start)))
+		}
+	}
+	if i == 0 {
+		return 0, 0, ErrNoFrames
+	}
+	if numFrames >= 0 && i != numFrames {
+		return 0, 0, errors.New("apng: fewer frames than FrameSource length")
+	}
+	e.writeIEND()
+	return off, i, e.err
+}
+
+// streamFormat picks the scanline format for a stream starting with m.
+// Unless the color model can only hold opaque colors, an alpha channel is
+// kept, since later frames may need it.
+func streamFormat(m image.Image) scanlineFormat {
+	f := chooseFormat([]image.Image{m})
+	switch m.ColorModel() {
+	case color.YCbCrModel, color.GrayModel, color.Gray16Model, color.CMYKModel:
+		return f
+	}
+	if f.colorType == ctTrueColor {
+		f.colorType, f.bpp = ctTrueColorAlpha, f.bpp/3*4
+	}
+	return f
+}
+
+// acTLChunk returns a complete acTL chunk.
+func acTLChunk(numFrames int, numPlays uint32) []byte {
+	b := make([]byte, 20)
+	writeUint32(b[0:4], 8)
+	copy(b[4:8], "acTL")
+	writeUint32(b[8:12], uint32(numFrames))
+	writeUint32(b[12:16], numPlays)
+	writeUint32(b[16:20], crc32.ChecksumIEEE(b[4:16]))
+	return b
+}
+
+// DirtyFrame is a frame of a screen recording: the whole canvas as it is
+// to be displayed, with the rectangles of it that changed since the
+// previous frame, as screen capture APIs report them.
+type DirtyFrame struct {
+	Canvas image.Image
+	Dirty  []image.Rectangle // In the coordinates of Canvas.
+	Delay  time.Duration
+}
+
+// DirtySource returns a FrameSource that takes its frames from next, which
+// returns io.EOF after the last frame. Only the part of each canvas that
+// holds its dirty rectangles is encoded, without comparing it to the
+// previous frame. APNG frames are single rectangles, so that part is the
+// smallest rectangle holding them all; a frame with nothing dirty is
+// written as one unchanged pixel. The first frame is always written whole.
+// The canvas is only read until next is called again, so a capture buffer
+// can be reused from frame to frame. Frames are placed by the Min of their
+// bounds, so the source must be encoded with KeepOrigin.
+func DirtySource(next func() (DirtyFrame, error)) FrameSource {
+	return &dirtySource{next: next}
+}
+
+type dirtySource struct {
+	next    func() (DirtyFrame, error)
+	started bool // Whether the first frame has been returned.
+}
+
+func (s *dirtySource) Next() (image.Image, time.Duration, error) {
+	f, err := s.next()
+	if err != nil {
+		return nil, 0, err
+	}
+	b := f.Canvas.Bounds()
+	r := b
+	if s.started {
+		r = image.Rectangle{}
+		for _, d := range f.Dirty {
+			r = r.Union(d)
+		}
+		r = r.Intersect(b)
+		if r.Empty() {
+			r = image.Rectangle{b.Min, b.Min.Add(image.Pt(1, 1))}
+		}
+	}
+	s.started = true
+	// translate moves images in place, so it is given a new image sharing
+	// the pixels of the canvas.
+	return translate(subImage(f.Canvas, r), b.Min.Mul(-1)), f.Delay, nil
+}
+
+// EncodeDirty writes the frames returned by next to w in APNG format,
+// encoding only the dirty part of each, as DirtySource describes.
+func EncodeDirty(w io.Writer, next func() (DirtyFrame, error), loopCount uint32) error {
+	return EncodeSource(w, DirtySource(next), loopCount)
+}
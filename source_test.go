@@ -0,0 +1,119 @@
+package goapng
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncodeSourceFile(t *testing.T) {
+	a := testAPNG(3, 8, 8)
+	a.LoopCount = 2
+	f, err := os.Create(filepath.Join(t.TempDir(), "a.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// Write after a prefix, so that acTL is not at a fixed offset.
+	prefix := []byte("prefix")
+	if _, err := f.Write(prefix); err != nil {
+		t.Fatal(err)
+	}
+	if err := EncodeSource(f, sliceSource(a), a.LoopCount); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	off, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if off != fi.Size() {
+		t.Errorf("offset after EncodeSource = %d, want the file size %d", off, fi.Size())
+	}
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, prefix) {
+		t.Fatalf("prefix overwritten: %q", data[:len(prefix)])
+	}
+	b, err := DecodeAll(bytes.NewReader(data[len(prefix):]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, diffs := EqualFrames(a, b, 0); !ok {
+		t.Errorf("frames differ: %v", diffs)
+	}
+}
+
+// lenSource is a sliceSource that reports n as its length.
+type lenSource struct {
+	FrameSource
+	n int
+}
+
+func (s *lenSource) Len() int { return s.n }
+
+func TestEncodeSource(t *testing.T) {
+	a := testAPNG(3, 8, 6)
+	a.LoopCount = 4
+	gray := image.NewGray(image.Rect(0, 0, 8, 6))
+	mixed := &APNG{Images: []image.Image{a.Images[0], gray}, Durations: a.Durations[:2]}
+	nilFirst := &APNG{Images: []image.Image{nil}, Durations: a.Durations[:1]}
+	nilLater := &APNG{Images: []image.Image{a.Images[0], nil}, Durations: a.Durations[:2]}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		src     func() FrameSource
+		wantErr error // The error, or nil for one not wrapping a sentinel.
+		ok      bool
+	}{
+		{"buffered", context.Background(), func() FrameSource { return sliceSource(a) }, nil, true},
+		{"with Len", context.Background(), func() FrameSource { return &lenSource{sliceSource(a), 3} }, nil, true},
+		{"Len too small", context.Background(), func() FrameSource { return &lenSource{sliceSource(a), 2} }, nil, false},
+		{"Len too large", context.Background(), func() FrameSource { return &lenSource{sliceSource(a), 4} }, nil, false},
+		{"no frames", context.Background(), func() FrameSource { return sliceSource(&APNG{}) }, ErrNoFrames, false},
+		{"color model change", context.Background(), func() FrameSource { return sliceSource(mixed) }, ErrColorModel, false},
+		{"nil first frame", context.Background(), func() FrameSource { return sliceSource(nilFirst) }, ErrNilFrame, false},
+		{"nil later frame", context.Background(), func() FrameSource { return &lenSource{sliceSource(nilLater), 2} }, ErrNilFrame, false},
+		{"canceled", canceled, func() FrameSource { return sliceSource(a) }, context.Canceled, false},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		err := EncodeSourceContext(tt.ctx, &buf, tt.src(), a.LoopCount)
+		switch {
+		case tt.ok && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		case !tt.ok && err == nil:
+			t.Errorf("%s: got no error", tt.name)
+			continue
+		case tt.wantErr != nil && !errors.Is(err, tt.wantErr):
+			t.Errorf("%s: got error %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		case !tt.ok:
+			continue
+		}
+		b, err := DecodeAll(&buf)
+		if err != nil {
+			t.Errorf("%s: decoding the result: %v", tt.name, err)
+			continue
+		}
+		if ok, diffs := EqualFrames(a, b, 0); !ok {
+			t.Errorf("%s: frames differ: %v", tt.name, diffs)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package goapng
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"time"
+)
+
+// ThumbnailOptions sets the budget of a thumbnail made by Thumbnail. Zero
+// fields impose no limit.
+type ThumbnailOptions struct {
+	MaxSize   int    // The longest side of the canvas, in pixels.
+	MaxFrames int    // The number of frames.
+	MaxBytes  int    // The size of the encoded thumbnail.
+	Filter    Filter // The kernel used to scale the frames down.
+
+	// Background, if non-nil, is drawn behind the frames, making the
+	// thumbnail opaque; it usually compresses better too.
+	Background color.Color
+}
+
+// Thumbnail writes a reduced copy of a to w that fits opts, such as at
+// most 128 pixels across, 10 frames and 100 KB for a gallery preview. The
+// canvas is scaled down to MaxSize, keeping its aspect ratio, and frames
+// are picked at even intervals to stay within MaxFrames, each shown until
+// the next picked frame so the animation keeps its length. If the result
+// is still larger than MaxBytes, frames and then pixels are halved in turn
+// until it fits. a is not modified.
+func Thumbnail(w io.Writer, a *APNG, opts ThumbnailOptions) error {
+	frames, err := Composite(a)
+	if err != nil {
+		return err
+	}
+	if opts.Background != nil {
+		for _, m := range frames {
+			overBackground(m, cloneRGBA(m), opts.Background)
+		}
+	}
+	ts := a.timeline()
+
+	b := a.bounds()
+	scale := 1.0
+	if opts.MaxSize > 0 {
+		if long := math.Max(float64(b.Dx()), float64(b.Dy())); long > float64(opts.MaxSize) {
+			scale = float64(opts.MaxSize) / long
+		}
+	}
+	n := len(frames)
+	if opts.MaxFrames > 0 && n > opts.MaxFrames {
+		n = opts.MaxFrames
+	}
+
+	for round := 0; ; round++ {
+		width := int(math.Max(1, math.Round(float64(b.Dx())*scale)))
+		height := int(math.Max(1, math.Round(float64(b.Dy())*scale)))
+		t, err := thumbnail(frames, ts, n, width, height, a.LoopCount, opts.Filter)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := EncodeAll(&buf, t); err != nil {
+			return err
+		}
+		if opts.MaxBytes <= 0 || buf.Len() <= opts.MaxBytes {
+			_, err := buf.WriteTo(w)
+			return err
+		}
+
+		switch {
+		case n > 1 && (round%2 == 0 || width*height == 1):
+			n = (n + 1) / 2
+		case width*height > 1:
+			scale /= math.Sqrt2
+		default:
+			return errors.New("apng: thumbnail does not fit in MaxBytes")
+		}
+	}
+}
+
+// thumbnail returns an animation of n of the composited frames, picked at
+// even intervals and scaled to width x height. ts is the timeline of the
+// frames.
+func thumbnail(frames []*image.RGBA, ts []time.Duration, n, width, height int, loopCount uint32, f Filter) (*APNG, error) {
+	t := &APNG{
+		Images:    make([]image.Image, n),
+		Durations: make([]time.Duration, n),
+		LoopCount: loopCount,
+	}
+	for j := 0; j < n; j++ {
+		i, next := j*len(frames)/n, (j+1)*len(frames)/n
+		t.Images[j] = frames[i]
+		t.Durations[j] = ts[next] - ts[i]
+	}
+	if err := Resize(t, width, height, f); err != nil {
+		return nil, err
+	}
+	Optimize(t, OptimizePalette)
+	return t, nil
+}
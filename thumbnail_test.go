@@ -0,0 +1,97 @@
+package goapng
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestThumbnail(t *testing.T) {
+	a := testAPNG(10, 40, 20)
+	a.LoopCount = 3
+
+	var buf bytes.Buffer
+	if err := Thumbnail(&buf, a, ThumbnailOptions{MaxSize: 10, MaxFrames: 4}); err != nil {
+		t.Fatal(err)
+	}
+	th, err := DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if th.Config.Width != 10 || th.Config.Height != 5 {
+		t.Errorf("canvas %dx%d, want 10x5", th.Config.Width, th.Config.Height)
+	}
+	// Frames 0, 2, 5 and 7, each shown until the next.
+	var durs []time.Duration
+	for i := range th.Images {
+		durs = append(durs, th.delay(i))
+	}
+	if want := "[200ms 300ms 200ms 300ms]"; fmt.Sprint(durs) != want {
+		t.Errorf("delays %v, want %s", durs, want)
+	}
+	for j, i := range []int{0, 2, 5, 7} {
+		want := color.NRGBAModel.Convert(a.Images[i].At(0, 0))
+		if got := color.NRGBAModel.Convert(th.Images[j].At(th.Images[j].Bounds().Min.X, th.Images[j].Bounds().Min.Y)); got != want {
+			t.Errorf("frame %d: %v, want the color of frame %d, %v", j, got, i, want)
+		}
+	}
+	if th.LoopCount != 3 {
+		t.Errorf("loop count %d, want 3", th.LoopCount)
+	}
+	if len(a.Images) != 10 || a.Images[0].Bounds() != image.Rect(0, 0, 40, 20) {
+		t.Error("Thumbnail modified its input")
+	}
+}
+
+func TestThumbnailMaxBytes(t *testing.T) {
+	// Noise compresses badly, so the frames and pixels must be cut down.
+	a := testAPNG(8, 64, 64)
+	for i, img := range a.Images {
+		m := img.(*image.NRGBA)
+		for j := range m.Pix {
+			m.Pix[j] = uint8((j*7919 + i*104729) >> 3)
+		}
+	}
+	var full bytes.Buffer
+	if err := Thumbnail(&full, a, ThumbnailOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	budget := full.Len() / 10
+	var buf bytes.Buffer
+	if err := Thumbnail(&buf, a, ThumbnailOptions{MaxBytes: budget}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() > budget {
+		t.Errorf("thumbnail is %d bytes, want at most %d", buf.Len(), budget)
+	}
+	th, err := DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if th.Duration() != a.Duration() {
+		t.Errorf("duration %v, want %v", th.Duration(), a.Duration())
+	}
+
+	if err := Thumbnail(&bytes.Buffer{}, a, ThumbnailOptions{MaxBytes: 10}); err == nil {
+		t.Error("impossible budget: got no error")
+	}
+}
+
+func TestThumbnailBackground(t *testing.T) {
+	a := testAPNG(2, 8, 8)
+	a.Images[0] = solid(8, 8, color.NRGBA{0, 0, 0xff, 0x80})
+	var buf bytes.Buffer
+	if err := Thumbnail(&buf, a, ThumbnailOptions{MaxSize: 4, Background: color.White}); err != nil {
+		t.Fatal(err)
+	}
+	th, err := DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, alpha := th.Images[0].At(1, 1).RGBA(); alpha != 0xffff {
+		t.Errorf("alpha %#x over a background, want opaque", alpha)
+	}
+}
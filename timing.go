@@ -0,0 +1,330 @@
+package goapng
+
+import (
+	"errors"
+	"image"
+	"math"
+	"time"
+)
+
+// NewFromFPS returns an animation of frames played at fps frames per
+// second. Every frame gets the same delay, the fraction of a second closest
+// to 1/fps, so 30 fps is stored as exactly 1/30 s rather than 3/100 s. The
+// canvas size is taken from the first frame. If fps is not positive, the
+// delays are zero.
+func NewFromFPS(frames []image.Image, fps float64) *APNG {
+	a := &APNG{
+		Images:    frames,
+		Delays:    make([]uint16, len(frames)),
+		DelayDens: make([]uint16, len(frames)),
+	}
+	num, den := uint16(0), uint16(100)
+	if fps > 0 {
+		// fps is rounded to a millionth of a frame per second.
+		q := int64(math.Round(math.Min(fps, 1e12) * 1e6))
+		if q < 1 {
+			q = 1
+		}
+		num, den = bestFraction(1e6, q, 0xffff)
+	}
+	for i := range frames {
+		a.Delays[i], a.DelayDens[i] = num, den
+	}
+	if len(frames) > 0 {
+		b := frames[0].Bounds()
+		a.Config = image.Config{
+			ColorModel: frames[0].ColorModel(),
+			Width:      b.Dx(),
+			Height:     b.Dy(),
+		}
+	}
+	return a
+}
+
+// Duration returns the length of one play of a, the sum of its frame
+// delays.
+func (a *APNG) Duration() time.Duration {
+	ts := a.timeline()
+	return ts[len(ts)-1]
+}
+
+// Timestamps returns the time at which each frame of a is displayed,
+// relative to the start of a play. The first timestamp is zero.
+func (a *APNG) Timestamps() []time.Duration {
+	ts := a.timeline()
+	return ts[:len(ts)-1]
+}
+
+// timeline returns the start time of each frame followed by the end of the
+// last one. Fractional delays are summed exactly and rounded once, so
+// thirty frames of 1/30 s end at exactly one second.
+func (a *APNG) timeline() []time.Duration {
+	ts := make([]time.Duration, len(a.Images)+1)
+	if a.Durations != nil {
+		for i, d := range a.Durations[:len(a.Images)] {
+			ts[i+1] = ts[i] + d
+		}
+		return ts
+	}
+	var t float64 // In nanoseconds.
+	for i := range a.Images {
+		t += float64(a.Delays[i]) * float64(time.Second) / float64(a.delayDen(i))
+		ts[i+1] = time.Duration(math.Round(t))
+	}
+	return ts
+}
+
+// SetDelayMS sets the delay of frame i to ms milliseconds, stored as the
+// closest delay_num/delay_den pair. Negative delays are stored as zero.
+func (a *APNG) SetDelayMS(i, ms int) {
+	d := time.Duration(ms) * time.Millisecond
+	if a.Durations != nil {
+		if d < 0 {
+			d = 0
+		}
+		a.Durations[i] = d
+		return
+	}
+	if a.DelayDens == nil {
+		a.DelayDens = make([]uint16, len(a.Delays))
+		for j := range a.DelayDens {
+			a.DelayDens[j] = 100
+		}
+	}
+	a.Delays[i], a.DelayDens[i] = DelayFraction(d)
+}
+
+// SetSpeed retimes a to play factor times as fast: a factor of 2 halves
+// every delay and a factor of 0.5 doubles it. Each delay is rounded so that
+// the start of every frame stays as close as possible to its exact scaled
+// time, rather than letting rounding errors accumulate over the animation.
+// Delays that no longer fit their denominator are given a coarser one when
+// DelayDens is set, and are clamped otherwise.
+func SetSpeed(a *APNG, factor float64) error {
+	if err := checkSpeed(factor); err != nil {
+		return err
+	}
+
+	if a.Durations != nil {
+		var target float64
+		var elapsed time.Duration
+		for i, d := range a.Durations {
+			target += float64(d) / factor
+			d := time.Duration(math.Round(target)) - elapsed
+			if d < 0 {
+				d = 0
+			}
+			a.Durations[i] = d
+			elapsed += d
+		}
+	}
+
+	if a.Delays != nil {
+		s := speedScaler{factor: factor}
+		for i, num := range a.Delays {
+			den := a.delayDen(i)
+			d := s.next(num, den)
+			n := math.Round(d * float64(den))
+			switch {
+			case n <= 0xffff:
+				a.Delays[i] = uint16(n)
+			case a.DelayDens != nil:
+				a.Delays[i], a.DelayDens[i] = bestFraction(int64(math.Round(math.Min(d, 1e6)*1e9)), 1e9, 0xffff)
+			default:
+				// Clamping loses time for good; do not make it up later.
+				a.Delays[i] = 0xffff
+				s.elapsed = s.target
+				continue
+			}
+			s.elapsed += float64(a.Delays[i]) / float64(a.delayDen(i))
+		}
+	}
+	return nil
+}
+
+// SpeedDelay returns a RemuxOptions.Delay that retimes an animation to play
+// factor times as fast. Unlike SetSpeed it is free to change denominators:
+// each delay is the fraction DelayFraction picks for the time left until
+// the exact scaled start of the next frame, so rounding errors don't add
+// up. The function keeps the time elapsed so far, so it serves a single
+// Remux call.
+func SpeedDelay(factor float64) (func(i int, num, den uint16) (uint16, uint16), error) {
+	if err := checkSpeed(factor); err != nil {
+		return nil, err
+	}
+	s := speedScaler{factor: factor}
+	return func(_ int, num, den uint16) (uint16, uint16) {
+		if den == 0 {
+			den = 100
+		}
+		d := math.Min(s.next(num, den), 1e6)
+		num, den = DelayFraction(time.Duration(d * float64(time.Second)))
+		s.elapsed += float64(num) / float64(den)
+		return num, den
+	}, nil
+}
+
+func checkSpeed(factor float64) error {
+	if !(factor > 0) || math.IsInf(factor, 1) {
+		return errors.New("apng: invalid speed factor")
+	}
+	return nil
+}
+
+// speedScaler keeps the time of a run of delays being scaled by 1/factor,
+// for SetSpeed and SpeedDelay. The delays stored are added to elapsed.
+type speedScaler struct {
+	factor          float64
+	target, elapsed float64 // In seconds.
+}
+
+// next adds the delay num/den, scaled, to the exact time and returns the
+// delay, in seconds, that starts the next frame on time.
+func (s *speedScaler) next(num, den uint16) float64 {
+	s.target += float64(num) / float64(den) / s.factor
+	return math.Max(0, s.target-s.elapsed)
+}
+
+// delay returns the delay of frame i.
+func (a *APNG) delay(i int) time.Duration {
+	if a.Durations != nil {
+		return a.Durations[i]
+	}
+	return time.Duration(a.Delays[i]) * time.Second / time.Duration(a.delayDen(i))
+}
+
+// delayDen returns the denominator of Delays[i].
+func (a *APNG) delayDen(i int) uint16 {
+	if a.DelayDens == nil || a.DelayDens[i] == 0 {
+		return 100
+	}
+	return a.DelayDens[i]
+}
+
+// delayFraction returns the delay_num and delay_den to write for frame i.
+func (a *APNG) delayFraction(i int) (uint16, uint16) {
+	if a.Durations != nil {
+		return DelayFraction(a.Durations[i])
+	}
+	if a.DelayDens != nil {
+		return a.Delays[i], a.DelayDens[i]
+	}
+	return a.Delays[i], 100
+}
+
+// foldDelay adds the delay of frame src to frame dst.
+func (a *APNG) foldDelay(dst, src int) {
+	if a.Delays != nil {
+		num, den := int64(a.Delays[dst]), int64(a.delayDen(dst))
+		if sden := int64(a.delayDen(src)); sden != den {
+			num, den = num*sden+int64(a.Delays[src])*den, den*sden
+		} else {
+			num += int64(a.Delays[src])
+		}
+		switch {
+		case num == 0:
+			a.Delays[dst] = 0
+		case den == int64(a.delayDen(dst)) && num <= 0xffff:
+			a.Delays[dst] = uint16(num)
+		case a.DelayDens == nil:
+			// Round to 100ths of a second.
+			d := (num*100 + den/2) / den
+			if d > 0xffff {
+				d = 0xffff
+			}
+			a.Delays[dst] = uint16(d)
+		default:
+			a.Delays[dst], a.DelayDens[dst] = bestFraction(num, den, 0xffff)
+		}
+	}
+	if a.Durations != nil {
+		a.Durations[dst] += a.Durations[src]
+	}
+}
+
+// frameFolder collects the frames of a that are kept while dropping the
+// others, adding the delay of each dropped frame to the kept frame before
+// it, or to the first one kept when no frame before it is.
+type frameFolder struct {
+	a       *APNG
+	kept    []int
+	pending []int // Dropped before any frame was kept.
+}
+
+// keep keeps frame i, which follows every frame seen so far.
+func (f *frameFolder) keep(i int) {
+	for _, j := range f.pending {
+		f.a.foldDelay(i, j)
+	}
+	f.pending = nil
+	f.kept = append(f.kept, i)
+}
+
+// drop drops frame i, which follows every frame seen so far.
+func (f *frameFolder) drop(i int) {
+	if len(f.kept) == 0 {
+		f.pending = append(f.pending, i)
+		return
+	}
+	f.a.foldDelay(f.kept[len(f.kept)-1], i)
+}
+
+// DelayFraction returns the delay_num and delay_den to store for d: the
+// fraction num/den of a second, with both fitting in a uint16, that best
+// approximates d. Delays that fit exactly, such as
+// 1/30 s or 125 ms, are represented exactly.
+func DelayFraction(d time.Duration) (num, den uint16) {
+	if d <= 0 {
+		return 0, 100
+	}
+	return bestFraction(int64(d), int64(time.Second), 0xffff)
+}
+
+// bestFraction returns the fraction with numerator and denominator at most
+// max that is closest to p/q, for positive p and q. It walks the continued
+// fraction expansion of p/q and, where a convergent would exceed max,
+// considers the best semiconvergent instead.
+func bestFraction(p, q, max int64) (uint16, uint16) {
+	x := float64(p) / float64(q)
+	if g := gcd(p, q); g > 1 {
+		p, q = p/g, q/g
+	}
+	if p <= max && q <= max {
+		return uint16(p), uint16(q)
+	}
+
+	// h/k are the successive convergents.
+	h0, h1 := int64(0), int64(1)
+	k0, k1 := int64(1), int64(0)
+	for q != 0 {
+		a := p / q
+		h2, k2 := a*h1+h0, a*k1+k0
+		if h2 > max || k2 > max {
+			t := a
+			if h1 > 0 && (max-h0)/h1 < t {
+				t = (max - h0) / h1
+			}
+			if k1 > 0 && (max-k0)/k1 < t {
+				t = (max - k0) / k1
+			}
+			h, k := t*h1+h0, t*k1+k0
+			if k1 == 0 || (k > 0 && math.Abs(float64(h)/float64(k)-x) < math.Abs(float64(h1)/float64(k1)-x)) {
+				h1, k1 = h, k
+			}
+			break
+		}
+		h0, h1, k0, k1 = h1, h2, k1, k2
+		p, q = q, p-a*q
+	}
+	if k1 == 0 {
+		return uint16(max), 1
+	}
+	return uint16(h1), uint16(k1)
+}
+
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
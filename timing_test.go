@@ -0,0 +1,115 @@
+package goapng
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDelayFraction(t *testing.T) {
+	tests := []struct {
+		d        time.Duration
+		num, den uint16
+	}{
+		{0, 0, 100},
+		{-time.Second, 0, 100},
+		{time.Second, 1, 1},
+		{100 * time.Millisecond, 1, 10},
+		{125 * time.Millisecond, 1, 8},
+		{time.Second / 30, 1, 30},
+		{40 * time.Millisecond, 1, 25},
+		{1500 * time.Millisecond, 3, 2},
+		{90 * time.Second, 90, 1},
+		{time.Nanosecond, 0, 1},
+		{20 * time.Hour, 65535, 1},
+	}
+	for _, tt := range tests {
+		num, den := DelayFraction(tt.d)
+		if num != tt.num || den != tt.den {
+			t.Errorf("DelayFraction(%v) = %d/%d, want %d/%d", tt.d, num, den, tt.num, tt.den)
+		}
+	}
+}
+
+func TestSpeedDelay(t *testing.T) {
+	// Delays of 1/30 s, which no delay in 30ths of a second halves, 7/100 s
+	// and one that outgrows its denominator when slowed down.
+	a := testAPNG(7, 4, 4)
+	a.Durations = nil
+	a.Delays = []uint16{1, 1, 1, 7, 7, 60000, 1}
+	a.DelayDens = []uint16{30, 30, 30, 100, 0, 1000, 30}
+	var buf bytes.Buffer
+	if err := EncodeAll(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, factor := range []float64{2, 3, 0.7, 0.25} {
+		delay, err := SpeedDelay(factor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		if err := Remux(&out, bytes.NewReader(buf.Bytes()), &RemuxOptions{Delay: delay}); err != nil {
+			t.Fatal(err)
+		}
+		b, err := DecodeAll(&out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Every frame starts within a millisecond of its exact time.
+		var exact, got time.Duration
+		for i := range a.Images {
+			exact += time.Duration(float64(a.delay(i)) / factor)
+			got += b.delay(i)
+			if d := got - exact; d > time.Millisecond || d < -time.Millisecond {
+				t.Errorf("factor %g: frame %d ends at %v, want %v", factor, i, got, exact)
+			}
+		}
+	}
+
+	for _, factor := range []float64{0, -1, math.Inf(1), math.NaN()} {
+		if _, err := SpeedDelay(factor); err == nil {
+			t.Errorf("SpeedDelay(%g): got no error", factor)
+		}
+	}
+}
+
+func TestBestFraction(t *testing.T) {
+	// Check each result against every denominator up to max: no fraction
+	// of uint16s is closer.
+	tests := []struct {
+		p, q, max int64
+	}{
+		{333333333, 1e9, 0xffff},
+		{16666667, 1e9, 0xffff},
+		{314159265, 1e8, 0xffff},
+		{1, 3, 0xffff},
+		{12345678, 1e9, 0xffff},
+		{99999, 100000, 0xffff},
+		{7, 1e9, 0xffff},
+		{2, 3, 1},
+		{22, 7, 10},
+		{355, 113, 100},
+		{1e9, 3, 0xffff},
+	}
+	for _, tt := range tests {
+		num, den := bestFraction(tt.p, tt.q, tt.max)
+		if den == 0 || int64(num) > tt.max || int64(den) > tt.max {
+			t.Errorf("bestFraction(%d, %d, %d) = %d/%d, out of range", tt.p, tt.q, tt.max, num, den)
+			continue
+		}
+		x := float64(tt.p) / float64(tt.q)
+		got := math.Abs(float64(num)/float64(den) - x)
+		for k := int64(1); k <= tt.max; k++ {
+			h := int64(math.Round(x * float64(k)))
+			if h > tt.max {
+				h = tt.max
+			}
+			if e := math.Abs(float64(h)/float64(k) - x); e < got*(1-1e-12) {
+				t.Errorf("bestFraction(%d, %d, %d) = %d/%d, but %d/%d is closer", tt.p, tt.q, tt.max, num, den, h, k)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package goapng
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+	"time"
+)
+
+// fixedQuantizer adds its palette to the one it is given.
+type fixedQuantizer color.Palette
+
+func (q fixedQuantizer) Quantize(p color.Palette, m image.Image) color.Palette {
+	return append(p, q...)
+}
+
+func TestToGIF(t *testing.T) {
+	red := color.NRGBA{0xff, 0, 0, 0xff}
+	blue := color.NRGBA{0, 0, 0xff, 0xff}
+	a := &APNG{
+		Images: []image.Image{
+			solid(6, 6, red),
+			solid(6, 6, blue),
+			// Clears the canvas, leaving it transparent.
+			solid(6, 6, color.NRGBA{}),
+		},
+		Durations: []time.Duration{time.Second / 30, time.Second / 30, time.Second / 30},
+		LoopCount: 1,
+	}
+
+	g, err := ToGIF(a, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Image) != 3 || g.Config.Width != 6 || g.Config.Height != 6 {
+		t.Fatalf("got %d frames on a %dx%d canvas, want 3 on 6x6", len(g.Image), g.Config.Width, g.Config.Height)
+	}
+	if g.LoopCount != -1 {
+		t.Errorf("loop count %d, want -1 for a single play", g.LoopCount)
+	}
+	// Three 1/30 s delays round to 100ths without drifting from the 1/10 s
+	// total.
+	if d := g.Delay; d[0]+d[1]+d[2] != 10 || d[0] != 3 || d[1] != 4 {
+		t.Errorf("delays %v, want [3 4 3]", d)
+	}
+	for i, want := range []color.Color{red, blue, color.NRGBA{}} {
+		m := g.Image[i]
+		if m.Bounds() != image.Rect(0, 0, 6, 6) {
+			t.Errorf("frame %d: bounds %v, want the canvas", i, m.Bounds())
+		}
+		if got := color.NRGBAModel.Convert(m.At(3, 3)); got != color.NRGBAModel.Convert(want) {
+			t.Errorf("frame %d: color %v, want %v", i, got, want)
+		}
+	}
+	// The frame before the transparent one is cleared rather than showing
+	// through it.
+	if g.Disposal[1] != gif.DisposalBackground || g.Disposal[0] != gif.DisposalNone {
+		t.Errorf("disposals %v, want background before the transparent frame only", g.Disposal)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatalf("encoding the GIF: %v", err)
+	}
+	if _, err := gif.DecodeAll(&buf); err != nil {
+		t.Errorf("decoding the GIF: %v", err)
+	}
+
+	// A quantizer chooses the palette.
+	q := fixedQuantizer{color.RGBA{0xff, 0, 0, 0xff}, color.RGBA{0, 0, 0xff, 0xff}}
+	g, err = ToGIF(a, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(g.Image[0].Palette); n != 2 {
+		t.Errorf("opaque frame: %d palette entries, want the quantizer's 2", n)
+	}
+	if n := len(g.Image[2].Palette); n != 3 {
+		t.Errorf("transparent frame: %d palette entries, want 3 with the transparent one", n)
+	}
+
+	if _, err := ToGIF(&APNG{}, nil); !errors.Is(err, ErrNoFrames) {
+		t.Errorf("no frames: got error %v, want ErrNoFrames", err)
+	}
+}
+
+func TestToGIFRoundTrip(t *testing.T) {
+	// A GIF converted to an APNG and back shows the same frames.
+	a, err := FromGIF(testGIF())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := ToGIF(a, fixedQuantizer{gifRed, gifGreen, gifBlue})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := FromGIF(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, diffs := EqualFrames(a, b, 0); !ok {
+		t.Errorf("frames differ: %v", diffs)
+	}
+	if b.LoopCount != a.LoopCount {
+		t.Errorf("loop count %d, want %d", b.LoopCount, a.LoopCount)
+	}
+}
@@ -0,0 +1,60 @@
+package goapng
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"testing"
+)
+
+func TestToSpriteSheet(t *testing.T) {
+	a := testAPNG(5, 4, 3)
+	// The third frame is a small region, drawn over the second.
+	a.Images[2] = translate(solid(2, 1, a.Images[2].(*image.NRGBA).NRGBAAt(0, 0)), image.Pt(1, 1))
+	want, err := Composite(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		cols       int
+		wantW      int
+		wantH      int
+		wantColumn int // Of frame 4.
+	}{
+		{2, 8, 9, 0},
+		{0, 12, 6, 1}, // About square: 3 columns.
+		{9, 20, 3, 4}, // No wider than the frames.
+	}
+	for _, tt := range tests {
+		sheet, frames, err := ToSpriteSheet(a, tt.cols)
+		if err != nil {
+			t.Errorf("cols %d: %v", tt.cols, err)
+			continue
+		}
+		if b := sheet.Bounds(); b != image.Rect(0, 0, tt.wantW, tt.wantH) {
+			t.Errorf("cols %d: sheet %v, want %dx%d", tt.cols, b, tt.wantW, tt.wantH)
+		}
+		if len(frames) != 5 {
+			t.Errorf("cols %d: got %d frames, want 5", tt.cols, len(frames))
+			continue
+		}
+		if x := frames[4].Rect.Min.X / 4; x != tt.wantColumn {
+			t.Errorf("cols %d: frame 4 in column %d, want %d", tt.cols, x, tt.wantColumn)
+		}
+		for i, f := range frames {
+			cell := image.NewRGBA(image.Rect(0, 0, 4, 3))
+			draw.Draw(cell, cell.Rect, sheet, f.Rect.Min, draw.Src)
+			if f.Rect.Size() != image.Pt(4, 3) || !samePixels(cell, want[i]) {
+				t.Errorf("cols %d: frame %d at %v differs from the composited frame", tt.cols, i, f.Rect)
+			}
+			if f.Duration != a.Durations[i] {
+				t.Errorf("cols %d: frame %d: duration %v, want %v", tt.cols, i, f.Duration, a.Durations[i])
+			}
+		}
+	}
+
+	if _, _, err := ToSpriteSheet(&APNG{}, 2); !errors.Is(err, ErrNoFrames) {
+		t.Errorf("no frames: got error %v, want ErrNoFrames", err)
+	}
+}
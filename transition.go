@@ -0,0 +1,166 @@
+package goapng
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"time"
+)
+
+// Crossfade returns steps images that fade from a to b, not including a
+// and b themselves: image i is (i+1)/(steps+1) of the way to b. The images
+// cover the bounds of both a and b, which are transparent outside their
+// own bounds.
+func Crossfade(a, b image.Image, steps int) []image.Image {
+	r := a.Bounds().Union(b.Bounds())
+	ma, mb := toRGBA(a, r), toRGBA(b, r)
+	out := make([]image.Image, steps)
+	for i := range out {
+		out[i] = mix(ma, mb, float64(i+1)/float64(steps+1))
+	}
+	return out
+}
+
+// InsertCrossfades inserts steps crossfaded frames between each pair of
+// consecutive frames of a, so that hard cuts become smooth transitions.
+// Each transition lasts d, which is taken from the display time of the
+// frame before it; a frame shown for less than d fades out for the whole of
+// its display time. The frames of a are replaced with full-canvas frames,
+// and its delays with Durations.
+func InsertCrossfades(a *APNG, steps int, d time.Duration) error {
+	if steps < 0 || d < 0 {
+		return errors.New("apng: invalid crossfade")
+	}
+	frames, err := Composite(a)
+	if err != nil {
+		return err
+	}
+	ts := a.timeline()
+
+	var (
+		imgs []image.Image
+		durs []time.Duration
+	)
+	for i, m := range frames {
+		delay := ts[i+1] - ts[i]
+		if i == len(frames)-1 || steps == 0 {
+			imgs = append(imgs, m)
+			durs = append(durs, delay)
+			continue
+		}
+		fade := d
+		if fade > delay {
+			fade = delay
+		}
+		imgs = append(imgs, m)
+		durs = append(durs, delay-fade)
+		imgs = append(imgs, Crossfade(m, frames[i+1], steps)...)
+		durs = append(durs, spread(fade, steps)...)
+	}
+	a.setTransitioned(imgs, durs)
+	return nil
+}
+
+// FadeIn adds steps frames to the start of a that fade from the color c to
+// its first frame over d. A nil c fades in from transparent. The frames of
+// a are replaced with full-canvas frames, and its delays with Durations.
+func FadeIn(a *APNG, c color.Color, steps int, d time.Duration) error {
+	return fade(a, c, steps, d, true)
+}
+
+// FadeOut adds steps frames to the end of a that fade from its last frame
+// to the color c over d, the last of them showing c alone. A nil c fades
+// out to transparent. The frames of a are replaced as with FadeIn.
+func FadeOut(a *APNG, c color.Color, steps int, d time.Duration) error {
+	return fade(a, c, steps, d, false)
+}
+
+func fade(a *APNG, c color.Color, steps int, d time.Duration, in bool) error {
+	if steps < 0 || d < 0 {
+		return errors.New("apng: invalid fade")
+	}
+	frames, err := Composite(a)
+	if err != nil {
+		return err
+	}
+	ts := a.timeline()
+	imgs := make([]image.Image, len(frames))
+	durs := make([]time.Duration, len(frames))
+	for i, m := range frames {
+		imgs[i] = m
+		durs[i] = ts[i+1] - ts[i]
+	}
+	if steps == 0 {
+		a.setTransitioned(imgs, durs)
+		return nil
+	}
+
+	if c == nil {
+		c = color.Transparent
+	}
+	solid := image.NewRGBA(a.bounds())
+	draw.Draw(solid, solid.Rect, image.NewUniform(c), image.Point{}, draw.Src)
+	if in {
+		fades := Crossfade(solid, frames[0], steps)
+		imgs = append(fades, imgs...)
+		durs = append(spread(d, steps), durs...)
+	} else {
+		// The fade ends on the color itself, so it is held at the end
+		// of the animation.
+		fades := append(Crossfade(frames[len(frames)-1], solid, steps-1), solid)
+		imgs = append(imgs, fades...)
+		durs = append(durs, spread(d, steps)...)
+	}
+	a.setTransitioned(imgs, durs)
+	return nil
+}
+
+// spread divides d into n durations that add up to exactly d.
+func spread(d time.Duration, n int) []time.Duration {
+	out := make([]time.Duration, n)
+	for i := range out {
+		k, n := time.Duration(i), time.Duration(n)
+		out[i] = d*(k+1)/n - d*k/n
+	}
+	return out
+}
+
+// setTransitioned replaces the frames of a, which have been composited to
+// full canvases and have had transition frames added, with imgs shown for
+// durs. The frames keep the color model of a unless it is paletted, as the
+// blended colors are unlikely to be in the palette.
+func (a *APNG) setTransitioned(imgs []image.Image, durs []time.Duration) {
+	ref := a.Images[0]
+	if l, ok := ref.(*LazyImage); ok {
+		ref, _ = l.Decode()
+	}
+	if _, ok := ref.(*image.Paletted); !ok {
+		for i, m := range imgs {
+			imgs[i] = convertLike(ref, m)
+		}
+	}
+	a.setFlat(imgs)
+	a.Delays, a.DelayDens = nil, nil
+	a.Durations = durs
+	a.Config.ColorModel = imgs[0].ColorModel()
+}
+
+// toRGBA returns a copy of m with bounds r.
+func toRGBA(m image.Image, r image.Rectangle) *image.RGBA {
+	dst := image.NewRGBA(r)
+	draw.Draw(dst, m.Bounds(), m, m.Bounds().Min, draw.Src)
+	return dst
+}
+
+// mix returns m1 and m2, which have the same bounds, blended with weight t
+// for m2. The colors are premultiplied, so transparent pixels don't darken
+// the blend.
+func mix(m1, m2 *image.RGBA, t float64) *image.RGBA {
+	dst := image.NewRGBA(m1.Rect)
+	for i := range dst.Pix {
+		dst.Pix[i] = uint8(math.Round(float64(m1.Pix[i])*(1-t) + float64(m2.Pix[i])*t))
+	}
+	return dst
+}
@@ -0,0 +1,74 @@
+package goapng
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"testing"
+	"time"
+)
+
+func TestTrimRange(t *testing.T) {
+	a := testAPNG(4, 4, 4)
+	var file bytes.Buffer
+	if err := EncodeAll(&file, a); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		from, to   int
+		wantFrames int   // The frames left, if the trim succeeds.
+		wantErr    error // nil for an error other than ErrFrameIndex.
+		ok         bool
+	}{
+		{0, 1, 3, nil, true},
+		{1, 3, 2, nil, true},
+		{3, 4, 3, nil, true},
+		{2, 2, 4, nil, true},
+		{-1, 2, 0, ErrFrameIndex, false},
+		{1, 5, 0, ErrFrameIndex, false},
+		{3, 1, 0, ErrFrameIndex, false},
+		{0, 4, 0, nil, false},
+	}
+	for _, tt := range tests {
+		b := *a
+		b.Images = append([]image.Image(nil), a.Images...)
+		b.Durations = append([]time.Duration(nil), a.Durations...)
+		errs := map[string]error{"Trim": Trim(&b, tt.from, tt.to, true)}
+		if errs["Trim"] == nil && len(b.Images) != tt.wantFrames {
+			t.Errorf("Trim(%d, %d): %d frames left, want %d", tt.from, tt.to, len(b.Images), tt.wantFrames)
+		}
+
+		var out bytes.Buffer
+		errs["TrimStream"] = TrimStream(&out, bytes.NewReader(file.Bytes()), tt.from, tt.to, true)
+		if errs["TrimStream"] == nil {
+			if c, err := DecodeAll(&out); err != nil {
+				t.Errorf("TrimStream(%d, %d): decoding the result: %v", tt.from, tt.to, err)
+			} else if len(c.Images) != tt.wantFrames {
+				t.Errorf("TrimStream(%d, %d): %d frames left, want %d", tt.from, tt.to, len(c.Images), tt.wantFrames)
+			}
+		}
+
+		for name, err := range errs {
+			switch {
+			case tt.ok && err != nil:
+				t.Errorf("%s(%d, %d): %v", name, tt.from, tt.to, err)
+			case !tt.ok && err == nil:
+				t.Errorf("%s(%d, %d): got no error", name, tt.from, tt.to)
+			case tt.wantErr != nil && !errors.Is(err, tt.wantErr):
+				t.Errorf("%s(%d, %d): got error %v, want %v", name, tt.from, tt.to, err, tt.wantErr)
+			}
+		}
+	}
+}
+
+func TestTrimYCbCr(t *testing.T) {
+	// Trimming frame 0 flattens frame 1, which only draws part of the
+	// canvas, into a frame that can't be YCbCr; frame 2 must follow it.
+	a := ycbcrAPNG(3, 8, 8)
+	a.Images[1] = a.Images[1].(*image.YCbCr).SubImage(image.Rect(2, 2, 6, 6))
+	b := copyAPNG(a)
+	if err := Trim(b, 0, 1, false); err != nil {
+		t.Fatal(err)
+	}
+	checkShows(t, b, a, []int{1, 2})
+}
@@ -0,0 +1,164 @@
+package goapng
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+// Validate checks that a can be encoded: that there are frames, that the
+// per-frame slices have one entry per frame, that the frames share a color
+// model, that the first frame starts at the origin and that every frame
+// lies within the canvas it sets. It
+// returns nil, or an error listing every problem found together with the
+// frames concerned.
+func Validate(a *APNG) error {
+	return validate(a, len(a.Images))
+}
+
+// validate is Validate, but compares the color models of the first n
+// frames only, so that lazily decoded frames after them are not
+// decompressed.
+func validate(a *APNG, n int) error {
+	if len(a.Images) == 0 {
+		return ErrNoFrames
+	}
+	return errors.Join(append(checkSlices(a), checkFrames(a, n)...)...)
+}
+
+// checkSlices returns the problems with the per-frame slices and the loop
+// count of a, which has frames.
+func checkSlices(a *APNG) []error {
+	var errs []error
+	n := len(a.Images)
+	if a.Durations != nil {
+		if len(a.Durations) != n {
+			errs = append(errs, ErrDurationMismatch)
+		}
+	} else if len(a.Delays) != n {
+		errs = append(errs, ErrDelayMismatch)
+	} else if a.DelayDens != nil && len(a.DelayDens) != n {
+		errs = append(errs, ErrDelayDenMismatch)
+	}
+	if a.Disposals != nil && len(a.Disposals) != n {
+		errs = append(errs, ErrDisposalMismatch)
+	}
+	if a.Blends != nil && len(a.Blends) != n {
+		errs = append(errs, ErrBlendMismatch)
+	}
+	if a.LoopCount > maxLoopCount {
+		errs = append(errs, ErrLoopCount)
+	}
+	return errs
+}
+
+// checkFrames returns the problems with the frames of a, which has frames,
+// comparing the color models of the first n only.
+func checkFrames(a *APNG, n int) []error {
+	first := a.Images[0]
+	if first == nil {
+		return []error{&FrameError{0, ErrNilFrame}}
+	}
+	var errs []error
+	canvas := first.Bounds()
+	if err := checkCanvas(canvas); err != nil {
+		errs = append(errs, &FrameError{0, err})
+	}
+	model := first.ColorModel()
+	for i, img := range a.Images[1:] {
+		i++
+		if img == nil {
+			errs = append(errs, &FrameError{i, ErrNilFrame})
+			continue
+		}
+		if i < n && !equalColorModel(img.ColorModel(), model) {
+			errs = append(errs, &FrameError{i, ErrColorModel})
+		}
+		// x_offset >= 0 && y_offset >= 0 &&
+		// x_offset + width <= first frame width &&
+		// y_offset + height <= first frame height
+		if err := checkRegion(img.Bounds(), canvas); err != nil {
+			errs = append(errs, &FrameError{i, err})
+		}
+	}
+	return errs
+}
+
+// checkCanvas returns an error unless a first frame with bounds canvas
+// starts at the origin, as the canvas it sets does.
+func checkCanvas(canvas image.Rectangle) error {
+	if canvas.Min != (image.Point{}) {
+		return fmt.Errorf("%w: the first frame, %v, sets the canvas", ErrFrameOrigin, canvas)
+	}
+	return nil
+}
+
+// checkRegion returns a RegionError if a frame with bounds b does not lie
+// within the canvas set by a first frame with bounds canvas.
+func checkRegion(b, canvas image.Rectangle) error {
+	if b.In(regionOf(canvas)) {
+		return nil
+	}
+	return &RegionError{b, regionOf(canvas)}
+}
+
+// regionOf returns the area that frames must lie within when the first
+// frame has bounds canvas: the canvas, which starts at the origin, as
+// a.bounds and the IHDR chunk have it.
+func regionOf(canvas image.Rectangle) image.Rectangle {
+	return image.Rect(0, 0, canvas.Dx(), canvas.Dy())
+}
+
+// regionErrorsOnly reports whether every problem in err, as returned by
+// Validate, is a RegionError.
+func regionErrorsOnly(err error) bool {
+	for _, err := range unwrapAll(err) {
+		var re *RegionError
+		if !errors.As(err, &re) {
+			return false
+		}
+	}
+	return true
+}
+
+// unwrapAll returns the errors joined in err, or err alone.
+func unwrapAll(err error) []error {
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		return j.Unwrap()
+	}
+	return []error{err}
+}
+
+// fixRegions returns a copy of a with every frame cropped to the canvas.
+// Frames left empty are dropped and their delays added to the preceding
+// frame, or to the following one when no frame before them is kept. a
+// must otherwise be valid.
+func fixRegions(a *APNG) *APNG {
+	all := make([]int, len(a.Images))
+	for i := range all {
+		all[i] = i
+	}
+	b := a.selectFrames(all)
+
+	r := regionOf(a.Images[0].Bounds())
+	f := frameFolder{a: b}
+	var imgs []image.Image
+	for i, img := range b.Images {
+		fr := img.Bounds()
+		if fr.In(r) {
+			f.keep(i)
+			imgs = append(imgs, img)
+			continue
+		}
+		fr = fr.Intersect(r)
+		if fr.Empty() {
+			f.drop(i)
+			continue
+		}
+		f.keep(i)
+		imgs = append(imgs, subImage(img, fr))
+	}
+	b = b.selectFrames(f.kept)
+	b.Images = imgs
+	return b
+}
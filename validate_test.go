@@ -0,0 +1,58 @@
+package goapng
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFirstFrameOrigin(t *testing.T) {
+	// The first frame sets the canvas, but is cut from (5, 5) of a larger
+	// picture, as is the frame that follows it.
+	big := solid(20, 20, color.NRGBA{0x10, 0x20, 0x30, 0xff})
+	a := testAPNG(2, 10, 10)
+	a.Images[0] = big.SubImage(image.Rect(5, 5, 15, 15))
+	a.Images[1] = solid(20, 20, color.NRGBA{0xff, 0, 0, 0xff}).SubImage(image.Rect(8, 8, 15, 15))
+
+	if err := Validate(a); !errors.Is(err, ErrFrameOrigin) {
+		t.Errorf("Validate: got %v, want ErrFrameOrigin", err)
+	}
+	var buf bytes.Buffer
+	if err := EncodeAll(&buf, a); !errors.Is(err, ErrFrameOrigin) {
+		t.Errorf("EncodeAll: got %v, want ErrFrameOrigin", err)
+	}
+	buf.Reset()
+	err := EncodeSource(&buf, sliceSource(a), 0)
+	var fe *FrameError
+	if !errors.As(err, &fe) || fe.Index != 0 || !errors.Is(err, ErrFrameOrigin) {
+		t.Errorf("EncodeSource: got %v, want ErrFrameOrigin for frame 0", err)
+	}
+
+	// Moved to the origin, the same frames make a 10x10 animation.
+	buf.Reset()
+	enc := Encoder{OriginPolicy: TranslateOrigin}
+	if err := enc.EncodeAll(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+	r, err := VerifyStream(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.OK() {
+		t.Errorf("VerifyStream: %v", r)
+	}
+	got, err := DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := got.Images[0].Bounds(); b != image.Rect(0, 0, 10, 10) {
+		t.Errorf("canvas %v, want %v", b, image.Rect(0, 0, 10, 10))
+	}
+	for i, img := range got.Images {
+		if want := translate(a.Images[i], a.Images[i].Bounds().Min.Mul(-1)); !samePixels(img, want) {
+			t.Errorf("frame %d differs", i)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package goapng
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Report lists the problems VerifyStream found in a file.
+type Report struct {
+	Problems  []Problem
+	NumFrames int // The number of fcTL chunks.
+}
+
+// OK reports whether no problems were found.
+func (r *Report) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// A Problem is a way in which a file departs from the PNG or APNG spec.
+type Problem struct {
+	Offset  int64  // Offset of the chunk in the file, or -1 for the file as a whole.
+	Chunk   string // Type of the chunk, or "" for the file as a whole.
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Chunk == "" {
+		return p.Message
+	}
+	return fmt.Sprintf("offset %d: %s: %s", p.Offset, p.Chunk, p.Message)
+}
+
+// VerifyStream checks the file read from r against the PNG and APNG specs
+// without decoding any pixels: chunk checksums and order, sequence
+// numbers, frame regions within the IHDR bounds, dispose_op and blend_op
+// values, and that acTL num_frames matches the frames present. Problems go
+// in the report; the error is set only if r could not be read to the end
+// of the file.
+func VerifyStream(r io.Reader) (*Report, error) {
+	cr := newChunkReader(r)
+	if err := cr.readSignature(); err != nil {
+		return nil, err
+	}
+
+	v := &verifier{numFrames: -1}
+	off := int64(len(pngHeader))
+	for {
+		name, data, crcOK, err := cr.nextUnchecked()
+		if err == io.EOF {
+			v.fileErrorf("missing IEND chunk")
+			break
+		}
+		if err != nil {
+			return &v.report, unexpectedEOF(err)
+		}
+		v.chunk(off, name, data, crcOK)
+		off += 12 + int64(len(data))
+		if name == "IEND" {
+			break
+		}
+	}
+
+	if !v.order.seenIDAT {
+		v.fileErrorf("no IDAT chunk")
+	}
+	if v.numFrames >= 0 && int64(v.report.NumFrames) != v.numFrames {
+		v.fileErrorf("acTL has num_frames %d but there are %d frames", v.numFrames, v.report.NumFrames)
+	}
+	return &v.report, nil
+}
+
+// verifier checks the order and contents of the chunks of a file.
+type verifier struct {
+	report Report
+
+	off  int64 // Offset of the current chunk.
+	name string
+
+	order         chunkOrder
+	width, height uint32
+	numFrames     int64 // From acTL, or -1 if there is none.
+}
+
+func (v *verifier) errorf(format string, args ...interface{}) {
+	v.report.Problems = append(v.report.Problems, Problem{
+		Offset:  v.off,
+		Chunk:   v.name,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *verifier) fileErrorf(format string, args ...interface{}) {
+	v.report.Problems = append(v.report.Problems, Problem{
+		Offset:  -1,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *verifier) chunk(off int64, name string, data []byte, crcOK bool) {
+	v.off, v.name = off, name
+	if !crcOK {
+		v.errorf("bad CRC")
+	}
+	problems, _ := v.order.check(name, data)
+	for _, p := range problems {
+		v.errorf("%s", p)
+	}
+
+	switch name {
+	case "IHDR":
+		if len(data) != 13 {
+			v.errorf("bad length %d", len(data))
+			return
+		}
+		v.width = binary.BigEndian.Uint32(data[0:4])
+		v.height = binary.BigEndian.Uint32(data[4:8])
+		if v.width == 0 || v.height == 0 {
+			v.errorf("empty image")
+		}
+	case "acTL":
+		if len(data) != 8 {
+			v.errorf("bad length %d", len(data))
+			return
+		}
+		v.numFrames = int64(binary.BigEndian.Uint32(data[0:4]))
+		if v.numFrames == 0 {
+			v.errorf("num_frames is 0")
+		}
+		if binary.BigEndian.Uint32(data[4:8]) > maxLoopCount {
+			v.errorf("num_plays too large")
+		}
+	case "fcTL":
+		v.report.NumFrames++
+		if len(data) != 26 {
+			v.errorf("bad length %d", len(data))
+			return
+		}
+		fc, _ := parsefcTL(data)
+		w, h, x, y := fc.width, fc.height, fc.xOffset, fc.yOffset
+		if w == 0 || h == 0 || uint64(x)+uint64(w) > uint64(v.width) || uint64(y)+uint64(h) > uint64(v.height) {
+			v.errorf("frame region %dx%d+%d+%d outside the %dx%d canvas", w, h, x, y, v.width, v.height)
+		}
+		if !v.order.seenIDAT && (x != 0 || y != 0 || w != v.width || h != v.height) {
+			v.errorf("frame before IDAT does not cover the canvas")
+		}
+		if fc.disposeOp > DisposeOpPrevious {
+			v.errorf("unknown dispose_op %d", fc.disposeOp)
+		}
+		if fc.blendOp > BlendOpOver {
+			v.errorf("unknown blend_op %d", fc.blendOp)
+		}
+	case "fdAT":
+		if len(data) < 4 {
+			v.errorf("bad length %d", len(data))
+		}
+	case "IEND":
+		if len(data) != 0 {
+			v.errorf("bad length %d", len(data))
+		}
+	}
+}
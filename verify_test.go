@@ -0,0 +1,77 @@
+package goapng
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestVerifyStream(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeAll(&buf, testAPNG(3, 6, 6)); err != nil {
+		t.Fatal(err)
+	}
+	file := buf.Bytes()
+	// fcTL changes the second fcTL chunk of the file.
+	fcTL := func(fn func(data []byte)) []byte {
+		n := 0
+		return rewriteChunks(t, file, func(c *testChunk) bool {
+			if c.name == "fcTL" {
+				if n == 1 {
+					fn(c.data)
+				}
+				n++
+			}
+			return true
+		})
+	}
+	drop := func(name string) []byte {
+		return rewriteChunks(t, file, func(c *testChunk) bool { return c.name != name })
+	}
+	badCRC := append([]byte(nil), file...)
+	badCRC[len(pngHeader)+8+13] ^= 1 // The checksum of IHDR.
+
+	tests := []struct {
+		name      string
+		data      []byte
+		wantChunk string // The chunk of the first problem, "" for the file.
+		wantMsg   string // Part of the message of the first problem.
+	}{
+		{"valid", file, "", ""},
+		{"bad checksum", badCRC, "IHDR", "bad CRC"},
+		{"sequence gap", bumpSequence(t, file), "fcTL", "sequence"},
+		{"num_frames", setNumFrames(t, file, 5), "", "num_frames 5"},
+		{"region outside", fcTL(func(d []byte) { writeUint32(d[12:16], 3) }), "fcTL", "outside"},
+		{"empty region", fcTL(func(d []byte) { writeUint32(d[4:8], 0) }), "fcTL", "outside"},
+		{"dispose_op", fcTL(func(d []byte) { d[24] = 3 }), "fcTL", "dispose_op 3"},
+		{"blend_op", fcTL(func(d []byte) { d[25] = 2 }), "fcTL", "blend_op 2"},
+		{"no acTL", drop("acTL"), "fcTL", "without acTL"},
+		{"no IDAT", drop("IDAT"), "fcTL", "no data"},
+		{"no IEND", file[:len(file)-12], "", "missing IEND"},
+	}
+	for _, tt := range tests {
+		report, err := VerifyStream(bytes.NewReader(tt.data))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if tt.wantMsg == "" && tt.wantChunk == "" {
+			if !report.OK() {
+				t.Errorf("%s: got problems %v", tt.name, report.Problems)
+			}
+			continue
+		}
+		if report.OK() {
+			t.Errorf("%s: got no problems", tt.name)
+			continue
+		}
+		p := report.Problems[0]
+		if p.Chunk != tt.wantChunk || !strings.Contains(p.Message, tt.wantMsg) {
+			t.Errorf("%s: first problem %q, want one in %q mentioning %q", tt.name, p, tt.wantChunk, tt.wantMsg)
+		}
+	}
+
+	if _, err := VerifyStream(bytes.NewReader(file[:len(file)-5])); err == nil {
+		t.Error("truncated in IEND: got no error")
+	}
+}
@@ -1,18 +1,176 @@
 package goapng
 
 import (
-	"bytes"
 	"compress/zlib"
+	"context"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"hash/crc32"
 	"image"
+	"image/color"
 	"image/png"
 	"io"
+	"sync"
+	"time"
 )
 
+// Encoder configures encoding APNG images.
+//
+// An Encoder is safe for concurrent use: once configured, it may encode
+// from any number of goroutines at once, as its settings are only read
+// and everything that changes during an encode is kept per call. Its
+// Compressor must then be safe for concurrent use too, and OnFrame and
+// OnWarning may be called from several goroutines at once. The settings
+// must not be changed while encodes are running, and an Encoder must not
+// be copied after first use.
 type Encoder struct {
 	CompressionLevel CompressionLevel
+
+	// Compressor, if non-nil, is used instead of compress/zlib to compress
+	// the frame data.
+	Compressor Compressor
+
+	// Deterministic guarantees byte-identical output for identical inputs
+	// and Encoder settings, across runs and machines built with the same
+	// version of Go. Chunks are always written in a fixed order and no
+	// time-dependent chunks are emitted, and the frames are compressed
+	// with the standard library's zlib, whose output may change between
+	// Go versions. Setting Compressor as well fails the encode.
+	Deterministic bool
+
+	// MaxChunkSize, if positive, is the maximum number of bytes of
+	// compressed frame data stored in each IDAT or fdAT chunk. Frame data
+	// is re-split to fill chunks up to this size. Otherwise the chunking
+	// of the underlying PNG encoder is kept, save that frame data never
+	// goes into a chunk longer than the PNG limit of 2^31-1 bytes.
+	MaxChunkSize int
+
+	// Interlace stores every frame with Adam7 interlacing, so that a viewer
+	// showing a frame as it arrives can draw all of it coarsely first.
+	// Interlaced frames are larger and slower to decode, so they are off
+	// by default.
+	Interlace bool
+
+	// ZeroDelay, if positive, is written in place of zero delays. A delay
+	// of zero asks for the next frame as soon as possible, which renderers
+	// play at very different speeds. Leave ZeroDelay unset to keep zero
+	// delays as they are.
+	ZeroDelay time.Duration
+
+	// MinDelay, if positive, is the shortest frame delay the output should
+	// contain. Renderers disagree on how to play very short delays, so
+	// flooring them keeps the speed the same everywhere. Delays below
+	// MinDelay are handled according to MinDelayPolicy.
+	MinDelay       time.Duration
+	MinDelayPolicy MinDelayPolicy
+
+	// RegionPolicy says what the Encoder does with frames that extend
+	// beyond the canvas set by the first frame.
+	RegionPolicy RegionPolicy
+
+	// OriginPolicy says how the Encoder reads the Min of the bounds of
+	// frames, which image.SubImage leaves where the part was in the whole.
+	OriginPolicy OriginPolicy
+
+	// OnFrame, if non-nil, is called after each frame has been written.
+	OnFrame func(i int, stats FrameStats)
+
+	// OnWarning, if non-nil, is called with each problem the Encoder works
+	// around instead of failing: a delay below MinDelay, whether raised or
+	// kept, an unknown disposal method or blend operation written as the
+	// default, and a frame cropped or dropped under FixRegion. Each problem
+	// is a *FrameError.
+	OnWarning func(err error)
+
+	buffers *sync.Pool // Of *encodeBuffers, kept between encodes; see Reset.
+}
+
+// MinDelayPolicy says what the Encoder does with delays below MinDelay.
+type MinDelayPolicy int
+
+const (
+	// ClampMinDelay writes MinDelay in place of shorter delays.
+	ClampMinDelay MinDelayPolicy = iota
+	// WarnMinDelay writes shorter delays unchanged and only reports them
+	// through FrameStats.ShortDelay.
+	WarnMinDelay
+	// RejectMinDelay fails the encode, before anything is written, if any
+	// delay is shorter than MinDelay.
+	RejectMinDelay
+)
+
+// RegionPolicy says what the Encoder does with frames outside the canvas.
+type RegionPolicy int
+
+const (
+	// StrictRegion fails the encode with a RegionError, before anything is
+	// written.
+	StrictRegion RegionPolicy = iota
+	// FixRegion crops frames to the canvas. Frames wholly outside it are
+	// dropped, and their delays added to the preceding frame. EncodeSource
+	// cannot change frames it has already written, so it still fails on
+	// frames wholly outside the canvas.
+	FixRegion
+)
+
+// OriginPolicy says what the Encoder does with frames whose bounds don't
+// start at the origin.
+type OriginPolicy int
+
+const (
+	// KeepOrigin takes the Min of the bounds of each frame as its offset
+	// on the canvas, the x_offset and y_offset of its fcTL chunk.
+	KeepOrigin OriginPolicy = iota
+	// TranslateOrigin moves every frame to the origin, so that it is drawn
+	// at the top left corner of the canvas wherever its bounds start.
+	TranslateOrigin
+	// RejectOrigin fails the encode with ErrFrameOrigin if any frame's
+	// bounds don't start at the origin.
+	RejectOrigin
+)
+
+// atOrigin applies enc.OriginPolicy to frame i, img.
+func (enc *Encoder) atOrigin(i int, img image.Image) (image.Image, error) {
+	if img == nil || enc.OriginPolicy == KeepOrigin {
+		return img, nil
+	}
+	b := img.Bounds()
+	if b.Min == (image.Point{}) {
+		return img, nil
+	}
+	if enc.OriginPolicy == RejectOrigin {
+		return nil, &FrameError{i, fmt.Errorf("%w: %v", ErrFrameOrigin, b)}
+	}
+	// translate moves images in place, so it is given a new image sharing
+	// the pixels of img.
+	return translate(subImage(img, b), b.Min.Mul(-1)), nil
+}
+
+// FrameStats describes how a single frame was encoded.
+type FrameStats struct {
+	Bytes           int64         // Bytes written for the frame, including chunk framing.
+	RawBytes        int64         // Size of the filtered, uncompressed frame data.
+	CompressedBytes int64         // Size of the compressed frame data.
+	Ratio           float64       // CompressedBytes / RawBytes; smaller is better.
+	Elapsed         time.Duration // Time spent encoding and writing the frame.
+	Disposal        byte          // The dispose_op written for the frame.
+	Blend           byte          // The blend_op written for the frame.
+	ShortDelay      bool          // Whether the frame's delay was below Encoder.MinDelay.
+}
+
+// EncodeStats summarizes an encode.
+type EncodeStats struct {
+	Bytes  int64        // Total bytes written.
+	Frames []FrameStats // Statistics of each frame, in order.
+}
+
+// checkSettings returns an error if the settings of enc conflict.
+func (enc *Encoder) checkSettings() error {
+	if enc.Deterministic && enc.Compressor != nil {
+		return errors.New("apng: Deterministic output needs the standard Compressor")
+	}
+	return nil
 }
 
 const (
@@ -44,6 +202,21 @@ func levelToZlib(l CompressionLevel) int {
 	}
 }
 
+func levelToPNG(l CompressionLevel) png.CompressionLevel {
+	switch l {
+	case DefaultCompression:
+		return png.DefaultCompression
+	case NoCompression:
+		return png.NoCompression
+	case BestSpeed:
+		return png.BestSpeed
+	case BestCompression:
+		return png.BestCompression
+	default:
+		return png.DefaultCompression
+	}
+}
+
 type idat []byte
 
 func writeUint16(b []uint8, u uint16) {
@@ -58,24 +231,82 @@ func writeUint32(b []uint8, u uint32) {
 	b[3] = uint8(u)
 }
 
+// Disposal methods, as stored in the dispose_op field of fcTL.
+const (
+	DisposeOpNone       = 0 // Leave the frame region as is.
+	DisposeOpBackground = 1 // Clear the frame region to transparent black.
+	DisposeOpPrevious   = 2 // Restore the frame region to its prior contents.
+)
+
+// Blend operations, as stored in the blend_op field of fcTL.
+const (
+	BlendOpSource = 0 // Overwrite the frame region.
+	BlendOpOver   = 1 // Alpha-composite the frame over the region.
+)
+
+// APNG represents an animation: its frames, in Images, and the per-frame
+// slices that hold their timing, disposal and blending, indexed like
+// Images. Either Durations or Delays must have an entry for every frame;
+// DelayDens, Disposals and Blends may be nil, which gives every frame the
+// default, as may whichever of Durations and Delays is not used. The
+// fields are plural, unlike those of image/gif's GIF; package gifcompat
+// offers the singular Image and Delay for code written against image/gif.
 type APNG struct {
-	Images    []image.Image // The successive images.
-	Delays    []uint16      // The successive delay times, one per frame, in 100ths of a second.
-	Disposals []byte        // The successive disposal methods, one per frame.
-	LoopCount uint32        // The loop count. 0 indicates infinite looping.
+	Images    []image.Image   // The successive images.
+	Delays    []uint16        // The successive delay times, one per frame, in 100ths of a second unless DelayDens is set.
+	DelayDens []uint16        // The delay denominators, one per frame. If non-nil, Delays[i] is in 1/DelayDens[i] seconds; 0 means 100.
+	Durations []time.Duration // The successive delay times, one per frame. If non-nil, used instead of Delays.
+	Disposals []byte          // The successive disposal methods, one per frame.
+	Blends    []byte          // The successive blend operations, one per frame.
+	LoopCount uint32          // The number of times to play the animation. LoopForever (0) plays it forever.
 	Config    image.Config
+
+	// Background is the color the file suggests showing the animation
+	// against, read from its bKGD chunk, or nil. Renderers keep the alpha
+	// channel unless told to use it, as with Player.Background. The
+	// encoder does not write it.
+	Background color.Color
+}
+
+// LoopForever is the LoopCount of an animation that plays indefinitely.
+// LoopCount is APNG's num_plays, the total number of plays, so unlike
+// image/gif's LoopCount, 1 plays the animation once and there is no value
+// for "no looping" other than that.
+const LoopForever = 0
+
+// maxLoopCount is the largest num_plays allowed; like every PNG four-byte
+// integer, it must fit in 31 bits.
+const maxLoopCount = 1<<31 - 1
+
+// PlayOnce makes a play a single time and stop on its last frame.
+func (a *APNG) PlayOnce() {
+	a.LoopCount = 1
+}
+
+// Loop makes a play n times in total. Loop(LoopForever) plays it forever.
+func (a *APNG) Loop(n uint32) {
+	a.LoopCount = n
 }
 
 type encoder struct {
+	enc    *Encoder
+	ctx    context.Context // Checked before each chunk; may be nil.
 	a      *APNG
 	w      io.Writer
 	seqNum uint32 // Sequence number of the animation chunk.
+	first  int    // Index in the animation of the first frame of a.
+	buf    *encodeBuffers
 
 	tmpHeader [8]byte
 	tmp       [4 * 256]byte
 	tmpFooter [4]byte
 
+	format       *scanlineFormat // Used with a custom Compressor.
+	mixedOpacity bool            // Whether image/png would vary the color type between frames.
+
 	ihdr  []byte
+	plte  []byte
+	trns  []byte
 	idats []idat
 
 	err error
@@ -85,26 +316,31 @@ func (e *encoder) writeChunk(b []byte, name string) {
 	if e.err != nil {
 		return
 	}
+	if e.ctx != nil {
+		if e.err = e.ctx.Err(); e.err != nil {
+			return
+		}
+	}
 
 	// Write header (length, type).
-	n := uint32(len(b))
-	if int(n) != len(b) {
-		e.err = errors.New("apng: chunk is too large")
+	if len(b) > maxChunkLength {
+		e.err = fmt.Errorf("%w: %s chunk of %d bytes", ErrChunkTooLarge, name, len(b))
 		return
 	}
+	n := uint32(len(b))
 	writeUint32(e.tmpHeader[:4], n)
 	e.tmpHeader[4] = name[0]
 	e.tmpHeader[5] = name[1]
 	e.tmpHeader[6] = name[2]
 	e.tmpHeader[7] = name[3]
-	_, e.err = e.w.Write(e.tmpHeader[:8])
-	if e.err != nil {
+	if _, err := e.w.Write(e.tmpHeader[:8]); err != nil {
+		e.err = fmt.Errorf("apng: writing %s chunk: %w", name, err)
 		return
 	}
 
 	// Write data.
-	_, e.err = e.w.Write(b)
-	if e.err != nil {
+	if _, err := e.w.Write(b); err != nil {
+		e.err = fmt.Errorf("apng: writing %s chunk: %w", name, err)
 		return
 	}
 
@@ -113,15 +349,46 @@ func (e *encoder) writeChunk(b []byte, name string) {
 	crc.Write(e.tmpHeader[4:8])
 	crc.Write(b)
 	writeUint32(e.tmpFooter[:4], crc.Sum32())
-	_, e.err = e.w.Write(e.tmpFooter[:4])
+	if _, err := e.w.Write(e.tmpFooter[:4]); err != nil {
+		e.err = fmt.Errorf("apng: writing %s chunk: %w", name, err)
+	}
+}
+
+// frameErr returns e.err, with the index of the frame being written unless
+// the encoding was canceled.
+func (e *encoder) frameErr(frameIndex int) error {
+	if e.err == nil || (e.ctx != nil && e.err == e.ctx.Err()) {
+		return e.err
+	}
+	return e.frameError(frameIndex, e.err)
+}
+
+// frameError returns err as a FrameError for frame frameIndex of e.a,
+// indexed from the start of the animation.
+func (e *encoder) frameError(frameIndex int, err error) *FrameError {
+	return &FrameError{e.first + frameIndex, err}
+}
+
+// minDelayErr returns the error of RejectMinDelay for frame frameIndex.
+func (e *encoder) minDelayErr(frameIndex int) error {
+	return e.frameError(frameIndex, fmt.Errorf("%w of %v", ErrMinDelay, e.enc.MinDelay))
 }
 
 func (e *encoder) writeIHDR() {
 	e.writeChunk(e.ihdr, "IHDR")
 }
 
-func (e *encoder) writeacTL() {
-	writeUint32(e.tmp[0:4], uint32(len(e.a.Images)))
+func (e *encoder) writePLTEAndtRNS() {
+	if e.plte != nil {
+		e.writeChunk(e.plte, "PLTE")
+	}
+	if e.trns != nil {
+		e.writeChunk(e.trns, "tRNS")
+	}
+}
+
+func (e *encoder) writeacTL(numFrames int) {
+	writeUint32(e.tmp[0:4], uint32(numFrames))
 	writeUint32(e.tmp[4:8], e.a.LoopCount)
 	e.writeChunk(e.tmp[:8], "acTL")
 }
@@ -144,143 +411,194 @@ func (e *encoder) writefcTL(frameIndex int) {
 	// Write y_offset.
 	writeUint32(e.tmp[16:20], uint32(bounds.Min.Y))
 
+	num, den := e.delayFraction(frameIndex)
+	if e.shortDelay(frameIndex) {
+		if e.enc.MinDelayPolicy == ClampMinDelay {
+			e.warn(frameIndex, fmt.Errorf("%w, raised to %v", ErrMinDelay, e.enc.MinDelay))
+		} else {
+			e.warn(frameIndex, fmt.Errorf("%w of %v", ErrMinDelay, e.enc.MinDelay))
+		}
+	}
+
 	// Write delay_num(numerator).
-	writeUint16(e.tmp[20:22], e.a.Delays[frameIndex])
+	writeUint16(e.tmp[20:22], num)
 
 	// Write delay_den(denominator).
-	writeUint16(e.tmp[22:24], uint16(100))
+	writeUint16(e.tmp[22:24], den)
 
 	// Write dispose_op.
-	//switch d := e.a.Disposals[frameIndex]; d {
-	//case 0, 1, 2:
-	//	e.tmp[24] = d
-	//default:
-	//	e.tmp[24] = 0
-	//}
-	e.tmp[24] = 0
+	e.tmp[24] = e.disposeOp(frameIndex)
+	if e.a.Disposals != nil && e.a.Disposals[frameIndex] != e.tmp[24] {
+		e.warn(frameIndex, fmt.Errorf("apng: unknown disposal method %d, written as %d", e.a.Disposals[frameIndex], e.tmp[24]))
+	}
 
 	// Write blend_op.
-	e.tmp[25] = 0
+	e.tmp[25] = e.blendOp(frameIndex)
+	if e.a.Blends != nil && e.a.Blends[frameIndex] != e.tmp[25] {
+		e.warn(frameIndex, fmt.Errorf("apng: unknown blend operation %d, written as %d", e.a.Blends[frameIndex], e.tmp[25]))
+	}
 
 	e.writeChunk(e.tmp[:26], "fcTL")
 	e.seqNum++
 }
 
-func (e *encoder) writeIDATs() {
-	for _, id := range e.idats {
-		e.writeChunk(id, "IDAT")
+// warn passes a problem with the frame to Encoder.OnWarning.
+func (e *encoder) warn(frameIndex int, err error) {
+	if e.enc.OnWarning != nil {
+		e.enc.OnWarning(e.frameError(frameIndex, err))
 	}
 }
 
-func (e *encoder) writefdATs() {
-	for _, id := range e.idats {
-		writeUint32(e.tmp[0:4], e.seqNum)
-		fdat := make([]byte, 4, len(id))
-		copy(fdat, e.tmp[0:4])
-		fdat = append(fdat, id...)
-		e.writeChunk(fdat, "fdAT")
-		e.seqNum++
+// delayFraction returns the delay_num and delay_den of the frame, after
+// applying Encoder.ZeroDelay and Encoder.MinDelay.
+func (e *encoder) delayFraction(frameIndex int) (uint16, uint16) {
+	if e.shortDelay(frameIndex) && e.enc.MinDelayPolicy == ClampMinDelay {
+		return DelayFraction(e.enc.MinDelay)
 	}
+	return e.nonZeroDelay(frameIndex)
 }
 
-func (e *encoder) writeIEND() {
-	e.writeChunk(nil, "IEND")
+// nonZeroDelay returns the delay_num and delay_den of the frame, with a
+// zero delay replaced by Encoder.ZeroDelay.
+func (e *encoder) nonZeroDelay(frameIndex int) (uint16, uint16) {
+	num, den := e.a.delayFraction(frameIndex)
+	if num == 0 && e.enc.ZeroDelay > 0 {
+		return DelayFraction(e.enc.ZeroDelay)
+	}
+	return num, den
 }
 
-const (
-	dsStart = iota
-	dsSeenIHDR
-	dsSeenPLTE
-	dsSeentRNS
-	dsSeenIDAT
-	dsSeenIEND
-)
-
-type chunkFetcher struct {
-	bb    *bytes.Buffer
-	tmp   [3 * 256]byte
-	stage int
-
-	pc *pngChunk
-	ac *apngChunk
+// shortDelay reports whether the frame's delay is below Encoder.MinDelay.
+func (e *encoder) shortDelay(frameIndex int) bool {
+	if e.enc.MinDelay <= 0 {
+		return false
+	}
+	num, den := e.nonZeroDelay(frameIndex)
+	if den == 0 {
+		den = 100
+	}
+	return time.Duration(num)*time.Second/time.Duration(den) < e.enc.MinDelay
 }
 
-type pngChunk struct {
-	ihdr  []byte
-	idats []idat
+// disposeOp returns the dispose_op of the frame. Unknown values are
+// written as DisposeOpNone.
+func (e *encoder) disposeOp(frameIndex int) byte {
+	if e.a.Disposals != nil {
+		switch d := e.a.Disposals[frameIndex]; d {
+		case DisposeOpNone, DisposeOpBackground, DisposeOpPrevious:
+			return d
+		}
+	}
+	return DisposeOpNone
 }
 
-type apngChunk struct {
-	ihdr []byte
+// blendOp returns the blend_op of the frame. Unknown values are written as
+// BlendOpSource.
+func (e *encoder) blendOp(frameIndex int) byte {
+	if e.a.Blends != nil {
+		switch b := e.a.Blends[frameIndex]; b {
+		case BlendOpSource, BlendOpOver:
+			return b
+		}
+	}
+	return BlendOpSource
 }
 
-func (c *chunkFetcher) parseIHDR(length uint32) error {
-	_, err := io.ReadFull(c.bb, c.tmp[:length])
-	if err != nil {
-		return err
+// splitIDATs joins the frame data in idats and splits it again into chunks
+// of at most max bytes. If max is not positive, idats is returned as is,
+// except that chunks too long for an fdAT chunk are split at the limit.
+func splitIDATs(idats []idat, max int) []idat {
+	if max <= 0 || max > maxFrameDataLength {
+		out := idats[:0:0]
+		for _, id := range idats {
+			for len(id) > maxFrameDataLength {
+				out = append(out, id[:maxFrameDataLength:maxFrameDataLength])
+				id = id[maxFrameDataLength:]
+			}
+			out = append(out, id)
+		}
+		if max <= 0 {
+			return out
+		}
+		return splitIDATs(out, maxFrameDataLength)
 	}
-	c.pc.ihdr = make([]byte, length)
-	copy(c.pc.ihdr, c.tmp[:length])
-	return nil
-}
 
-func (c *chunkFetcher) parseIDAT(length uint32) error {
-	id := c.bb.Next(int(length))
-	if len(id) < int(length) {
-		return io.EOF
+	n := 0
+	for _, id := range idats {
+		n += len(id)
+	}
+	data := make([]byte, 0, n)
+	for _, id := range idats {
+		data = append(data, id...)
 	}
-	c.pc.idats = append(c.pc.idats, id)
-	return nil
-}
 
-func (c *chunkFetcher) parseIEND(length uint32) error {
-	return nil
+	out := make([]idat, 0, (n+max-1)/max)
+	for len(data) > max {
+		out = append(out, data[:max:max])
+		data = data[max:]
+	}
+	return append(out, data)
 }
 
-func (c *chunkFetcher) parsePNGChunk() error {
-	_, err := io.ReadFull(c.bb, c.tmp[:8])
-	if err != nil {
-		return err
+func (e *encoder) writeIDATs() {
+	for _, id := range e.idats {
+		e.writeChunk(id, "IDAT")
 	}
-	length := binary.BigEndian.Uint32(c.tmp[:4])
+}
 
-	switch string(c.tmp[4:8]) {
-	case "IHDR":
-		c.stage = dsSeenIHDR
-		err = c.parseIHDR(length)
-	case "PLTE":
-		// todo
-	case "tRNS":
-		// todo
-	case "IDAT":
-		c.stage = dsSeenIDAT
-		err = c.parseIDAT(length)
-	case "IEND":
-		c.stage = dsSeenIEND
-		err = c.parseIEND(length)
+func (e *encoder) writefdATs() {
+	for _, id := range e.idats {
+		writeUint32(e.tmp[0:4], e.seqNum)
+		fdat := make([]byte, 4, len(id))
+		copy(fdat, e.tmp[0:4])
+		fdat = append(fdat, id...)
+		e.writeChunk(fdat, "fdAT")
+		e.seqNum++
 	}
+}
+
+func (e *encoder) writeIEND() {
+	e.writeChunk(nil, "IEND")
+}
 
-	c.bb.Next(4) // Get rid of crc(4 bytes).
-	return err
+type pngChunk struct {
+	ihdr  []byte
+	plte  []byte
+	trns  []byte
+	idats []idat
 }
 
-func fetchPNGChunk(bb *bytes.Buffer) (*pngChunk, error) {
-	bb.Next(len(pngHeader))
-	c := &chunkFetcher{
-		bb:    bb,
-		stage: dsStart,
-		pc:    new(pngChunk),
+// fetchPNGChunk reads a PNG stream from r and collects the chunks needed to
+// re-mux it as an APNG frame. Every returned slice is owned by the caller.
+func fetchPNGChunk(r io.Reader) (*pngChunk, error) {
+	cr := newChunkReader(r)
+	if err := cr.readSignature(); err != nil {
+		return nil, err
 	}
 
-	for c.stage != dsSeenIEND {
-		if err := c.parsePNGChunk(); err != nil {
+	pc := new(pngChunk)
+	for {
+		name, data, err := cr.next()
+		if err != nil {
 			if err == io.EOF {
 				err = io.ErrUnexpectedEOF
 			}
 			return nil, err
 		}
+
+		switch name {
+		case "IHDR":
+			pc.ihdr = data
+		case "PLTE":
+			pc.plte = data
+		case "tRNS":
+			pc.trns = data
+		case "IDAT":
+			pc.idats = append(pc.idats, data)
+		case "IEND":
+			return pc, nil
+		}
 	}
-	return c.pc, nil
 }
 
 func isSameColorModel(img []image.Image) bool {
@@ -290,95 +608,231 @@ func isSameColorModel(img []image.Image) bool {
 
 	reference := img[0].ColorModel()
 	for i := 1; i < len(img); i++ {
-		if img[i] == nil || img[i].ColorModel() != reference {
+		if img[i] == nil || !equalColorModel(img[i].ColorModel(), reference) {
 			return false
 		}
 	}
 	return true
 }
 
-func fullfillFrameRegionConstraints(img []image.Image) bool {
-	if len(img) == 0 || img[0] == nil {
-		return false
+// equalColorModel reports whether m1 and m2 are the same color model.
+// Palettes are compared by their colors, as they can't be compared with ==.
+func equalColorModel(m1, m2 color.Model) bool {
+	p1, ok1 := m1.(color.Palette)
+	p2, ok2 := m2.(color.Palette)
+	if !ok1 || !ok2 {
+		return !ok1 && !ok2 && m1 == m2
 	}
-
-	reference := img[0].Bounds()
-
-	// constraints:
-	// 	x_offset >= 0 && y_offset >= 0
-	if !(reference.Min.X >= 0 && reference.Min.Y >= 0) {
+	if len(p1) != len(p2) {
 		return false
 	}
-
-	for i := 1; i < len(img); i++ {
-		if img[i] == nil {
+	for i := range p1 {
+		r1, g1, b1, a1 := p1[i].RGBA()
+		r2, g2, b2, a2 := p2[i].RGBA()
+		if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
 			return false
 		}
+	}
+	return true
+}
 
-		bounds := img[i].Bounds()
+// frameStats returns the statistics of the frame currently held by e.
+func (e *encoder) frameStats(frameIndex int, written int64, elapsed time.Duration) FrameStats {
+	fs := FrameStats{
+		Bytes:      written,
+		RawBytes:   rawSize(e.ihdr),
+		Elapsed:    elapsed,
+		Disposal:   e.disposeOp(frameIndex),
+		Blend:      e.blendOp(frameIndex),
+		ShortDelay: e.shortDelay(frameIndex),
+	}
+	for _, id := range e.idats {
+		fs.CompressedBytes += int64(len(id))
+	}
+	if fs.RawBytes > 0 {
+		fs.Ratio = float64(fs.CompressedBytes) / float64(fs.RawBytes)
+	}
+	return fs
+}
 
-		// constrains:
-		// 	   x_offset >= 0
-		// 	&& y_offset >= 0
-		// 	&& x_offset + width  = max_x <= first frame width
-		// 	&& y_offset + height = max_y <= first frame height
-		if !(bounds.Min.X >= 0 && bounds.Min.Y >= 0 && bounds.Max.X <= reference.Max.X && bounds.Max.Y <= reference.Max.Y) {
-			return false
+// rawSize returns the size of the filtered scanlines of the image described
+// by ihdr.
+func rawSize(ihdr []byte) int64 {
+	if len(ihdr) != 13 {
+		return 0
+	}
+	width := int64(binary.BigEndian.Uint32(ihdr[0:4]))
+	height := int64(binary.BigEndian.Uint32(ihdr[4:8]))
+	depth := int64(ihdr[8])
+
+	var channels int64
+	switch ihdr[9] {
+	case 0, 3: // Grayscale, indexed.
+		channels = 1
+	case 4: // Grayscale with alpha.
+		channels = 2
+	case 2: // Truecolor.
+		channels = 3
+	case 6: // Truecolor with alpha.
+		channels = 4
+	}
+	if ihdr[12] == 1 {
+		// Each pass of Adam7 is stored as an image of its own.
+		var n int64
+		for p := range adam7 {
+			w, h := adam7Size(int(width), int(height), p)
+			if w > 0 && h > 0 {
+				n += int64(h) * (1 + (int64(w)*channels*depth+7)/8)
+			}
 		}
+		return n
 	}
-	return true
+	return height * (1 + (width*channels*depth+7)/8)
 }
 
-func EncodeAll(w io.Writer, a *APNG) error {
-	if len(a.Images) == 0 {
-		return errors.New("apng: need at least one image")
+// encodeFrame encodes img as a standalone PNG and returns its chunks.
+func (e *encoder) encodeFrame(img image.Image) (*pngChunk, error) {
+	c := e.enc.Compressor
+	if c == nil && (e.mixedOpacity || e.enc.Interlace) {
+		// Let every frame share the color type of the IHDR. image/png
+		// never interlaces, so interlaced frames are encoded here too.
+		c = ZlibCompressor{}
 	}
-
-	if len(a.Images) != len(a.Delays) {
-		return errors.New("apng: mismatched image and delay lengths")
+	if c != nil {
+		if e.format == nil {
+			f := chooseFormat(e.a.Images)
+			f.interlace = e.enc.Interlace
+			e.format = &f
+		}
+		pc, err := encodeScanlines(img, e.format, c, e.enc.CompressionLevel, e.buf)
+		if err != nil {
+			return nil, fmt.Errorf("apng: compression error: %w", err)
+		}
+		return pc, nil
 	}
 
-	if a.Disposals != nil && len(a.Images) != len(a.Disposals) {
-		return errors.New("apng: mismatch image and disposal lengths")
+	bb := &e.buf.png
+	bb.Reset()
+	pe := png.Encoder{
+		CompressionLevel: levelToPNG(e.enc.CompressionLevel),
+		BufferPool:       e.buf,
 	}
+	if err := pe.Encode(bb, img); err != nil {
+		return nil, fmt.Errorf("apng: png encoding error: %w", err)
+	}
+	return fetchPNGChunk(bb)
+}
+
+// EncodeAll writes the images in a to w in APNG format.
+func EncodeAll(w io.Writer, a *APNG) error {
+	var enc Encoder
+	return enc.EncodeAll(w, a)
+}
 
-	if !isSameColorModel(a.Images) {
-		return errors.New("apng: must be all the same color model of images")
+// EncodeAll writes the images in a to w in APNG format.
+func (enc *Encoder) EncodeAll(w io.Writer, a *APNG) error {
+	return enc.EncodeAllContext(context.Background(), w, a)
+}
+
+// EncodeAllContext is like EncodeAll but stops with ctx.Err() once ctx is
+// done. Cancellation is checked between frames and between chunks, so w
+// may hold a partial image on return.
+func EncodeAllContext(ctx context.Context, w io.Writer, a *APNG) error {
+	var enc Encoder
+	return enc.EncodeAllContext(ctx, w, a)
+}
+
+// EncodeAllContext is like EncodeAll but stops with ctx.Err() once ctx is
+// done.
+func (enc *Encoder) EncodeAllContext(ctx context.Context, w io.Writer, a *APNG) error {
+	return enc.encodeAll(ctx, w, a, enc.OnFrame)
+}
+
+// encodeAll implements EncodeAllContext, calling onFrame, if non-nil, in
+// place of enc.OnFrame, so that callers need not copy enc to hook in.
+func (enc *Encoder) encodeAll(ctx context.Context, w io.Writer, a *APNG, onFrame func(i int, stats FrameStats)) error {
+	if err := enc.checkSettings(); err != nil {
+		return err
+	}
+	if enc.OriginPolicy != KeepOrigin {
+		b := *a
+		b.Images = make([]image.Image, len(a.Images))
+		for i, img := range a.Images {
+			var err error
+			if b.Images[i], err = enc.atOrigin(i, img); err != nil {
+				return err
+			}
+		}
+		a = &b
 	}
 
-	if !fullfillFrameRegionConstraints(a.Images) {
-		return errors.New("apng: must fullfill frame region constraints.")
+	err := Validate(a)
+	if err != nil && enc.RegionPolicy == FixRegion && regionErrorsOnly(err) {
+		if enc.OnWarning != nil {
+			for _, err := range unwrapAll(err) {
+				enc.OnWarning(err)
+			}
+		}
+		a = fixRegions(a)
+		err = Validate(a)
+	}
+	if err != nil {
+		return err
 	}
 
+	cw := &countingWriter{w: w}
 	e := encoder{
-		a: a,
-		w: w,
+		enc:          enc,
+		ctx:          ctx,
+		a:            a,
+		w:            cw,
+		mixedOpacity: mixedOpacity(a.Images),
+	}
+	pool := enc.bufferPool()
+	e.buf = pool.Get().(*encodeBuffers)
+	defer pool.Put(e.buf)
+
+	if enc.MinDelayPolicy == RejectMinDelay {
+		for i := range a.Images {
+			if e.shortDelay(i) {
+				return e.minDelayErr(i)
+			}
+		}
 	}
 
-	_, e.err = io.WriteString(w, pngHeader)
+	_, e.err = io.WriteString(e.w, pngHeader)
 	for i, img := range a.Images {
-		bb := new(bytes.Buffer)
-		if err := png.Encode(bb, img); err != nil {
-			return errors.New("apng: png encoding error(" + err.Error() + ")")
+		if err := ctx.Err(); err != nil {
+			return err
 		}
-
-		pc, err := fetchPNGChunk(bb)
+		start, n := time.Now(), cw.n
+		pc, err := e.encodeFrame(img)
 		if err != nil {
-			return err
+			return e.frameError(i, err)
 		}
 		e.ihdr = pc.ihdr
-		e.idats = pc.idats
+		e.plte = pc.plte
+		e.trns = pc.trns
+		e.idats = splitIDATs(pc.idats, enc.MaxChunkSize)
 
 		// First image is defalt image.
 		if i == 0 {
 			e.writeIHDR()
-			e.writeacTL()
+			e.writeacTL(len(a.Images))
+			e.writePLTEAndtRNS()
 			e.writefcTL(i)
 			e.writeIDATs()
 		} else {
 			e.writefcTL(i)
 			e.writefdATs()
 		}
+		if e.err != nil {
+			return e.frameErr(i)
+		}
+
+		if onFrame != nil {
+			onFrame(i, e.frameStats(i, cw.n-n, time.Since(start)))
+		}
 	}
 	e.writeIEND()
 	return e.err
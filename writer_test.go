@@ -0,0 +1,67 @@
+package goapng
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func TestEncoderConcurrent(t *testing.T) {
+	a := testAPNG(4, 32, 16)
+	var want bytes.Buffer
+	if err := EncodeAll(&want, a); err != nil {
+		t.Fatal(err)
+	}
+
+	// A shared Encoder, used before its buffer pool exists, by every kind of
+	// encode at once. Run with -race.
+	enc := &Encoder{OnFrame: func(int, FrameStats) {}}
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for k := 0; k < 4; k++ {
+				var buf bytes.Buffer
+				var err error
+				switch (g + k) % 4 {
+				case 0:
+					err = enc.EncodeAll(&buf, a)
+				case 1:
+					var stats *EncodeStats
+					stats, err = enc.EncodeAllStats(&buf, a)
+					if err == nil && len(stats.Frames) != len(a.Images) {
+						t.Errorf("EncodeAllStats: got %d frame stats, want %d", len(stats.Frames), len(a.Images))
+					}
+				case 2:
+					enc.Reset()
+					err = enc.EncodeAll(&buf, a)
+				case 3:
+					err = enc.EncodeSource(&buf, sliceSource(a), a.LoopCount)
+					if err == nil {
+						// EncodeSource picks its own color type; only check
+						// that the frames survive.
+						b, derr := DecodeAll(&buf)
+						if derr != nil || len(b.Images) != len(a.Images) {
+							t.Errorf("EncodeSource: decoded %v frames, err %v", b, derr)
+						}
+						continue
+					}
+				}
+				if err != nil {
+					errs <- err
+					continue
+				}
+				if !bytes.Equal(buf.Bytes(), want.Bytes()) {
+					t.Errorf("goroutine %d encode %d: output differs from a lone EncodeAll", g, k)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}